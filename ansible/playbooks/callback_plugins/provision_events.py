@@ -0,0 +1,78 @@
+# Stdout callback used by provision-cli. It prints one JSON object per line
+# for every play, task and host result, which the CLI parses to show its
+# progress view and to report the failing task, see
+# cli/cmd/provision/ansible/events.go. Plain ansible-playbook runs are not
+# affected, the CLI selects it with ANSIBLE_STDOUT_CALLBACK.
+from __future__ import absolute_import, division, print_function
+__metaclass__ = type
+
+DOCUMENTATION = '''
+    name: provision_events
+    type: stdout
+    short_description: JSON lines of play, task and host results for provision-cli
+    description:
+      - Prints one JSON object per line for every play, task and host result.
+'''
+
+import json
+import sys
+
+from ansible.plugins.callback import CallbackBase
+
+
+class CallbackModule(CallbackBase):
+    CALLBACK_VERSION = 2.0
+    CALLBACK_TYPE = 'stdout'
+    CALLBACK_NAME = 'provision_events'
+
+    def _emit(self, event, **fields):
+        fields['event'] = event
+        sys.stdout.write(json.dumps(fields, default=str) + '\n')
+        sys.stdout.flush()
+
+    def _emit_result(self, status, result, ignore_errors=False):
+        res = result._result
+        fields = {
+            'status': status,
+            'host': result._host.get_name(),
+            'task': result._task.get_name().strip(),
+            'msg': res.get('msg', ''),
+            'stdout': res.get('stdout', ''),
+            'stderr': res.get('stderr', '') or res.get('module_stderr', ''),
+            'rc': res.get('rc'),
+            'ignore_errors': ignore_errors,
+        }
+        if not isinstance(fields['msg'], str):
+            fields['msg'] = json.dumps(fields['msg'], default=str)
+        # Keep what debug tasks print, since nothing else shows it
+        if result._task.action in ('debug', 'ansible.builtin.debug') and status == 'ok':
+            var = result._task.args.get('var')
+            fields['output'] = res.get(var) if var else res.get('msg', '')
+        self._emit('result', **fields)
+
+    def v2_playbook_on_play_start(self, play):
+        self._emit('play_start', play=play.get_name().strip())
+
+    def v2_playbook_on_task_start(self, task, is_conditional):
+        self._emit('task_start', task=task.get_name().strip())
+
+    def v2_playbook_on_handler_task_start(self, task):
+        self._emit('task_start', task=task.get_name().strip())
+
+    def v2_runner_on_ok(self, result):
+        self._emit_result('changed' if result._result.get('changed') else 'ok', result)
+
+    def v2_runner_on_failed(self, result, ignore_errors=False):
+        self._emit_result('failed', result, ignore_errors)
+
+    def v2_runner_on_skipped(self, result):
+        self._emit_result('skipped', result)
+
+    def v2_runner_on_unreachable(self, result):
+        self._emit_result('unreachable', result)
+
+    def v2_playbook_on_stats(self, stats):
+        hosts = {}
+        for host in sorted(stats.processed.keys()):
+            hosts[host] = stats.summarize(host)
+        self._emit('stats', hosts=hosts)
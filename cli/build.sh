@@ -15,6 +15,11 @@ if ! command -v go &> /dev/null; then
     exit 1
 fi
 
+# Copy the playbooks, scripts, cloud-init template and Helm chart into the
+# binary so it runs outside the repository
+echo "Embedding assets..."
+go generate ./cmd/provision/assets
+
 # Build for Linux amd64 only
 echo "Building for linux/amd64..."
 GOOS=linux GOARCH=amd64 go build -o "../provision-cli" ./cmd/provision
@@ -0,0 +1,254 @@
+package ansible
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// CallbackName is the stdout callback, shipped in callback_plugins next to
+// the playbooks, that prints the events parsed here as JSON lines
+const CallbackName = "provision_events"
+
+// Statuses of a task result
+const (
+	StatusOK          = "ok"
+	StatusChanged     = "changed"
+	StatusFailed      = "failed"
+	StatusSkipped     = "skipped"
+	StatusUnreachable = "unreachable"
+)
+
+// TaskResult is the outcome of a task on one host
+type TaskResult struct {
+	Play         string `json:"-"`
+	Task         string `json:"task"`
+	Host         string `json:"host"`
+	Status       string `json:"status"`
+	Msg          string `json:"msg"`
+	Stdout       string `json:"stdout"`
+	Stderr       string `json:"stderr"`
+	RC           *int   `json:"rc"`
+	IgnoreErrors bool   `json:"ignore_errors"`
+	// Output is what a debug task printed
+	Output interface{} `json:"output"`
+}
+
+// Failed reports whether the result fails the playbook
+func (r TaskResult) Failed() bool {
+	return (r.Status == StatusFailed && !r.IgnoreErrors) || r.Status == StatusUnreachable
+}
+
+// HostStats is the recap of a host at the end of a run
+type HostStats struct {
+	OK          int `json:"ok"`
+	Changed     int `json:"changed"`
+	Failures    int `json:"failures"`
+	Unreachable int `json:"unreachable"`
+	Skipped     int `json:"skipped"`
+	Rescued     int `json:"rescued"`
+	Ignored     int `json:"ignored"`
+}
+
+// Run is what a playbook run did, as reported by the callback
+type Run struct {
+	Results []TaskResult
+	Stats   map[string]HostStats
+}
+
+// Failures returns the failed results whose errors were not ignored. A
+// rescue block may still have handled some of them.
+func (r *Run) Failures() []TaskResult {
+	var failures []TaskResult
+	for _, result := range r.Results {
+		if result.Failed() {
+			failures = append(failures, result)
+		}
+	}
+	return failures
+}
+
+// PlaybookError names the task and host a playbook failed on
+type PlaybookError struct {
+	Play        string
+	Task        string
+	Host        string
+	Unreachable bool
+	Msg         string
+	Stderr      string
+	// Others counts the other failed task results
+	Others int
+}
+
+func (e *PlaybookError) Error() string {
+	what := "failed"
+	if e.Unreachable {
+		what = "could not reach the host"
+	}
+	msg := fmt.Sprintf("task %q %s on %s (play %q)", e.Task, what, e.Host, e.Play)
+	if e.Msg != "" {
+		msg += ": " + e.Msg
+	}
+	if e.Stderr != "" {
+		msg += "\nstderr: " + strings.TrimSpace(e.Stderr)
+	}
+	if e.Others > 0 {
+		msg += fmt.Sprintf("\n(%d more task result(s) failed)", e.Others)
+	}
+	return msg
+}
+
+// newPlaybookError describes the task that failed the playbook. Earlier
+// failures may have been handled by a rescue block, so the last failed task
+// is described, on the first host it failed on.
+func newPlaybookError(failures []TaskResult) *PlaybookError {
+	i := len(failures) - 1
+	for i > 0 && failures[i-1].Play == failures[i].Play && failures[i-1].Task == failures[i].Task {
+		i--
+	}
+	first := failures[i]
+	return &PlaybookError{
+		Play:        first.Play,
+		Task:        first.Task,
+		Host:        first.Host,
+		Unreachable: first.Status == StatusUnreachable,
+		Msg:         first.Msg,
+		Stderr:      first.Stderr,
+		Others:      len(failures) - 1,
+	}
+}
+
+// event is a line printed by the callback
+type event struct {
+	Event string `json:"event"`
+	Play  string `json:"play"`
+	// TaskResult holds the task name of task_start events too
+	TaskResult
+	Hosts map[string]HostStats `json:"hosts"`
+}
+
+// parseEvents reads the callback's JSON lines from r into a Run, writing a
+// line per task to out as each task completes. Lines that are not events,
+// such as output of other plugins, are copied to out unchanged.
+func parseEvents(r io.Reader, out io.Writer) (*Run, error) {
+	run := &Run{}
+	p := &progress{out: out}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		var e event
+		if len(line) == 0 || line[0] != '{' || json.Unmarshal(line, &e) != nil || e.Event == "" {
+			p.finishTask()
+			fmt.Fprintln(out, string(line))
+			continue
+		}
+
+		switch e.Event {
+		case "play_start":
+			p.finishTask()
+			p.play = e.Play
+			fmt.Fprintf(out, "PLAY %s\n", e.Play)
+		case "task_start":
+			p.finishTask()
+			p.task = e.Task
+		case "result":
+			result := e.TaskResult
+			result.Play = p.play
+			run.Results = append(run.Results, result)
+			p.results = append(p.results, result)
+		case "stats":
+			p.finishTask()
+			run.Stats = e.Hosts
+		}
+	}
+	p.finishTask()
+	return run, scanner.Err()
+}
+
+// progress prints one line per task, once all its results are in
+type progress struct {
+	out     io.Writer
+	play    string
+	task    string
+	results []TaskResult
+}
+
+// finishTask prints the line of the current task, if any
+func (p *progress) finishTask() {
+	if p.task == "" && len(p.results) == 0 {
+		return
+	}
+	defer func() { p.task, p.results = "", nil }()
+
+	counts := map[string][]string{}
+	for _, result := range p.results {
+		counts[result.Status] = append(counts[result.Status], result.Host)
+	}
+
+	symbol, summary := "✓", ""
+	switch {
+	case len(counts[StatusFailed])+len(counts[StatusUnreachable]) > 0:
+		failed := append(append([]string{}, counts[StatusFailed]...), counts[StatusUnreachable]...)
+		sort.Strings(failed)
+		symbol, summary = "✗", "failed on "+strings.Join(failed, ", ")
+		for _, result := range p.results {
+			if result.Status == StatusFailed && result.IgnoreErrors {
+				symbol, summary = "⚠", summary+", ignored"
+				break
+			}
+		}
+	case len(counts[StatusChanged]) > 0:
+		summary = fmt.Sprintf("changed on %d/%d", len(counts[StatusChanged]), len(p.results))
+	case len(p.results) > 0 && len(counts[StatusSkipped]) == len(p.results):
+		symbol, summary = "-", "skipped"
+	case len(p.results) == 0:
+		symbol = "-"
+	}
+
+	line := "  " + symbol + " " + p.task
+	if summary != "" {
+		line += " (" + summary + ")"
+	}
+	fmt.Fprintln(p.out, line)
+
+	for _, result := range p.results {
+		if result.Output != nil {
+			printOutput(p.out, result.Host, result.Output)
+		}
+		if result.Failed() {
+			if result.Msg != "" {
+				fmt.Fprintf(p.out, "      %s: %s\n", result.Host, result.Msg)
+			}
+			if result.Stderr != "" {
+				fmt.Fprintf(p.out, "      %s stderr: %s\n", result.Host, strings.TrimSpace(result.Stderr))
+			}
+		}
+	}
+}
+
+// printOutput writes what a debug task printed, indented below the task
+func printOutput(w io.Writer, host string, output interface{}) {
+	var text string
+	switch v := output.(type) {
+	case string:
+		text = v
+	case []interface{}:
+		lines := make([]string, 0, len(v))
+		for _, line := range v {
+			lines = append(lines, fmt.Sprint(line))
+		}
+		text = strings.Join(lines, "\n")
+	default:
+		data, _ := json.MarshalIndent(v, "", "  ")
+		text = string(data)
+	}
+	fmt.Fprintf(w, "      %s:\n", host)
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		fmt.Fprintf(w, "        %s\n", line)
+	}
+}
@@ -0,0 +1,86 @@
+package ansible
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// sampleEvents is the output of the provision_events callback for a run in
+// which the worker join fails on node02
+const sampleEvents = `{"event": "play_start", "play": "Kubernetes setup for all nodes"}
+{"event": "task_start", "task": "Install containerd", "host": "", "status": ""}
+{"event": "result", "status": "changed", "host": "controlplane", "task": "Install containerd", "msg": "", "stdout": "", "stderr": "", "rc": null, "ignore_errors": false}
+{"event": "result", "status": "ok", "host": "node01", "task": "Install containerd", "msg": "", "stdout": "", "stderr": "", "rc": null, "ignore_errors": false}
+{"event": "task_start", "task": "Show join command"}
+{"event": "result", "status": "ok", "host": "controlplane", "task": "Show join command", "msg": "Hello", "output": ["kubeadm join", "--token abc"]}
+{"event": "task_start", "task": "Check swap"}
+{"event": "result", "status": "failed", "host": "node01", "task": "Check swap", "msg": "non-zero return code", "ignore_errors": true}
+[WARNING]: not an event
+{"event": "play_start", "play": "Join workers"}
+{"event": "task_start", "task": "Join the cluster"}
+{"event": "result", "status": "failed", "host": "node02", "task": "Join the cluster", "msg": "non-zero return code", "stdout": "", "stderr": "error execution phase preflight: connection refused\n", "rc": 1, "ignore_errors": false}
+{"event": "result", "status": "unreachable", "host": "node03", "task": "Join the cluster", "msg": "Failed to connect to the host via ssh"}
+{"event": "stats", "hosts": {"node02": {"ok": 3, "changed": 1, "failures": 1, "unreachable": 0, "skipped": 0, "rescued": 0, "ignored": 0}}}
+`
+
+func TestParseEvents(t *testing.T) {
+	var out bytes.Buffer
+	run, err := parseEvents(strings.NewReader(sampleEvents), &out)
+	if err != nil {
+		t.Fatalf("parseEvents() error = %v", err)
+	}
+
+	if len(run.Results) != 6 {
+		t.Fatalf("Results = %+v, want 6", run.Results)
+	}
+	join := run.Results[4]
+	if join.Play != "Join workers" || join.Task != "Join the cluster" || join.Host != "node02" || join.RC == nil || *join.RC != 1 {
+		t.Errorf("Results[4] = %+v, want the failed join on node02 with rc 1", join)
+	}
+	if run.Stats["node02"].Failures != 1 || run.Stats["node02"].OK != 3 {
+		t.Errorf("Stats = %+v, want the recap of node02", run.Stats)
+	}
+
+	// The ignored failure does not count
+	failures := run.Failures()
+	if len(failures) != 2 || failures[0].Host != "node02" || failures[1].Host != "node03" {
+		t.Errorf("Failures() = %+v, want node02 and node03", failures)
+	}
+
+	for _, want := range []string{
+		"PLAY Kubernetes setup for all nodes\n",
+		"  ✓ Install containerd (changed on 1/2)\n",
+		"  ✓ Show join command\n      controlplane:\n        kubeadm join\n        --token abc\n",
+		"  ⚠ Check swap (failed on node01, ignored)\n",
+		"[WARNING]: not an event\n",
+		"  ✗ Join the cluster (failed on node02, node03)\n",
+		"      node02 stderr: error execution phase preflight: connection refused\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("progress does not contain %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestPlaybookError(t *testing.T) {
+	run, _ := parseEvents(strings.NewReader(sampleEvents), &bytes.Buffer{})
+	var err error = newPlaybookError(run.Failures())
+
+	var playbookErr *PlaybookError
+	if !errors.As(err, &playbookErr) {
+		t.Fatalf("error %v is not a *PlaybookError", err)
+	}
+	want := `task "Join the cluster" failed on node02 (play "Join workers"): non-zero return code` +
+		"\nstderr: error execution phase preflight: connection refused" +
+		"\n(1 more task result(s) failed)"
+	if err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+
+	unreachable := newPlaybookError(run.Failures()[1:])
+	if !unreachable.Unreachable || !strings.Contains(unreachable.Error(), "could not reach the host") {
+		t.Errorf("Error() = %q, want the host reported unreachable", unreachable.Error())
+	}
+}
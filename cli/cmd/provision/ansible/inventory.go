@@ -0,0 +1,174 @@
+package ansible
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Inventory is an Ansible YAML inventory. Groups, hosts and variables are
+// rendered in the order they were added.
+type Inventory struct {
+	all *Group
+}
+
+// Group is an inventory group with hosts, child groups and group vars
+type Group struct {
+	Name     string
+	Hosts    []Host
+	Children []*Group
+	Vars     map[string]interface{}
+}
+
+// Host is an inventory host with its host vars
+type Host struct {
+	Name string
+	Vars map[string]interface{}
+}
+
+// NewInventory returns an inventory with an empty "all" group
+func NewInventory() *Inventory {
+	return &Inventory{all: &Group{Name: "all"}}
+}
+
+// All returns the top-level "all" group
+func (inv *Inventory) All() *Group {
+	return inv.all
+}
+
+// AddChild adds an empty child group and returns it
+func (g *Group) AddChild(name string) *Group {
+	child := &Group{Name: name}
+	g.Children = append(g.Children, child)
+	return child
+}
+
+// AddHost adds a host with the given host vars
+func (g *Group) AddHost(name string, vars map[string]interface{}) {
+	g.Hosts = append(g.Hosts, Host{Name: name, Vars: vars})
+}
+
+// SetVar sets a group variable
+func (g *Group) SetVar(key string, value interface{}) {
+	if g.Vars == nil {
+		g.Vars = make(map[string]interface{})
+	}
+	g.Vars[key] = value
+}
+
+// Marshal renders the inventory as YAML
+func (inv *Inventory) Marshal() ([]byte, error) {
+	root, err := groupNode(inv.all)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &yaml.Node{Kind: yaml.MappingNode}
+	doc.Content = append(doc.Content, scalarNode(inv.all.Name), root)
+
+	var buf bytes.Buffer
+	buf.WriteString("---\n")
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return nil, fmt.Errorf("failed to marshal inventory: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to marshal inventory: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteFile renders the inventory to path, creating its directory
+func (inv *Inventory) WriteFile(path string) error {
+	data, err := inv.Marshal()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create inventory directory: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write inventory file: %w", err)
+	}
+	return nil
+}
+
+// groupNode builds the mapping for a group, leaving out empty sections
+func groupNode(g *Group) (*yaml.Node, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+
+	if len(g.Hosts) > 0 {
+		hosts := &yaml.Node{Kind: yaml.MappingNode}
+		for _, host := range g.Hosts {
+			vars, err := varsNode(host.Vars)
+			if err != nil {
+				return nil, fmt.Errorf("host %s: %w", host.Name, err)
+			}
+			hosts.Content = append(hosts.Content, scalarNode(host.Name), vars)
+		}
+		node.Content = append(node.Content, scalarNode("hosts"), hosts)
+	}
+
+	if len(g.Children) > 0 {
+		children := &yaml.Node{Kind: yaml.MappingNode}
+		for _, child := range g.Children {
+			childNode, err := groupNode(child)
+			if err != nil {
+				return nil, err
+			}
+			children.Content = append(children.Content, scalarNode(child.Name), childNode)
+		}
+		node.Content = append(node.Content, scalarNode("children"), children)
+	}
+
+	if len(g.Vars) > 0 {
+		vars, err := varsNode(g.Vars)
+		if err != nil {
+			return nil, fmt.Errorf("group %s: %w", g.Name, err)
+		}
+		node.Content = append(node.Content, scalarNode("vars"), vars)
+	}
+
+	return node, nil
+}
+
+// varsNode builds a mapping of variables sorted by name
+func varsNode(vars map[string]interface{}) (*yaml.Node, error) {
+	keys := make([]string, 0, len(vars))
+	for key := range vars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, key := range keys {
+		value := &yaml.Node{}
+		if err := value.Encode(vars[key]); err != nil {
+			return nil, fmt.Errorf("failed to encode variable %s: %w", key, err)
+		}
+		node.Content = append(node.Content, scalarNode(key), value)
+	}
+	return node, nil
+}
+
+func scalarNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+// SSHVars returns the connection variables for VMs provisioned with the
+// cloud-init template, which log in as ubuntu with the given key
+func SSHVars(keyPath string) map[string]interface{} {
+	return map[string]interface{}{
+		"ansible_user":                 "ubuntu",
+		"ansible_become":               true,
+		"ansible_ssh_private_key_file": keyPath,
+		"ansible_ssh_common_args":      "-o StrictHostKeyChecking=no",
+	}
+}
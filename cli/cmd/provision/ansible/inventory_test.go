@@ -0,0 +1,78 @@
+package ansible
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestInventoryMarshal(t *testing.T) {
+	inventory := NewInventory()
+	cluster := inventory.All().AddChild("cluster")
+	cluster.AddChild("leader").AddHost("node1", map[string]interface{}{"ansible_host": "10.0.0.10"})
+	followers := cluster.AddChild("followers")
+	followers.AddHost("node2", map[string]interface{}{"ansible_host": "10.0.0.11"})
+	followers.AddHost("node3", map[string]interface{}{"ansible_host": "10.0.0.12", "node_id": 3})
+	cluster.SetVar("nodes", []string{"node1", "node2", "node3"})
+	inventory.All().Vars = SSHVars("/home/user/.ssh/key")
+
+	data, err := inventory.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	// Groups keep the order they were added in
+	if strings.Index(string(data), "leader:") > strings.Index(string(data), "followers:") {
+		t.Errorf("leader group should come before followers:\n%s", data)
+	}
+
+	var parsed struct {
+		All struct {
+			Children map[string]struct {
+				Children map[string]struct {
+					Hosts map[string]map[string]interface{} `yaml:"hosts"`
+				} `yaml:"children"`
+				Vars map[string][]string `yaml:"vars"`
+			} `yaml:"children"`
+			Vars map[string]interface{} `yaml:"vars"`
+		} `yaml:"all"`
+	}
+	if err := yaml.Unmarshal(data, &parsed); err != nil {
+		t.Fatalf("Marshal() produced invalid YAML: %v\n%s", err, data)
+	}
+
+	groups := parsed.All.Children["cluster"].Children
+	if groups["leader"].Hosts["node1"]["ansible_host"] != "10.0.0.10" {
+		t.Errorf("leader host = %v, want node1 at 10.0.0.10", groups["leader"].Hosts)
+	}
+	if len(groups["followers"].Hosts) != 2 || groups["followers"].Hosts["node3"]["node_id"] != 3 {
+		t.Errorf("followers hosts = %v", groups["followers"].Hosts)
+	}
+	if nodes := parsed.All.Children["cluster"].Vars["nodes"]; len(nodes) != 3 || nodes[0] != "node1" {
+		t.Errorf("nodes group var = %v, want [node1 node2 node3]", nodes)
+	}
+	if parsed.All.Vars["ansible_become"] != true || parsed.All.Vars["ansible_ssh_private_key_file"] != "/home/user/.ssh/key" {
+		t.Errorf("all vars = %v", parsed.All.Vars)
+	}
+}
+
+func TestInventoryWriteFile(t *testing.T) {
+	inventory := NewInventory()
+	inventory.All().AddHost("node1", nil)
+
+	path := filepath.Join(t.TempDir(), "state", "cluster", "inventory.yml")
+	if err := inventory.WriteFile(path); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("inventory not written: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "---\nall:\n") {
+		t.Errorf("inventory = %q, want a document starting with all:", data)
+	}
+}
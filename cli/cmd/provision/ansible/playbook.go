@@ -2,37 +2,107 @@ package ansible
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 )
 
 // RunPlaybook executes an Ansible playbook with the given inventory
 func RunPlaybook(playbook, inventory string, extraArgs []string) error {
+	return RunPlaybookWithLog(playbook, inventory, extraArgs, nil)
+}
+
+// RunPlaybookWithLog executes an Ansible playbook like RunPlaybook and, when
+// log is not nil, also writes the command and its output to log
+func RunPlaybookWithLog(playbook, inventory string, extraArgs []string, log io.Writer) error {
+	_, err := Execute(playbook, inventory, extraArgs, log)
+	return err
+}
+
+// Execute runs an Ansible playbook and returns the result of every task on
+// every host. With the provision_events callback in callback_plugins next to
+// the playbook, a line per task is printed instead of the full output, and
+// when ansible-playbook fails the failed task is returned as a
+// *PlaybookError. Without it, the plain output is shown and no results are
+// returned. When log is not nil, the command and
+// what is printed are written to log too.
+func Execute(playbook, inventory string, extraArgs []string, log io.Writer) (*Run, error) {
 	// Check if ansible-playbook is available
 	_, err := exec.LookPath("ansible-playbook")
 	if err != nil {
-		return fmt.Errorf("ansible-playbook command not found: %w", err)
+		return nil, fmt.Errorf("ansible-playbook command not found, run provision-cli doctor for installation steps: %w", err)
 	}
 
 	// Build the command
+	args := PlaybookArgs(playbook, inventory, extraArgs)
+	cmd := exec.Command("ansible-playbook", args...)
+
+	// Connect the command's outputs to our process's outputs
+	var stdout, stderr io.Writer = os.Stdout, os.Stderr
+	if log != nil {
+		stdout, stderr = io.MultiWriter(os.Stdout, log), io.MultiWriter(os.Stderr, log)
+	}
+	cmd.Stderr = stderr
+	cmd.Stdin = os.Stdin
+	fmt.Fprintf(stdout, "Running: ansible-playbook %v\n", args)
+
+	pluginDir := filepath.Join(filepath.Dir(playbook), "callback_plugins")
+	if _, err := os.Stat(filepath.Join(pluginDir, CallbackName+".py")); err != nil {
+		cmd.Stdout = stdout
+		if err := cmd.Run(); err != nil {
+			return &Run{}, fmt.Errorf("ansible-playbook failed: %w", err)
+		}
+		return &Run{}, nil
+	}
+
+	if plugins := os.Getenv("ANSIBLE_CALLBACK_PLUGINS"); plugins != "" {
+		pluginDir += string(os.PathListSeparator) + plugins
+	}
+	cmd.Env = append(os.Environ(),
+		"ANSIBLE_STDOUT_CALLBACK="+CallbackName,
+		"ANSIBLE_CALLBACK_PLUGINS="+pluginDir)
+
+	events, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run ansible-playbook: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to run ansible-playbook: %w", err)
+	}
+
+	run, parseErr := parseEvents(events, stdout)
+	// Keep reading when parsing stopped early, so the command can exit
+	io.Copy(io.Discard, events)
+	waitErr := cmd.Wait()
+
+	// The exit status decides, as tasks failing inside a rescued block
+	// are reported as failed too
+	if waitErr != nil {
+		if failures := run.Failures(); len(failures) > 0 {
+			return run, newPlaybookError(failures)
+		}
+		return run, fmt.Errorf("ansible-playbook failed: %w", waitErr)
+	}
+	if parseErr != nil {
+		return run, fmt.Errorf("failed to read ansible-playbook output: %w", parseErr)
+	}
+	return run, nil
+}
+
+// PlaybookArgs returns the ansible-playbook arguments RunPlaybook uses
+func PlaybookArgs(playbook, inventory string, extraArgs []string) []string {
 	args := []string{"-i", inventory}
 
 	// Add any extra arguments
 	args = append(args, extraArgs...)
 
 	// Add the playbook at the end
-	args = append(args, playbook)
-
-	// Create the command
-	cmd := exec.Command("ansible-playbook", args...)
-
-	// Connect the command's outputs to our process's outputs
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin
-
-	// Run the command
-	fmt.Printf("Running: ansible-playbook %v\n", args)
-	return cmd.Run()
+	return append(args, playbook)
 }
 
+// ExtraVarsFile returns the arguments that load a YAML file as extra vars,
+// which take precedence over the playbook's vars_files
+func ExtraVarsFile(path string) []string {
+	return []string{"-e", "@" + path}
+}
@@ -2,6 +2,13 @@
 package ansible
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -11,3 +18,106 @@ func TestAnsiblePlaybookExistence(t *testing.T) {
 	var _ func(string, string, []string) error = RunPlaybook
 }
 
+
+func TestExtraVarsFile(t *testing.T) {
+	args := ExtraVarsFile("/tmp/config.yml")
+	if len(args) != 2 || args[0] != "-e" || args[1] != "@/tmp/config.yml" {
+		t.Errorf("ExtraVarsFile() = %v, want [-e @/tmp/config.yml]", args)
+	}
+}
+
+func TestPlaybookArgs(t *testing.T) {
+	args := PlaybookArgs("site.yml", "inventory.yml", ExtraVarsFile("config.yml"))
+	want := "-i inventory.yml -e @config.yml site.yml"
+	if got := strings.Join(args, " "); got != want {
+		t.Errorf("PlaybookArgs() = %q, want %q", got, want)
+	}
+}
+
+// fakeAnsible puts an ansible-playbook script in PATH that prints
+// sampleEvents and exits with code when the callback is selected
+func fakeAnsible(t *testing.T, code int) {
+	t.Helper()
+	fakeAnsibleEvents(t, sampleEvents, code)
+}
+
+// fakeAnsibleEvents is fakeAnsible printing events instead
+func fakeAnsibleEvents(t *testing.T, events string, code int) {
+	t.Helper()
+	dir := t.TempDir()
+	script := fmt.Sprintf(`#!/bin/sh
+[ "$ANSIBLE_STDOUT_CALLBACK" = %s ] || { echo "plain output"; exit %d; }
+cat <<'EOF'
+%sEOF
+exit %d
+`, CallbackName, code, events, code)
+	if err := os.WriteFile(filepath.Join(dir, "ansible-playbook"), []byte(script), 0o755); err != nil {
+		t.Fatalf("Failed to write fake ansible-playbook: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// playbookWithCallback creates a playbook with the callback plugin next to it
+func playbookWithCallback(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "callback_plugins"), 0o755); err != nil {
+		t.Fatalf("Failed to create callback_plugins: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "callback_plugins", CallbackName+".py"), nil, 0o644); err != nil {
+		t.Fatalf("Failed to write callback plugin: %v", err)
+	}
+	return filepath.Join(dir, "site.yml")
+}
+
+func TestExecute(t *testing.T) {
+	fakeAnsible(t, 2)
+
+	var log bytes.Buffer
+	run, err := Execute(playbookWithCallback(t), "inventory.yml", nil, &log)
+	var playbookErr *PlaybookError
+	if !errors.As(err, &playbookErr) || playbookErr.Host != "node02" || playbookErr.Task != "Join the cluster" {
+		t.Fatalf("Execute() error = %v, want a *PlaybookError for node02", err)
+	}
+	if len(run.Results) != 6 {
+		t.Errorf("Execute() results = %d, want 6", len(run.Results))
+	}
+	if !strings.Contains(log.String(), "✗ Join the cluster") {
+		t.Errorf("log does not contain the progress:\n%s", log.String())
+	}
+
+	// Without the callback plugin the plain output is shown
+	log.Reset()
+	_, err = Execute(filepath.Join(t.TempDir(), "site.yml"), "inventory.yml", nil, &log)
+	if err == nil || errors.As(err, &playbookErr) || !strings.Contains(log.String(), "plain output") {
+		t.Errorf("Execute() error = %v, log = %q, want the plain output and exit status", err, log.String())
+	}
+}
+
+func TestExecuteSucceedsWithRescuedFailures(t *testing.T) {
+	// The sample events hold a failed task, but a rescue block handled it
+	// and ansible-playbook exits 0
+	fakeAnsible(t, 0)
+
+	run, err := Execute(playbookWithCallback(t), "inventory.yml", nil, io.Discard)
+	if err != nil {
+		t.Fatalf("Execute() error = %v, want success as ansible-playbook exited 0", err)
+	}
+	if len(run.Failures()) == 0 {
+		t.Errorf("Execute() results hold no failures, want the reported ones kept")
+	}
+
+	// A later failure that is not rescued is the one reported
+	fakeAnsibleEvents(t, `{"event": "play_start", "play": "Kubernetes setup for all nodes"}
+{"event": "result", "status": "failed", "host": "node01", "task": "Pull images", "msg": "timeout"}
+{"event": "result", "status": "ok", "host": "node01", "task": "Retry pulling images"}
+{"event": "play_start", "play": "Join workers"}
+{"event": "result", "status": "failed", "host": "node02", "task": "Join the cluster", "msg": "non-zero return code"}
+{"event": "stats", "hosts": {"node01": {"rescued": 1}, "node02": {"failures": 1}}}
+`, 2)
+	_, err = Execute(playbookWithCallback(t), "inventory.yml", nil, io.Discard)
+	var playbookErr *PlaybookError
+	if !errors.As(err, &playbookErr) || playbookErr.Task != "Join the cluster" || playbookErr.Host != "node02" {
+		t.Errorf("Execute() error = %v, want the join on node02 reported, not the rescued pull", err)
+	}
+}
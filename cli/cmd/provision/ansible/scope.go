@@ -0,0 +1,84 @@
+package ansible
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ScopeArgs returns the ansible-playbook arguments that run only the plays
+// and tasks with the given tags on the given hosts or groups. Empty lists
+// leave the run unrestricted.
+func ScopeArgs(tags, limit []string) []string {
+	var args []string
+	if len(tags) > 0 {
+		args = append(args, "--tags", strings.Join(tags, ","))
+	}
+	if len(limit) > 0 {
+		args = append(args, "--limit", strings.Join(limit, ","))
+	}
+	return args
+}
+
+// CheckTags returns an error naming the tags that are not in known
+func CheckTags(tags, known []string) error {
+	var unknown []string
+	for _, tag := range tags {
+		if !contains(known, tag) {
+			unknown = append(unknown, tag)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("unknown tag(s) %s, use %s", strings.Join(unknown, ", "), strings.Join(known, ", "))
+	}
+	return nil
+}
+
+// CheckLimit returns an error naming the entries of limit that are neither
+// a host nor a group of the inventory. Entries using Ansible's pattern
+// syntax, such as wildcards or exclusions, are not checked.
+func (inv *Inventory) CheckLimit(limit []string) error {
+	names := inv.Names()
+	var unknown []string
+	for _, pattern := range limit {
+		if strings.ContainsAny(pattern, "*?![]&:~") {
+			continue
+		}
+		if !contains(names, pattern) {
+			unknown = append(unknown, pattern)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("unknown host(s) or group(s) %s, use %s",
+			strings.Join(unknown, ", "), strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// Names returns the names of every group below "all" and every host, in
+// the order they were added
+func (inv *Inventory) Names() []string {
+	var names []string
+	var walk func(g *Group)
+	walk = func(g *Group) {
+		for _, host := range g.Hosts {
+			if !contains(names, host.Name) {
+				names = append(names, host.Name)
+			}
+		}
+		for _, child := range g.Children {
+			names = append(names, child.Name)
+			walk(child)
+		}
+	}
+	walk(inv.all)
+	return names
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
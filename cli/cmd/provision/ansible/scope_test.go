@@ -0,0 +1,44 @@
+package ansible
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScopeArgs(t *testing.T) {
+	args := ScopeArgs([]string{"common", "workers"}, []string{"node02"})
+	if got := strings.Join(args, " "); got != "--tags common,workers --limit node02" {
+		t.Errorf("ScopeArgs() = %q, want --tags common,workers --limit node02", got)
+	}
+	if args := ScopeArgs(nil, nil); len(args) != 0 {
+		t.Errorf("ScopeArgs(nil, nil) = %v, want none", args)
+	}
+}
+
+func TestCheckTags(t *testing.T) {
+	known := []string{"common", "workers"}
+	if err := CheckTags([]string{"workers"}, known); err != nil {
+		t.Errorf("CheckTags() error = %v", err)
+	}
+	if err := CheckTags([]string{"worker", "common"}, known); err == nil || !strings.Contains(err.Error(), "worker, use common, workers") {
+		t.Errorf("CheckTags() error = %v, want the unknown tag and the known ones", err)
+	}
+}
+
+func TestCheckLimit(t *testing.T) {
+	inventory := NewInventory()
+	cluster := inventory.All().AddChild("k8s_cluster")
+	cluster.AddChild("control_plane").AddHost("controlplane", nil)
+	cluster.AddChild("workers").AddHost("node01", nil)
+
+	if got := strings.Join(inventory.Names(), ","); got != "k8s_cluster,control_plane,controlplane,workers,node01" {
+		t.Errorf("Names() = %s", got)
+	}
+
+	if err := inventory.CheckLimit([]string{"node01", "control_plane", "node*", "!node01"}); err != nil {
+		t.Errorf("CheckLimit() error = %v", err)
+	}
+	if err := inventory.CheckLimit([]string{"node02"}); err == nil || !strings.Contains(err.Error(), "node02") {
+		t.Errorf("CheckLimit() error = %v, want node02 reported", err)
+	}
+}
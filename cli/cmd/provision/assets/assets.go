@@ -0,0 +1,168 @@
+// Package assets embeds the playbooks, scripts, cloud-init template and Helm
+// chart of the repository so the binary can run outside a checkout. They are
+// extracted to a cache directory named after their content on first use.
+package assets
+
+//go:generate go run sync.go
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrNotEmbedded is returned when the binary was built without running
+// go generate, so it carries no assets
+var ErrNotEmbedded = errors.New("assets are not embedded in this binary, build it with build.sh")
+
+// files holds a copy of the ansible, scripts, multipass and helm directories
+// made by go generate. Its .gitignore keeps the
+// copy out of git and lets the package build before the copy exists.
+//
+//go:embed all:files
+var files embed.FS
+
+var (
+	extractOnce sync.Once
+	extractDir  string
+	extractErr  error
+)
+
+// Dir returns the directory holding the extracted assets, extracting them
+// the first time it is called
+func Dir() (string, error) {
+	extractOnce.Do(func() {
+		fsys, err := fs.Sub(files, "files")
+		if err != nil {
+			extractErr = err
+			return
+		}
+
+		cacheRoot, err := CacheRoot()
+		if err != nil {
+			extractErr = err
+			return
+		}
+		extractDir, extractErr = extract(fsys, cacheRoot)
+	})
+	return extractDir, extractErr
+}
+
+// CacheRoot returns the directory the assets of every version are extracted
+// to, $XDG_CACHE_HOME/provision-cli/assets or ~/.cache/provision-cli/assets
+func CacheRoot() (string, error) {
+	cacheHome, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get cache directory: %w", err)
+	}
+	return filepath.Join(cacheHome, "provision-cli", "assets"), nil
+}
+
+// extract writes the assets in fsys to a directory in cacheRoot named after
+// their version and returns it. An existing extraction of the same version
+// is reused. The files are written to a temporary directory first, so an
+// interrupted run never leaves a partial copy behind.
+func extract(fsys fs.FS, cacheRoot string) (string, error) {
+	if _, err := fs.Stat(fsys, "ansible"); err != nil {
+		return "", ErrNotEmbedded
+	}
+
+	version, err := Version(fsys)
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(cacheRoot, version)
+	if _, err := os.Stat(dir); err == nil {
+		return dir, nil
+	}
+
+	if err := os.MkdirAll(cacheRoot, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create asset cache: %w", err)
+	}
+	tmpDir, err := os.MkdirTemp(cacheRoot, version+".tmp-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create asset cache: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := copyFS(fsys, tmpDir); err != nil {
+		return "", fmt.Errorf("failed to extract assets: %w", err)
+	}
+
+	// Another process may have extracted the same version meanwhile
+	if err := os.Rename(tmpDir, dir); err != nil {
+		if _, statErr := os.Stat(dir); statErr == nil {
+			return dir, nil
+		}
+		return "", fmt.Errorf("failed to extract assets: %w", err)
+	}
+	return dir, nil
+}
+
+// copyFS writes every file of fsys below dir. Shell scripts are made
+// executable since embedding does not keep file modes.
+func copyFS(fsys fs.FS, dir string) error {
+	return fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if entry.IsDir() {
+			return os.MkdirAll(target, 0o755)
+		}
+
+		mode := fs.FileMode(0o644)
+		if strings.HasSuffix(name, ".sh") {
+			mode = 0o755
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, mode)
+	})
+}
+
+// Version identifies the content of the assets in fsys, so a binary with
+// changed assets extracts them to a new directory
+func Version(fsys fs.FS) (string, error) {
+	var names []string
+	err := fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			names = append(names, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to read assets: %w", err)
+	}
+	sort.Strings(names)
+
+	hash := sha256.New()
+	for _, name := range names {
+		file, err := fsys.Open(name)
+		if err != nil {
+			return "", fmt.Errorf("failed to read assets: %w", err)
+		}
+		fmt.Fprintf(hash, "%s\x00", path.Clean(name))
+		_, err = io.Copy(hash, file)
+		file.Close()
+		if err != nil {
+			return "", fmt.Errorf("failed to read assets: %w", err)
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:12], nil
+}
@@ -0,0 +1,62 @@
+package assets
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func testAssets() fstest.MapFS {
+	return fstest.MapFS{
+		"ansible/playbooks/kubernetes.yml": {Data: []byte("- hosts: all\n")},
+		"scripts/provision-kubernetes.sh":  {Data: []byte("#!/bin/bash\n")},
+		"multipass/cloud-init/common.yaml": {Data: []byte("#cloud-config\n")},
+	}
+}
+
+func TestExtract(t *testing.T) {
+	cacheRoot := t.TempDir()
+
+	dir, err := extract(testAssets(), cacheRoot)
+	if err != nil {
+		t.Fatalf("extract() error = %v", err)
+	}
+	if filepath.Dir(dir) != cacheRoot {
+		t.Errorf("extract() = %s, want a directory in %s", dir, cacheRoot)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "ansible", "playbooks", "kubernetes.yml"))
+	if err != nil || string(data) != "- hosts: all\n" {
+		t.Errorf("extracted playbook = %q (%v), want the embedded content", data, err)
+	}
+	info, err := os.Stat(filepath.Join(dir, "scripts", "provision-kubernetes.sh"))
+	if err != nil || info.Mode().Perm()&0o100 == 0 {
+		t.Errorf("extracted script is not executable: %v %v", info, err)
+	}
+
+	// The same content is extracted once, other content gets its own directory
+	again, err := extract(testAssets(), cacheRoot)
+	if err != nil || again != dir {
+		t.Errorf("second extract() = %s (%v), want %s", again, err, dir)
+	}
+	changed := testAssets()
+	changed["ansible/playbooks/kubernetes.yml"] = &fstest.MapFile{Data: []byte("- hosts: workers\n")}
+	other, err := extract(changed, cacheRoot)
+	if err != nil || other == dir {
+		t.Errorf("extract() of changed assets = %s (%v), want a new directory", other, err)
+	}
+
+	entries, _ := os.ReadDir(cacheRoot)
+	if len(entries) != 2 {
+		t.Errorf("cache entries = %d, want one per version without temporary directories", len(entries))
+	}
+}
+
+func TestExtractNotEmbedded(t *testing.T) {
+	empty := fstest.MapFS{".gitignore": {Data: []byte("/ansible/\n")}}
+	if _, err := extract(empty, t.TempDir()); !errors.Is(err, ErrNotEmbedded) {
+		t.Errorf("extract() error = %v, want ErrNotEmbedded", err)
+	}
+}
@@ -0,0 +1,63 @@
+//go:build ignore
+
+// sync copies the repository's asset directories into files/ so they are
+// embedded into the binary. It is run by go generate in the assets package.
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// repoRoot is the repository root relative to the assets package
+const repoRoot = "../../../.."
+
+// dirs are the top-level repository directories that are embedded
+var dirs = []string{"ansible", "scripts", "multipass", "helm"}
+
+func main() {
+	for _, dir := range dirs {
+		target := filepath.Join("files", dir)
+		if err := os.RemoveAll(target); err != nil {
+			fail(err)
+		}
+		if err := copyDir(filepath.Join(repoRoot, dir), target); err != nil {
+			fail(err)
+		}
+	}
+	fmt.Printf("Copied %v into files/\n", dirs)
+}
+
+// copyDir copies the files below src to dst, keeping their modes
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if entry.IsDir() {
+			return os.MkdirAll(target, 0o755)
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, info.Mode().Perm())
+	})
+}
+
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "failed to copy assets: %v\n", err)
+	os.Exit(1)
+}
@@ -1,49 +1,165 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/component"
 	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/interactive"
-	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/kubernetes"
 	"github.com/spf13/cobra"
 )
 
+// cleanupOptions holds the flags of the cleanup commands
+type cleanupOptions struct {
+	configFile string
+	name       string
+	yes        bool
+	teardown   bool
+	dryRun     string
+}
+
+var cleanupOpts cleanupOptions
+
 var cleanupCmd = &cobra.Command{
 	Use:   "cleanup",
 	Short: "Clean up provisioned resources",
-	Long:  `Clean up provisioned infrastructure components like Kubernetes clusters etc.`,
+	Long: `Clean up provisioned infrastructure components like Kubernetes clusters etc.
+
+Run without a subcommand to choose a component interactively, or use one of
+the subcommands with --yes to clean up without prompts. The nodes and settings
+recorded when the cluster was provisioned are used; --config is only needed
+for clusters provisioned without the CLI.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		if !interactive.IsTerminal() {
+			exitWithError("No component specified",
+				errors.New("stdin is not a terminal, use one of the cleanup subcommands"))
+		}
 		cleanupInteractive()
 	},
 }
 
+func init() {
+	cleanupCmd.PersistentFlags().StringVarP(&cleanupOpts.configFile, "config", "c", "",
+		"YAML file with the settings the cluster was provisioned with")
+	cleanupCmd.PersistentFlags().StringVar(&cleanupOpts.name, "name", "",
+		"Name of the cluster to clean up (default: the component name)")
+	cleanupCmd.PersistentFlags().BoolVarP(&cleanupOpts.yes, "yes", "y", false,
+		"Skip confirmation prompts")
+	addDryRunFlag(cleanupCmd.PersistentFlags(), &cleanupOpts.dryRun)
+	cleanupCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		checkDryRun(cleanupOpts.dryRun)
+	}
+
+	for _, c := range component.All() {
+		cleanupCmd.AddCommand(newCleanupComponentCmd(c))
+	}
+}
+
+// newCleanupComponentCmd builds the cleanup subcommand of a component.
+// Components that can tear down their software get a --teardown flag.
+func newCleanupComponentCmd(c component.Component) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   c.Name(),
+		Short: fmt.Sprintf("Delete the %s VMs", c.Title()),
+		Long: fmt.Sprintf(`Delete the %s VMs and remove the recorded state.
+
+Without --name the default cluster, named %s, is cleaned up. With --dry-run,
+the VMs and files that would be removed are printed instead.`, c.Title(), c.Name()),
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if cleanupOpts.dryRun != "" {
+				planCleanup(c, cleanupOpts.teardown)
+				return
+			}
+
+			message := fmt.Sprintf("Are you sure you want to clean up %s %s?", c.Title(), clusterName(c, cleanupOpts.name))
+			proceed, err := confirmProceed(cleanupOpts.yes, message)
+			if err != nil {
+				exitWithError("Failed to get confirmation", err)
+			}
+			if !proceed {
+				fmt.Println("Cleanup cancelled")
+				return
+			}
+			cleanupComponent(c, cleanupOpts.teardown)
+		},
+	}
+
+	if _, ok := c.(component.TearDowner); ok {
+		cmd.Long += "\n\nWith --teardown, the software is first removed cleanly from the VMs."
+		cmd.Flags().BoolVar(&cleanupOpts.teardown, "teardown", false,
+			"Remove the software from the VMs before deleting them")
+	}
+	return cmd
+}
+
 func cleanupInteractive() {
-	options := []string{"Kubernetes Cluster", "PostgreSQL Cluster"}
-	choice, err := interactive.PromptSelect("What would you like to clean up?", options)
+	c, err := selectComponent("What would you like to clean up?")
 	if err != nil {
 		exitWithError("Failed to get user input", err)
 	}
 
-	confirmed, err := interactive.PromptConfirm(fmt.Sprintf("Are you sure you want to clean up %s?", choice))
-	if err != nil {
-		exitWithError("Failed to get confirmation", err)
+	if cleanupOpts.dryRun == "" {
+		message := fmt.Sprintf("Are you sure you want to clean up %s %s?", c.Title(), clusterName(c, cleanupOpts.name))
+		confirmed, err := interactive.PromptConfirm(message)
+		if err != nil {
+			exitWithError("Failed to get confirmation", err)
+		}
+		if !confirmed {
+			fmt.Println("Cleanup cancelled")
+			return
+		}
 	}
 
-	if !confirmed {
-		fmt.Println("Cleanup cancelled")
+	teardown := false
+	if _, ok := c.(component.TearDowner); ok {
+		teardown, err = interactive.PromptConfirm("Remove the software from the VMs cleanly before deleting them?")
+		if err != nil {
+			exitWithError("Failed to get user input", err)
+		}
+	}
+
+	if cleanupOpts.dryRun != "" {
+		planCleanup(c, teardown)
 		return
 	}
+	cleanupComponent(c, teardown)
+}
 
-	switch choice {
-	case "Kubernetes Cluster":
-		fmt.Println("Starting Kubernetes cluster cleanup...")
-		if err := kubernetes.Cleanup(); err != nil {
-			exitWithError("Failed to clean up Kubernetes cluster", err)
+// cleanupComponent deletes the VMs of c, tearing it down first when asked to
+// and the component supports it
+func cleanupComponent(c component.Component, teardown bool) {
+	config := loadRecordedConfig(c, cleanupOpts.configFile, cleanupOpts.name)
+
+	fmt.Printf("Starting %s cleanup...\n", c.Title())
+	if tearDowner, ok := c.(component.TearDowner); ok && teardown {
+		if err := tearDowner.TearDown(config); err != nil {
+			exitWithError(fmt.Sprintf("Failed to tear down %s, run cleanup without teardown to only delete the VMs", c.Title()), err)
+		}
+	}
+
+	if err := c.Cleanup(config); err != nil {
+		exitWithError(fmt.Sprintf("Failed to clean up %s", c.Title()), err)
+	}
+	fmt.Printf("%s cleanup completed successfully\n", c.Title())
+}
+
+// planCleanup prints what cleaning up c would do, including the teardown
+// when asked to and the component supports it
+func planCleanup(c component.Component, teardown bool) {
+	config := loadRecordedConfig(c, cleanupOpts.configFile, cleanupOpts.name)
+
+	p, err := c.PlanCleanup(config)
+	if err != nil {
+		exitWithError(fmt.Sprintf("Failed to plan %s cleanup", c.Title()), err)
+	}
+	if tearDowner, ok := c.(component.TearDowner); ok && teardown {
+		teardownPlan, err := tearDowner.PlanTearDown(config)
+		if err != nil {
+			exitWithError(fmt.Sprintf("Failed to plan %s teardown", c.Title()), err)
 		}
-		fmt.Println("Kubernetes cluster cleanup completed successfully")
-	case "PostgreSQL Cluster":
-		fmt.Println("PostgreSQL cluster cleanup is not implemented yet")
-	default:
-		fmt.Println("Invalid choice")
+		teardownPlan.Include(p)
+		p = teardownPlan
 	}
+	printPlan(p, cleanupOpts.dryRun)
 }
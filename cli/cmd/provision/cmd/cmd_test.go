@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"testing"
+
+	"github.com/spf13/cobra"
 )
 
 func TestRootCmd(t *testing.T) {
@@ -10,8 +12,8 @@ func TestRootCmd(t *testing.T) {
 		t.Fatalf("rootCmd is nil")
 	}
 
-	if rootCmd.Use != "provision" {
-		t.Errorf("rootCmd.Use = %q, want \"provision\"", rootCmd.Use)
+	if rootCmd.Use != "provision-cli" {
+		t.Errorf("rootCmd.Use = %q, want \"provision-cli\"", rootCmd.Use)
 	}
 
 	if rootCmd.Short == "" {
@@ -46,6 +48,12 @@ func TestSubCommands(t *testing.T) {
 	if !findCmd("cleanup") {
 		t.Errorf("\"cleanup\" command not found in rootCmd")
 	}
+
+	for _, name := range []string{"list", "describe <cluster>", "doctor", "converge", "validate", "profile", "config"} {
+		if !findCmd(name) {
+			t.Errorf("%q command not found in rootCmd", name)
+		}
+	}
 }
 
 func TestExecuteFunction(t *testing.T) {
@@ -107,3 +115,148 @@ func TestCleanupCmd(t *testing.T) {
 		t.Errorf("cleanupCmd.Run is nil")
 	}
 }
+
+func TestProvisionSubCommands(t *testing.T) {
+	// Check that each component has a non-interactive subcommand
+	for _, name := range []string{"kubernetes", "rqlite"} {
+		sub, _, err := provisionCmd.Find([]string{name})
+		if err != nil || sub == provisionCmd {
+			t.Errorf("provision %s subcommand not found", name)
+			continue
+		}
+
+		if sub.Run == nil {
+			t.Errorf("provision %s Run is nil", name)
+		}
+	}
+
+	// The shared flags should be available on the subcommands
+	sub, _, _ := provisionCmd.Find([]string{"kubernetes"})
+	for _, flag := range []string{"config", "name", "yes", "force", "dry-run"} {
+		if sub.InheritedFlags().Lookup(flag) == nil {
+			t.Errorf("--%s flag not registered", flag)
+		}
+	}
+
+	// --dry-run defaults to the text plan when given without a value
+	if dryRun := sub.InheritedFlags().Lookup("dry-run"); dryRun != nil && dryRun.NoOptDefVal != "text" {
+		t.Errorf("--dry-run without a value = %q, want text", dryRun.NoOptDefVal)
+	}
+
+	// The component's own flags are registered on its subcommand
+	if sub.Flags().Lookup("worker-count") == nil {
+		t.Errorf("--worker-count flag not registered on provision kubernetes")
+	}
+}
+
+func TestCleanupSubCommands(t *testing.T) {
+	for _, name := range []string{"kubernetes", "rqlite"} {
+		sub, _, err := cleanupCmd.Find([]string{name})
+		if err != nil || sub == cleanupCmd {
+			t.Errorf("cleanup %s subcommand not found", name)
+			continue
+		}
+
+		for _, flag := range []string{"config", "name", "yes", "dry-run"} {
+			if sub.InheritedFlags().Lookup(flag) == nil {
+				t.Errorf("cleanup %s does not inherit --%s", name, flag)
+			}
+		}
+	}
+
+	// Only components that can tear down their software get --teardown
+	for name, want := range map[string]bool{"kubernetes": false, "rqlite": true} {
+		sub, _, _ := cleanupCmd.Find([]string{name})
+		if got := sub.Flags().Lookup("teardown") != nil; got != want {
+			t.Errorf("cleanup %s has --teardown = %t, want %t", name, got, want)
+		}
+	}
+}
+
+func TestStatusCmd(t *testing.T) {
+	if statusCmd.Use != "status" {
+		t.Errorf("Expected Use to be 'status', got '%s'", statusCmd.Use)
+	}
+
+	for _, name := range []string{"kubernetes", "rqlite"} {
+		sub, _, err := statusCmd.Find([]string{name})
+		if err != nil || sub == statusCmd {
+			t.Errorf("status %s subcommand not found", name)
+			continue
+		}
+		if sub.InheritedFlags().Lookup("name") == nil {
+			t.Errorf("status %s does not inherit --name", name)
+		}
+	}
+
+	output := statusCmd.PersistentFlags().Lookup("output")
+	if output == nil || output.Shorthand != "o" || output.DefValue != "table" {
+		t.Errorf("--output flag = %+v, want -o defaulting to table", output)
+	}
+}
+
+func TestConvergeSubCommands(t *testing.T) {
+	for _, name := range []string{"kubernetes", "rqlite"} {
+		sub, _, err := convergeCmd.Find([]string{name})
+		if err != nil || sub == convergeCmd {
+			t.Errorf("converge %s subcommand not found", name)
+			continue
+		}
+
+		for _, flag := range []string{"config", "name", "tags", "limit"} {
+			if sub.InheritedFlags().Lookup(flag) == nil {
+				t.Errorf("converge %s does not inherit --%s", name, flag)
+			}
+		}
+	}
+}
+
+func TestValidateSubCommands(t *testing.T) {
+	for name, flag := range map[string]string{"kubernetes": "pod-cidr", "rqlite": "rqlite-raft-port"} {
+		sub, _, err := validateCmd.Find([]string{name})
+		if err != nil || sub == validateCmd {
+			t.Errorf("validate %s subcommand not found", name)
+			continue
+		}
+		if sub.InheritedFlags().Lookup("config") == nil || sub.Flags().Lookup(flag) == nil {
+			t.Errorf("validate %s does not take --config and --%s", name, flag)
+		}
+	}
+}
+
+func TestProfileSubCommands(t *testing.T) {
+	for _, args := range [][]string{{"list"}, {"show"}, {"delete"}, {"save", "kubernetes"}, {"save", "rqlite"}} {
+		if sub, _, err := profileCmd.Find(args); err != nil || sub.Name() != args[len(args)-1] {
+			t.Errorf("profile %v subcommand not found", args)
+		}
+	}
+
+	save, _, _ := profileCmd.Find([]string{"save", "kubernetes"})
+	if save.InheritedFlags().Lookup("config") == nil || save.Flags().Lookup("worker-count") == nil {
+		t.Errorf("profile save kubernetes does not take --config and --worker-count")
+	}
+
+	for _, parent := range []*cobra.Command{provisionCmd, validateCmd} {
+		if parent.PersistentFlags().Lookup("profile") == nil {
+			t.Errorf("%s has no --profile flag", parent.Name())
+		}
+	}
+}
+
+func TestConfigViewSubCommands(t *testing.T) {
+	for name, flag := range map[string]string{"kubernetes": "worker-count", "rqlite": "node-memory"} {
+		sub, _, err := configCmd.Find([]string{"view", name})
+		if err != nil || sub.Name() != name {
+			t.Errorf("config view %s subcommand not found", name)
+			continue
+		}
+		for _, inherited := range []string{"config", "profile", "show-origin"} {
+			if sub.InheritedFlags().Lookup(inherited) == nil {
+				t.Errorf("config view %s does not inherit --%s", name, inherited)
+			}
+		}
+		if sub.Flags().Lookup(flag) == nil {
+			t.Errorf("config view %s does not take --%s", name, flag)
+		}
+	}
+}
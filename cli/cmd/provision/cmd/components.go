@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/component"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/config"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/interactive"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/profile"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/state"
+	"github.com/spf13/pflag"
+
+	// Components register themselves when their package is imported
+	_ "github.com/bxtal-lsn/kubernetes/cli/cmd/provision/kubernetes"
+	_ "github.com/bxtal-lsn/kubernetes/cli/cmd/provision/rqlite"
+)
+
+// selectComponent asks which registered component to act on
+func selectComponent(message string) (component.Component, error) {
+	components := component.All()
+	choice, err := interactive.PromptSelect(message, component.Titles(components))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range components {
+		if c.Title() == choice {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown component %q", choice)
+}
+
+// loadSettings merges the settings of c, lowest precedence first: the
+// defaults, the profile named profileName, the YAML file at path, the
+// $PROVISION_<COMPONENT>_<KEY> variables and the flags set in fs. The
+// profile, path and fs may be empty. It exits on failure.
+func loadSettings(c component.Component, fs *pflag.FlagSet, profileName, path string) (component.Config, *config.Settings) {
+	sources := component.Sources{ProfileName: profileName, File: path, Flags: fs}
+	if profileName != "" {
+		profilePath, err := profile.Find(c.Name(), profileName)
+		if err != nil {
+			exitWithError(fmt.Sprintf("Failed to load %s profile", c.Title()), err)
+		}
+		sources.Profile = profilePath
+	}
+	return loadSources(c, sources)
+}
+
+// loadSources merges the settings of c from sources and the
+// $PROVISION_<COMPONENT>_<KEY> variables, exiting on failure. What was
+// ignored is reported on stderr, which keeps printed settings parsable.
+func loadSources(c component.Component, sources component.Sources) (component.Config, *config.Settings) {
+	sources.Environ = os.Environ()
+	loaded, settings, err := component.Load(c, sources)
+	if err != nil {
+		exitWithError(fmt.Sprintf("Failed to load %s configuration", c.Title()), err)
+	}
+	for _, warning := range settings.Warnings {
+		fmt.Fprintf(os.Stderr, "⚠ %s\n", warning)
+	}
+	return loaded, settings
+}
+
+// copyConfig returns a copy of config, a pointer to a config struct. Lists
+// are shared, which is safe as prompts replace them instead of changing
+// their items.
+func copyConfig(config component.Config) component.Config {
+	v := reflect.ValueOf(config).Elem()
+	dup := reflect.New(v.Type())
+	dup.Elem().Set(v)
+	return dup.Interface()
+}
+
+// clusterName returns the cluster a command acts on: the one given with
+// --name, or the default cluster named after the component. It exits if the
+// name is invalid or recorded for another component.
+func clusterName(c component.Component, name string) string {
+	if name == "" {
+		return c.Name()
+	}
+	if err := component.ValidateName(name); err != nil {
+		exitWithError("Invalid cluster name", err)
+	}
+	if cluster, err := state.Load(name); err == nil && cluster.Component != c.Name() {
+		exitWithError("Invalid cluster name", fmt.Errorf("cluster %s is a %s cluster", name, cluster.Component))
+	}
+	return name
+}
+
+// applyName makes config describe the cluster given with --name. The
+// default cluster, named after the component, needs no name.
+func applyName(c component.Component, config component.Config, name string) {
+	if name = clusterName(c, name); name != c.Name() {
+		c.SetName(config, name)
+	}
+}
+
+// loadRecordedConfig loads the settings of the named cluster of c from the
+// YAML file at path or, without a path, from the config recorded when the
+// cluster was provisioned, so commands act on the cluster that actually
+// exists. The $PROVISION_<COMPONENT>_<KEY> variables apply on top of either.
+func loadRecordedConfig(c component.Component, path, name string) component.Config {
+	sources := component.Sources{File: path}
+	if path == "" {
+		sources.Recorded, _ = state.ConfigPath(clusterName(c, name))
+	}
+	config, _ := loadSources(c, sources)
+	applyName(c, config, name)
+	return config
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/component"
+	"github.com/spf13/cobra"
+)
+
+// configViewOptions holds the flags of the config view commands
+type configViewOptions struct {
+	configFile string
+	profile    string
+	showOrigin bool
+}
+
+var configViewOpts configViewOptions
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the settings the CLI would use",
+}
+
+var configViewCmd = &cobra.Command{
+	Use:   "view",
+	Short: "Print the effective settings of a component",
+	Long: `Print the settings a provision command with the same options would use,
+as YAML. Settings are merged in this order, later sources taking precedence:
+
+  1. built-in defaults, for settings the defaults file does not set
+  2. ansible/defaults/<component>.yml
+  3. the profile given with --profile
+  4. the file given with --config
+  5. PROVISION_<COMPONENT>_<KEY> environment variables, e.g.
+     PROVISION_KUBERNETES_WORKER_COUNT=1 or
+     PROVISION_RQLITE_DNS_SERVERS=1.1.1.1,9.9.9.9
+  6. individual flags
+
+With --show-origin each setting is followed by a comment naming where its
+value came from.`,
+}
+
+func init() {
+	configViewCmd.PersistentFlags().StringVarP(&configViewOpts.configFile, "config", "c", "",
+		"YAML file with settings that override the defaults")
+	configViewCmd.PersistentFlags().StringVarP(&configViewOpts.profile, "profile", "p", "",
+		"Stored profile whose settings override the defaults, see the profile command")
+	configViewCmd.PersistentFlags().BoolVar(&configViewOpts.showOrigin, "show-origin", false,
+		"Name where each setting came from")
+
+	for _, c := range component.All() {
+		configViewCmd.AddCommand(newConfigViewComponentCmd(c))
+	}
+	configCmd.AddCommand(configViewCmd)
+}
+
+// newConfigViewComponentCmd builds the config view subcommand of a
+// component. It takes the same setting flags as the provision subcommand.
+func newConfigViewComponentCmd(c component.Component) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   c.Name(),
+		Short: fmt.Sprintf("Print the effective %s settings", c.Title()),
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			_, settings := loadSettings(c, cmd.Flags(), configViewOpts.profile, configViewOpts.configFile)
+			if err := settings.Write(os.Stdout, configViewOpts.showOrigin); err != nil {
+				exitWithError(fmt.Sprintf("Failed to print %s configuration", c.Title()), err)
+			}
+		},
+	}
+	c.AddFlags(cmd.Flags())
+	return cmd
+}
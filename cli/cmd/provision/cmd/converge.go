@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/component"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/state"
+	"github.com/spf13/cobra"
+)
+
+// convergeOptions holds the flags of the converge commands
+type convergeOptions struct {
+	configFile string
+	name       string
+	tags       []string
+	limit      []string
+}
+
+var convergeOpts convergeOptions
+
+var convergeCmd = &cobra.Command{
+	Use:   "converge",
+	Short: "Re-run the playbook of a provisioned cluster",
+	Long: `Re-run the Ansible playbook of a provisioned cluster on its existing VMs.
+
+No VMs are created or deleted. Use --tags to only run some phases, e.g. the one
+that failed, and --limit to only run on some nodes or groups. The settings
+recorded when the cluster was provisioned are used, with the values of the
+--config file and the PROVISION_<COMPONENT>_<KEY> environment variables
+applied on top, so changed settings can be re-applied.`,
+}
+
+func init() {
+	convergeCmd.PersistentFlags().StringVarP(&convergeOpts.configFile, "config", "c", "",
+		"YAML file with settings to change before converging")
+	convergeCmd.PersistentFlags().StringVar(&convergeOpts.name, "name", "",
+		"Name of the cluster to converge (default: the component name)")
+	convergeCmd.PersistentFlags().StringSliceVar(&convergeOpts.tags, "tags", nil,
+		"Only run the plays with these tags")
+	convergeCmd.PersistentFlags().StringSliceVar(&convergeOpts.limit, "limit", nil,
+		"Only run on these hosts or groups")
+
+	for _, c := range component.All() {
+		if converger, ok := c.(component.Converger); ok {
+			convergeCmd.AddCommand(newConvergeComponentCmd(c, converger))
+		}
+	}
+}
+
+// newConvergeComponentCmd builds the converge subcommand of a component
+func newConvergeComponentCmd(c component.Component, converger component.Converger) *cobra.Command {
+	return &cobra.Command{
+		Use:   c.Name(),
+		Short: fmt.Sprintf("Re-run the %s playbook", c.Title()),
+		Long: fmt.Sprintf(`Re-run the %s playbook on the VMs of a provisioned cluster.
+
+Without --name the default cluster, named %s, is converged. The tags are, in
+the order the playbook runs them:
+  %s`, c.Title(), c.Name(), strings.Join(converger.Tags(), ", ")),
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			recorded, _ := state.ConfigPath(clusterName(c, convergeOpts.name))
+			config, _ := loadSources(c, component.Sources{Recorded: recorded, File: convergeOpts.configFile})
+			applyName(c, config, convergeOpts.name)
+			validateConfig(c, config)
+
+			opts := component.ConvergeOptions{Tags: convergeOpts.tags, Limit: convergeOpts.limit}
+			if err := converger.Converge(config, opts); err != nil {
+				exitWithError(fmt.Sprintf("Failed to converge %s", c.Title()), err)
+			}
+		},
+	}
+}
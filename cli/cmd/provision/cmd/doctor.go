@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/doctor"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/kubernetes"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/state"
+	"github.com/spf13/cobra"
+)
+
+// doctorOptions holds the flags of the doctor command
+type doctorOptions struct {
+	kubernetes bool
+}
+
+var doctorOpts doctorOptions
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the tools and host setup provisioning needs",
+	Long: `Check that Multipass is installed, running and accepts this client, that
+hardware virtualization is available, and that ansible-playbook (ansible-core
+` + doctor.MinAnsibleVersion + ` or newer), python3 with PyYAML and ssh-keygen are installed.
+kubectl and helm are checked too when a Kubernetes cluster is recorded, or
+with --kubernetes.
+
+Each problem is printed with how to fix it. The exit code is 1 when a check
+failed, so the command can gate CI jobs; warnings do not fail it.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		results := doctor.Run(doctor.Options{Kubernetes: doctorOpts.kubernetes || kubernetesRecorded()})
+		doctor.Print(os.Stdout, results)
+		if doctor.Failed(results) {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	doctorCmd.Flags().BoolVar(&doctorOpts.kubernetes, "kubernetes", false,
+		"Also check kubectl and helm, used with a provisioned Kubernetes cluster")
+}
+
+// kubernetesRecorded reports whether a Kubernetes cluster is recorded. A
+// state directory that cannot be read counts as none.
+func kubernetesRecorded() bool {
+	clusters, err := state.List()
+	if err != nil {
+		return false
+	}
+	for _, cluster := range clusters {
+		if cluster.Component == kubernetes.ComponentName {
+			return true
+		}
+	}
+	return false
+}
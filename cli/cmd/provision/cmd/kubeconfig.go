@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/kubernetes"
+	"github.com/spf13/cobra"
+)
+
+// kubeconfigOptions holds the flags of the kubeconfig command
+type kubeconfigOptions struct {
+	configFile string
+	name       string
+	outputFile string
+	remove     bool
+}
+
+var kubeconfigOpts kubeconfigOptions
+
+var kubeconfigCmd = &cobra.Command{
+	Use:   "kubeconfig",
+	Short: "Export the Kubernetes cluster's kubeconfig to the host",
+	Long: `Fetch admin.conf from the control plane and make it usable from the host.
+
+The server address is rewritten to the control plane (or load balancer) IP and
+the cluster, user and context are named provision-<name> for a cluster given
+with --name, or provision-<name_prefix>kubernetes otherwise. By
+default they are merged into $KUBECONFIG or ~/.kube/config and made the current
+context; with --output-file a standalone kubeconfig is written instead.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		c := lookupComponent(kubernetes.ComponentName)
+		k8sConfig := loadRecordedConfig(c, kubeconfigOpts.configFile, kubeconfigOpts.name).(*kubernetes.Config)
+
+		if kubeconfigOpts.outputFile != "" && !kubeconfigOpts.remove {
+			if err := kubernetes.WriteKubeconfig(k8sConfig, kubeconfigOpts.outputFile); err != nil {
+				exitWithError("Failed to write kubeconfig", err)
+			}
+			fmt.Printf("✓ Kubeconfig written to %s\n", kubeconfigOpts.outputFile)
+			fmt.Printf("Use it with: kubectl --kubeconfig %s get nodes\n", kubeconfigOpts.outputFile)
+			return
+		}
+
+		path := kubeconfigOpts.outputFile
+		if path == "" {
+			var err error
+			if path, err = kubernetes.DefaultKubeconfigPath(); err != nil {
+				exitWithError("Failed to locate kubeconfig", err)
+			}
+		}
+
+		if kubeconfigOpts.remove {
+			if err := kubernetes.RemoveKubeconfig(k8sConfig, path); err != nil {
+				exitWithError("Failed to remove kubeconfig context", err)
+			}
+			fmt.Printf("✓ Context '%s' removed from %s\n", k8sConfig.ContextName(), path)
+			return
+		}
+
+		if err := kubernetes.MergeKubeconfig(k8sConfig, path); err != nil {
+			exitWithError("Failed to merge kubeconfig", err)
+		}
+		fmt.Printf("✓ Context '%s' merged into %s and set as current context\n", k8sConfig.ContextName(), path)
+		fmt.Println("Use it with: kubectl get nodes")
+	},
+}
+
+func init() {
+	kubeconfigCmd.Flags().StringVarP(&kubeconfigOpts.configFile, "config", "c", "",
+		"YAML file with the settings the cluster was provisioned with")
+	kubeconfigCmd.Flags().StringVar(&kubeconfigOpts.name, "name", "",
+		"Name of the cluster (default: kubernetes)")
+	kubeconfigCmd.Flags().StringVar(&kubeconfigOpts.outputFile, "output-file", "",
+		"Write a standalone kubeconfig to this path instead of merging")
+	kubeconfigCmd.Flags().BoolVar(&kubeconfigOpts.remove, "remove", false,
+		"Remove the cluster's context instead of adding it")
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/state"
+	"github.com/spf13/cobra"
+)
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the clusters the CLI has provisioned",
+	Long: `List the clusters recorded in the state directory,
+$XDG_STATE_HOME/provision-cli (~/.local/state/provision-cli by default).`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		clusters, err := state.List()
+		if err != nil {
+			exitWithError("Failed to list clusters", err)
+		}
+		state.PrintList(os.Stdout, clusters)
+	},
+}
+
+var describeCmd = &cobra.Command{
+	Use:   "describe <cluster>",
+	Short: "Show what the CLI recorded about a cluster",
+	Long: `Show the status, nodes, settings and file locations recorded when the
+cluster was provisioned.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cluster, err := state.Load(args[0])
+		if err != nil {
+			exitWithError("Failed to describe cluster", err)
+		}
+		cluster.Describe(os.Stdout)
+	},
+}
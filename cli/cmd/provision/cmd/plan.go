@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/plan"
+	"github.com/spf13/pflag"
+)
+
+// Output formats of --dry-run
+const (
+	dryRunText = "text"
+	dryRunJSON = "json"
+)
+
+// addDryRunFlag registers --dry-run on fs. It stores the output format of
+// the plan in format, text when the flag is given without a value.
+func addDryRunFlag(fs *pflag.FlagSet, format *string) {
+	fs.StringVar(format, "dry-run", "",
+		"Print the plan without changing anything: text or json (--dry-run=json)")
+	fs.Lookup("dry-run").NoOptDefVal = dryRunText
+}
+
+// checkDryRun exits when the --dry-run value is not an output format
+func checkDryRun(format string) {
+	if format != "" && format != dryRunText && format != dryRunJSON {
+		exitWithError("Invalid flags", fmt.Errorf("unknown --dry-run format %q, use text or json", format))
+	}
+}
+
+// printPlan writes p to stdout in the --dry-run output format
+func printPlan(p *plan.Plan, format string) {
+	if format == dryRunJSON {
+		printJSON(p)
+		return
+	}
+	p.Print(os.Stdout)
+}
@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/component"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/interactive"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/profile"
+	"github.com/spf13/cobra"
+)
+
+// profileOptions holds the flags of the profile commands
+type profileOptions struct {
+	configFile string
+}
+
+var profileOpts profileOptions
+
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage reusable sets of settings",
+	Long: `Manage profiles, named sets of settings such as small, ci or ha that are
+reused with provision --profile.
+
+Profiles are stored per component in $XDG_CONFIG_HOME/provision-cli/profiles
+(~/.config/provision-cli/profiles by default). A profile only holds the
+settings that differ from the defaults in ansible/defaults, so it keeps
+following the defaults it does not override.`,
+}
+
+var profileSaveCmd = &cobra.Command{
+	Use:   "save",
+	Short: "Save settings as a profile",
+}
+
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the stored profiles",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		profiles, err := profile.List()
+		if err != nil {
+			exitWithError("Failed to list profiles", err)
+		}
+		profile.PrintList(os.Stdout, profiles)
+	},
+}
+
+var profileShowCmd = &cobra.Command{
+	Use:   "show <component> <profile>",
+	Short: "Print the settings a profile overrides",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		c := lookupComponent(args[0])
+		data, err := profile.Read(c.Name(), args[1])
+		if err != nil {
+			exitWithError("Failed to show profile", err)
+		}
+		fmt.Print(string(data))
+	},
+}
+
+var profileDeleteCmd = &cobra.Command{
+	Use:   "delete <component> <profile>",
+	Short: "Delete a profile",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		c := lookupComponent(args[0])
+		if err := profile.Delete(c.Name(), args[1]); err != nil {
+			exitWithError("Failed to delete profile", err)
+		}
+		fmt.Printf("✓ Deleted %s profile %s\n", c.Title(), args[1])
+	},
+}
+
+func init() {
+	profileSaveCmd.PersistentFlags().StringVarP(&profileOpts.configFile, "config", "c", "",
+		"YAML file with settings that override the defaults")
+	for _, c := range component.All() {
+		profileSaveCmd.AddCommand(newProfileSaveComponentCmd(c))
+	}
+
+	profileCmd.AddCommand(profileSaveCmd)
+	profileCmd.AddCommand(profileListCmd)
+	profileCmd.AddCommand(profileShowCmd)
+	profileCmd.AddCommand(profileDeleteCmd)
+}
+
+// newProfileSaveComponentCmd builds the profile save subcommand of a
+// component. It takes the same setting flags as the provision subcommand.
+func newProfileSaveComponentCmd(c component.Component) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   c.Name() + " <profile>",
+		Short: fmt.Sprintf("Save %s settings as a profile", c.Title()),
+		Long: fmt.Sprintf(`Save the %s settings from the file given with --config, the
+environment and individual flags that differ from ansible/defaults/%s.yml
+as a profile, replacing a profile of the same name. Invalid settings are
+refused.`, c.Title(), c.Name()),
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			config, _ := loadSettings(c, cmd.Flags(), "", profileOpts.configFile)
+			validateConfig(c, config)
+			if err := saveProfile(c, args[0], config, ""); err != nil {
+				exitWithError("Failed to save profile", err)
+			}
+		},
+	}
+	c.AddFlags(cmd.Flags())
+	return cmd
+}
+
+// lookupComponent returns the registered component of that name, exiting if
+// there is none
+func lookupComponent(name string) component.Component {
+	c, ok := component.Get(name)
+	if !ok {
+		exitWithError("Unknown component", fmt.Errorf("%q is not one of %v", name, componentNames()))
+	}
+	return c
+}
+
+// componentNames returns the names of the registered components
+func componentNames() []string {
+	var names []string
+	for _, c := range component.All() {
+		names = append(names, c.Name())
+	}
+	return names
+}
+
+// saveProfile stores the settings of config that differ from the defaults
+// as the named profile. name is the cluster config describes, so the
+// settings derived from it are not taken for overrides.
+func saveProfile(c component.Component, profileName string, config component.Config, name string) error {
+	defaults, _, err := component.Load(c, component.Sources{})
+	if err != nil {
+		return err
+	}
+	if name != "" {
+		applyName(c, defaults, name)
+	}
+
+	path, err := profile.Save(c.Name(), profileName, config, defaults)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✓ Saved %s profile %s to %s\n", c.Title(), profileName, path)
+	return nil
+}
+
+// promptConfirm and promptText are variables so tests can answer the
+// questions of offerProfileSave
+var (
+	promptConfirm = interactive.PromptConfirm
+	promptText    = interactive.PromptText
+)
+
+// offerProfileSave asks whether the answers of an interactive session
+// should be kept as a profile and saves them under the name given. Answers
+// that only accept start, the settings the session started from, are not
+// offered.
+func offerProfileSave(c component.Component, start, config component.Config) {
+	changed, err := profile.Differs(config, start)
+	if err != nil {
+		exitWithError("Failed to compare settings", err)
+	}
+	if !changed {
+		return
+	}
+
+	save, err := promptConfirm("Save these settings as a profile for later runs?")
+	if err != nil {
+		exitWithError("Failed to get user input", err)
+	}
+	if !save {
+		return
+	}
+
+	for {
+		profileName, err := promptText("Profile name", "")
+		if err != nil {
+			exitWithError("Failed to get user input", err)
+		}
+		if err := profile.ValidateName(profileName); err != nil {
+			fmt.Printf("⚠ %v\n", err)
+			continue
+		}
+		if _, err := profile.Find(c.Name(), profileName); err == nil {
+			replace, err := promptConfirm(fmt.Sprintf("Profile %s exists, replace it?", profileName))
+			if err != nil {
+				exitWithError("Failed to get user input", err)
+			}
+			if !replace {
+				continue
+			}
+		}
+		if err := saveProfile(c, profileName, config, provisionOpts.name); err != nil {
+			fmt.Printf("⚠ Failed to save profile: %v\n", err)
+		}
+		return
+	}
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/component"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/kubernetes"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/profile"
+)
+
+// answerProfilePrompts answers the questions of offerProfileSave with
+// confirm and the profile name, recording the questions asked
+func answerProfilePrompts(t *testing.T, confirm bool, name string) *[]string {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	var asked []string
+	origConfirm, origText := promptConfirm, promptText
+	t.Cleanup(func() { promptConfirm, promptText = origConfirm, origText })
+	promptConfirm = func(message string) (bool, error) {
+		asked = append(asked, message)
+		return confirm, nil
+	}
+	promptText = func(message, defaultValue string) (string, error) {
+		asked = append(asked, message)
+		return name, nil
+	}
+	return &asked
+}
+
+// interactiveDefaults returns the settings an interactive session starts from
+func interactiveDefaults(t *testing.T) (component.Component, *kubernetes.Config) {
+	t.Helper()
+	c, ok := component.Get(kubernetes.ComponentName)
+	if !ok {
+		t.Fatalf("kubernetes component not registered")
+	}
+	config, _ := loadSettings(c, nil, "", "")
+	return c, config.(*kubernetes.Config)
+}
+
+func TestOfferProfileSaveSkipsDefaults(t *testing.T) {
+	asked := answerProfilePrompts(t, true, "ci")
+	c, start := interactiveDefaults(t)
+
+	offerProfileSave(c, start, copyConfig(start))
+	if len(*asked) != 0 {
+		t.Errorf("asked %q, want no questions when every default was accepted", *asked)
+	}
+	if _, err := profile.Find(c.Name(), "ci"); !errors.Is(err, profile.ErrNotFound) {
+		t.Errorf("Find() error = %v, want no profile saved", err)
+	}
+}
+
+func TestOfferProfileSaveStoresChangedAnswers(t *testing.T) {
+	asked := answerProfilePrompts(t, true, "ci")
+	c, start := interactiveDefaults(t)
+	config := copyConfig(start).(*kubernetes.Config)
+	config.WorkerCount = 1
+
+	offerProfileSave(c, start, config)
+	if len(*asked) != 2 {
+		t.Errorf("asked %q, want the offer and the profile name", *asked)
+	}
+	data, err := profile.Read(c.Name(), "ci")
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if !strings.Contains(string(data), "worker_count: 1") || strings.Contains(string(data), "pod_cidr") {
+		t.Errorf("profile = %q, want only the changed worker_count", data)
+	}
+}
+
+func TestOfferProfileSaveDeclined(t *testing.T) {
+	answerProfilePrompts(t, false, "ci")
+	c, start := interactiveDefaults(t)
+	config := copyConfig(start).(*kubernetes.Config)
+	config.WorkerCount = 1
+
+	offerProfileSave(c, start, config)
+	dir, _ := profile.Dir()
+	if _, err := os.Stat(dir); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("profile directory exists after declining: %v", err)
+	}
+}
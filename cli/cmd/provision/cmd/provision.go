@@ -1,43 +1,227 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/component"
 	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/interactive"
-	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/kubernetes"
-	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/rqlite"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/vm"
 	"github.com/spf13/cobra"
 )
 
+// provisionOptions holds the flags shared by all provision subcommands
+type provisionOptions struct {
+	configFile string
+	profile    string
+	name       string
+	yes        bool
+	force      bool
+	dryRun     string
+}
+
+var provisionOpts provisionOptions
+
 var provisionCmd = &cobra.Command{
 	Use:   "provision",
 	Short: "Provision infrastructure components",
-	Long:  `Provision infrastructure components like Kubernetes clusters, RQLite, etc.`,
+	Long: `Provision infrastructure components like Kubernetes clusters, RQLite, etc.
+
+Run without a subcommand to choose a component interactively, or use one of
+the subcommands together with --config and flags to provision without prompts.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		if !interactive.IsTerminal() {
+			exitWithError("No component specified",
+				errors.New("stdin is not a terminal, use one of the provision subcommands"))
+		}
 		provisionInteractive()
 	},
 }
 
+func init() {
+	provisionCmd.PersistentFlags().StringVarP(&provisionOpts.configFile, "config", "c", "",
+		"YAML file with settings that override the defaults")
+	provisionCmd.PersistentFlags().StringVarP(&provisionOpts.profile, "profile", "p", "",
+		"Stored profile whose settings override the defaults, see the profile command")
+	provisionCmd.PersistentFlags().StringVar(&provisionOpts.name, "name", "",
+		"Name of the cluster, to run several clusters side by side (default: the component name)")
+	provisionCmd.PersistentFlags().BoolVarP(&provisionOpts.yes, "yes", "y", false,
+		"Skip confirmation prompts")
+	provisionCmd.PersistentFlags().BoolVar(&provisionOpts.force, "force", false,
+		"Provision even when the VMs do not fit the host's CPUs, memory or disk")
+	addDryRunFlag(provisionCmd.PersistentFlags(), &provisionOpts.dryRun)
+	provisionCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		checkDryRun(provisionOpts.dryRun)
+	}
+
+	for _, c := range component.All() {
+		provisionCmd.AddCommand(newProvisionComponentCmd(c))
+	}
+}
+
+// newProvisionComponentCmd builds the provision subcommand of a component
+func newProvisionComponentCmd(c component.Component) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   c.Name(),
+		Short: fmt.Sprintf("Provision the %s", c.Title()),
+		Long: fmt.Sprintf(`Provision the %s on local Multipass VMs.
+
+Settings are taken from the defaults in ansible/defaults/%s.yml, then
+from the profile given with --profile, then from the file given with
+--config, then from $PROVISION_%s_<KEY> variables, then from individual
+flags, and every invalid setting is listed before anything is done.
+Without any flags other than --name and --dry-run and with a terminal
+attached, the interactive prompts are used instead, and changed answers
+can be saved as a profile at the end.
+
+With --dry-run, the VMs, cloud-init, inventory and playbook runs are printed
+without changing anything; --dry-run=json prints them as JSON.
+
+With --name, the VM names are prefixed with the cluster name and the cluster
+gets its own state, so several clusters can run side by side.
+
+Before anything is launched, the CPUs, memory and disk of the VMs to create
+are checked against the host; --force provisions anyway.`, c.Title(), c.Name(), strings.ToUpper(c.Name())),
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if useInteractiveFlow(cmd) {
+				provisionComponentInteractive(c)
+				return
+			}
+
+			config, _ := loadSettings(c, cmd.Flags(), provisionOpts.profile, provisionOpts.configFile)
+			applyName(c, config, provisionOpts.name)
+			validateConfig(c, config)
+
+			if provisionOpts.dryRun != "" {
+				planProvision(c, config)
+				return
+			}
+
+			fmt.Println("\nSettings:")
+			c.PrintConfig(config)
+			preflight(c, config)
+
+			proceed, err := confirmProceed(provisionOpts.yes, "Do you want to proceed with provisioning?")
+			if err != nil {
+				exitWithError("Failed to get confirmation", err)
+			}
+			if !proceed {
+				fmt.Println("Provisioning cancelled.")
+				return
+			}
+
+			provisionComponent(c, config)
+		},
+	}
+	c.AddFlags(cmd.Flags())
+	return cmd
+}
+
 func provisionInteractive() {
-	options := []string{"Kubernetes Cluster", "RQLite Cluster"}
-	choice, err := interactive.PromptSelect("What would you like to provision?", options)
+	c, err := selectComponent("What would you like to provision?")
 	if err != nil {
 		exitWithError("Failed to get user input", err)
 	}
+	provisionComponentInteractive(c)
+}
 
-	switch choice {
-	case "Kubernetes Cluster":
-		fmt.Println("Starting Kubernetes cluster provisioning...")
-		if err := kubernetes.ProvisionInteractive(); err != nil {
-			exitWithError("Failed to provision Kubernetes cluster", err)
-		}
-	case "RQLite Cluster":
-		fmt.Println("Starting RQLite cluster provisioning...")
-		if err := rqlite.ProvisionInteractive(); err != nil {
-			exitWithError("Failed to provision RQLite cluster", err)
-		}
+// provisionComponentInteractive prompts for the settings of c, starting from
+// its defaults and the environment, offers to save changed answers as a
+// profile and provisions it after confirmation
+func provisionComponentInteractive(c component.Component) {
+	config, _ := loadSettings(c, nil, "", "")
+	applyName(c, config, provisionOpts.name)
+
+	fmt.Println("\nCurrent default settings:")
+	c.PrintConfig(config)
+
+	start := copyConfig(config)
+	if err := c.Prompt(config); err != nil {
+		exitWithError("Failed to get user input", err)
+	}
+	validateConfig(c, config)
+	offerProfileSave(c, start, config)
+
+	if provisionOpts.dryRun != "" {
+		planProvision(c, config)
+		return
+	}
+	preflight(c, config)
+
+	proceed, err := interactive.PromptConfirm("Do you want to proceed with provisioning?")
+	if err != nil {
+		exitWithError("Failed to get confirmation", err)
+	}
+	if !proceed {
+		fmt.Println("Provisioning cancelled.")
+		return
+	}
+
+	provisionComponent(c, config)
+}
 
+func provisionComponent(c component.Component, config component.Config) {
+	fmt.Printf("Starting %s provisioning...\n", c.Title())
+	if err := c.Provision(config); err != nil {
+		exitWithError(fmt.Sprintf("Failed to provision %s", c.Title()), err)
+	}
+}
+
+// preflight checks that the host can run the VMs of c. VMs that do not fit
+// are refused unless --force is given, while a host whose capacity cannot be
+// read only gets a warning.
+func preflight(c component.Component, config component.Config) {
+	err := c.Preflight(config)
+	var capacityErr *vm.CapacityError
+	switch {
+	case err == nil:
+	case errors.As(err, &capacityErr) && provisionOpts.force:
+		fmt.Printf("⚠ %v\nContinuing because of --force\n", err)
+	case errors.As(err, &capacityErr):
+		exitWithError("Pre-flight check failed",
+			fmt.Errorf("%w\nLower the node count or resources, or pass --force to provision anyway", err))
+	case errors.Is(err, vm.ErrCapacityUnknown):
+		fmt.Printf("⚠ Skipping the capacity check: %v\n", err)
 	default:
-		fmt.Println("Invalid choice")
+		exitWithError("Pre-flight check failed", err)
 	}
 }
+
+// planProvision prints what provisioning c with config would do
+func planProvision(c component.Component, config component.Config) {
+	p, err := c.PlanProvision(config)
+	if err != nil {
+		exitWithError(fmt.Sprintf("Failed to plan %s provisioning", c.Title()), err)
+	}
+	printPlan(p, provisionOpts.dryRun)
+}
+
+// useInteractiveFlow reports whether a provision subcommand should fall back
+// to the interactive prompts: only when nothing but a cluster name or
+// --dry-run was specified on the command line and there is a terminal to
+// prompt on
+func useInteractiveFlow(cmd *cobra.Command) bool {
+	flags := cmd.Flags().NFlag()
+	for _, name := range []string{"name", "dry-run"} {
+		if cmd.Flags().Changed(name) {
+			flags--
+		}
+	}
+	return flags == 0 && interactive.IsTerminal()
+}
+
+// confirmProceed asks for confirmation unless yes (--yes) is set. Without a
+// terminal there is nobody to ask, so --yes is required.
+func confirmProceed(yes bool, message string) (bool, error) {
+	if yes {
+		return true, nil
+	}
+
+	if !interactive.IsTerminal() {
+		return false, errors.New("stdin is not a terminal, pass --yes to proceed without confirmation")
+	}
+
+	return interactive.PromptConfirm(message)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/config"
 	"github.com/spf13/cobra"
 )
 
@@ -11,7 +12,10 @@ var rootCmd = &cobra.Command{
 	Use:   "provision-cli",
 	Short: "Kubernetes cluster provisioning tool",
 	Long: `A simple CLI for provisioning and managing Kubernetes clusters on local VMs.
-This tool must be run from the project root directory.`,
+
+The playbooks, scripts and cloud-init template are embedded in the binary. To
+use the ones of a local checkout instead, run from anywhere inside it or point
+--assets-dir or $PROVISION_CLI_HOME at it.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// If no subcommand is provided, show help
 		cmd.Help()
@@ -23,10 +27,30 @@ func Execute() error {
 	return rootCmd.Execute()
 }
 
+// rootOptions holds the flags shared by every command
+type rootOptions struct {
+	assetsDir string
+}
+
+var rootOpts rootOptions
+
 func init() {
+	rootCmd.PersistentFlags().StringVar(&rootOpts.assetsDir, "assets-dir", "",
+		"Checkout whose ansible, scripts and multipass directories are used instead of the embedded ones")
+	cobra.OnInitialize(func() { config.SetAssetsDir(rootOpts.assetsDir) })
+
 	// Initialize commands
 	rootCmd.AddCommand(provisionCmd)
 	rootCmd.AddCommand(cleanupCmd)
+	rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(kubeconfigCmd)
+	rootCmd.AddCommand(listCmd)
+	rootCmd.AddCommand(describeCmd)
+	rootCmd.AddCommand(doctorCmd)
+	rootCmd.AddCommand(convergeCmd)
+	rootCmd.AddCommand(validateCmd)
+	rootCmd.AddCommand(profileCmd)
+	rootCmd.AddCommand(configCmd)
 }
 
 // Display an error message and exit
@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/component"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/state"
+	"github.com/spf13/cobra"
+)
+
+// statusOptions holds the flags shared by the status commands
+type statusOptions struct {
+	configFile string
+	name       string
+	output     string
+}
+
+var statusOpts statusOptions
+
+// statusTarget is a cluster shown by the status command
+type statusTarget struct {
+	component component.Component
+	name      string
+}
+
+// statusReport is the JSON form of a component's status
+type statusReport struct {
+	Healthy bool             `json:"healthy"`
+	Status  component.Status `json:"status"`
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the health of the provisioned clusters",
+	Long: `Show the VMs and the health of each provisioned cluster.
+
+For Kubernetes this lists the VM states, the Ready condition of every node and
+the pods that are not running. For rqlite it queries the /status and /nodes
+endpoints of every node to show the leader, the members and the Raft state.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if statusOpts.configFile != "" {
+			exitWithError("Invalid flags", fmt.Errorf("--config needs a component, e.g. 'status kubernetes --config %s'", statusOpts.configFile))
+		}
+		if statusOpts.name != "" {
+			exitWithError("Invalid flags", fmt.Errorf("--name needs a component, e.g. 'status kubernetes --name %s'", statusOpts.name))
+		}
+
+		targets := statusTargets()
+		reports := make(map[string]statusReport, len(targets))
+		for _, target := range targets {
+			status := componentStatus(target.component, "", target.name)
+			reports[target.name] = statusReport{Healthy: status.Healthy(), Status: status}
+		}
+
+		if statusOpts.output == "json" {
+			printJSON(reports)
+			return
+		}
+
+		for i, target := range targets {
+			if i > 0 {
+				fmt.Println()
+			}
+			if target.name == target.component.Name() {
+				fmt.Printf("%s:\n", target.component.Title())
+			} else {
+				fmt.Printf("%s %s:\n", target.component.Title(), target.name)
+			}
+			reports[target.name].Status.Print(os.Stdout)
+		}
+	},
+}
+
+func init() {
+	statusCmd.PersistentFlags().StringVarP(&statusOpts.configFile, "config", "c", "",
+		"YAML file with the settings the cluster was provisioned with")
+	statusCmd.PersistentFlags().StringVar(&statusOpts.name, "name", "",
+		"Name of the cluster (default: the component name)")
+	statusCmd.PersistentFlags().StringVarP(&statusOpts.output, "output", "o", "table",
+		"Output format: table or json")
+	statusCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		if statusOpts.output != "table" && statusOpts.output != "json" {
+			exitWithError("Invalid flags", fmt.Errorf("unknown output format %q, use table or json", statusOpts.output))
+		}
+	}
+
+	for _, c := range component.All() {
+		statusCmd.AddCommand(newStatusComponentCmd(c))
+	}
+}
+
+// newStatusComponentCmd builds the status subcommand of a component
+func newStatusComponentCmd(c component.Component) *cobra.Command {
+	return &cobra.Command{
+		Use:   c.Name(),
+		Short: fmt.Sprintf("Show the health of the %s", c.Title()),
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			status := componentStatus(c, statusOpts.configFile, statusOpts.name)
+			if statusOpts.output == "json" {
+				printJSON(statusReport{Healthy: status.Healthy(), Status: status})
+				return
+			}
+			status.Print(os.Stdout)
+		},
+	}
+}
+
+// statusTargets returns the recorded clusters of every component, or the
+// component's default cluster when none is recorded
+func statusTargets() []statusTarget {
+	clusters, err := state.List()
+	if err != nil {
+		exitWithError("Failed to list clusters", err)
+	}
+
+	var targets []statusTarget
+	for _, c := range component.All() {
+		found := false
+		for _, cluster := range clusters {
+			if cluster.Component == c.Name() {
+				targets = append(targets, statusTarget{component: c, name: cluster.Name})
+				found = true
+			}
+		}
+		if !found {
+			targets = append(targets, statusTarget{component: c, name: c.Name()})
+		}
+	}
+	return targets
+}
+
+// componentStatus collects the status of the named cluster of c for the
+// given config file
+func componentStatus(c component.Component, configFile, name string) component.Status {
+	config := loadRecordedConfig(c, configFile, name)
+
+	status, err := c.Status(config)
+	if err != nil {
+		exitWithError(fmt.Sprintf("Failed to get %s status", c.Title()), err)
+	}
+	return status
+}
+
+// printJSON writes v to stdout as indented JSON
+func printJSON(v interface{}) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		exitWithError("Failed to encode JSON", err)
+	}
+	fmt.Println(string(data))
+}
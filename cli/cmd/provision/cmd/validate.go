@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/component"
+	"github.com/spf13/cobra"
+)
+
+// validateOptions holds the flags of the validate commands
+type validateOptions struct {
+	configFile string
+	profile    string
+}
+
+var validateOpts validateOptions
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check settings without provisioning",
+	Long: `Check the settings a provision command would use, without provisioning.
+
+Every invalid setting is listed with its YAML key, e.g. overlapping CIDRs,
+memory sizes Multipass does not accept, equal HTTP and Raft ports or DNS
+servers that are not IP addresses. The command exits with status 1 when a
+setting is invalid, so it can run in CI before provisioning.`,
+}
+
+func init() {
+	validateCmd.PersistentFlags().StringVarP(&validateOpts.configFile, "config", "c", "",
+		"YAML file with settings that override the defaults")
+	validateCmd.PersistentFlags().StringVarP(&validateOpts.profile, "profile", "p", "",
+		"Stored profile whose settings override the defaults, see the profile command")
+
+	for _, c := range component.All() {
+		validateCmd.AddCommand(newValidateComponentCmd(c))
+	}
+}
+
+// newValidateComponentCmd builds the validate subcommand of a component. It
+// takes the same setting flags as the provision subcommand.
+func newValidateComponentCmd(c component.Component) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   c.Name(),
+		Short: fmt.Sprintf("Check the %s settings", c.Title()),
+		Long: fmt.Sprintf(`Check the %s settings from ansible/defaults/%s.yml, the profile
+given with --profile, the file given with --config, the environment and
+individual flags, in that order, see provision-cli config view.`, c.Title(), c.Name()),
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			config, _ := loadSettings(c, cmd.Flags(), validateOpts.profile, validateOpts.configFile)
+			validateConfig(c, config)
+			fmt.Printf("✓ %s settings are valid\n", c.Title())
+		},
+	}
+	c.AddFlags(cmd.Flags())
+	return cmd
+}
+
+// validateConfig exits listing the invalid settings of config, if any
+func validateConfig(c component.Component, config component.Config) {
+	err := c.Validate(config)
+	if err == nil {
+		return
+	}
+
+	var invalid *component.ValidationError
+	if !errors.As(err, &invalid) {
+		exitWithError(fmt.Sprintf("Failed to validate %s configuration", c.Title()), err)
+	}
+	fmt.Fprintf(os.Stderr, "Invalid %s configuration:\n", c.Title())
+	for _, field := range invalid.Fields {
+		fmt.Fprintf(os.Stderr, "  %v\n", field)
+	}
+	os.Exit(1)
+}
@@ -0,0 +1,176 @@
+// Package component defines the infrastructure components the CLI can
+// provision, clean up and check, and the registry the commands are built from
+package component
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/config"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/plan"
+	"github.com/spf13/pflag"
+)
+
+// Config is the settings of a component, a pointer to the component's own
+// Config type
+type Config interface{}
+
+// Status is the health of a provisioned component
+type Status interface {
+	// Healthy reports whether every part of the component works
+	Healthy() bool
+	// Print writes the status as tables
+	Print(w io.Writer)
+}
+
+// Component is something the CLI can provision, such as a Kubernetes or an
+// rqlite cluster. The config passed to its methods is always one returned by
+// its own NewConfig, usually through Load.
+type Component interface {
+	// Name is the subcommand name, e.g. "kubernetes"
+	Name() string
+	// Title is the name shown in menus and messages, e.g. "Kubernetes Cluster"
+	Title() string
+
+	// NewConfig returns the built-in defaults, used for settings that
+	// ansible/defaults/<name>.yml does not set
+	NewConfig() Config
+	// AddFlags registers one flag per setting
+	AddFlags(fs *pflag.FlagSet)
+	// ApplyFlags copies the flags that were set explicitly onto config
+	ApplyFlags(fs *pflag.FlagSet, config Config)
+	// PrintConfig displays the settings that will be used for provisioning
+	PrintConfig(config Config)
+	// Prompt asks for the settings, starting from the values in config
+	Prompt(config Config) error
+	// SetName makes config describe the named cluster instead of the
+	// default one, which prefixes its VM names and separates its state
+	SetName(config Config, name string)
+	// Validate checks every setting. It returns a *ValidationError listing
+	// the invalid ones.
+	Validate(config Config) error
+
+	// Preflight checks that the host can run the VMs Provision would launch.
+	// It returns a *vm.CapacityError when they do not fit.
+	Preflight(config Config) error
+	// Provision creates the component without asking any questions
+	Provision(config Config) error
+	// Cleanup deletes the component's VMs and generated files
+	Cleanup(config Config) error
+	// Status collects the health of the component
+	Status(config Config) (Status, error)
+
+	// PlanProvision describes what Provision would do, without side effects
+	PlanProvision(config Config) (*plan.Plan, error)
+	// PlanCleanup describes what Cleanup would do, without side effects
+	PlanCleanup(config Config) (*plan.Plan, error)
+}
+
+// TearDowner is implemented by components that can remove their software
+// from the VMs cleanly before the VMs are deleted
+type TearDowner interface {
+	TearDown(config Config) error
+	// PlanTearDown describes what TearDown would do, without side effects
+	PlanTearDown(config Config) (*plan.Plan, error)
+}
+
+// Converger is implemented by components whose playbook can be re-run on
+// the VMs of a provisioned cluster, e.g. to retry a failed phase
+type Converger interface {
+	// Tags lists the tags of the plays in the component's playbook
+	Tags() []string
+	// Converge re-runs the playbook against the recorded cluster's VMs
+	Converge(config Config, opts ConvergeOptions) error
+}
+
+// ConvergeOptions narrow down a playbook re-run
+type ConvergeOptions struct {
+	// Tags selects the plays to run, all of them when empty
+	Tags []string
+	// Limit selects the hosts or groups to run on, all of them when empty
+	Limit []string
+}
+
+var registry = map[string]Component{}
+
+// Register makes a component available to the CLI commands. It panics if a
+// component with the same name is already registered.
+func Register(c Component) {
+	if _, exists := registry[c.Name()]; exists {
+		panic(fmt.Sprintf("component %q registered twice", c.Name()))
+	}
+	registry[c.Name()] = c
+}
+
+// Get returns the component registered under name
+func Get(name string) (Component, bool) {
+	c, ok := registry[name]
+	return c, ok
+}
+
+// All returns the registered components sorted by name
+func All() []Component {
+	components := make([]Component, 0, len(registry))
+	for _, c := range registry {
+		components = append(components, c)
+	}
+	sort.Slice(components, func(i, j int) bool {
+		return components[i].Name() < components[j].Name()
+	})
+	return components
+}
+
+// Titles returns the titles of the given components, for use in menus
+func Titles(components []Component) []string {
+	titles := make([]string, 0, len(components))
+	for _, c := range components {
+		titles = append(titles, c.Title())
+	}
+	return titles
+}
+
+// Sources are the settings of a component to merge on top of its defaults,
+// see Load
+type Sources struct {
+	// Recorded is the config recorded when the cluster was provisioned
+	Recorded string
+	// Profile is the file of a stored profile, and ProfileName its name
+	Profile     string
+	ProfileName string
+	// File is a YAML file given by the user
+	File string
+	// Environ holds the PROVISION_<COMPONENT>_<KEY> variables, see
+	// config.EnvLayer
+	Environ []string
+	// Flags holds the flags registered by AddFlags
+	Flags *pflag.FlagSet
+}
+
+// Load merges the settings of c, lowest precedence first: the built-in
+// defaults, ansible/defaults/<name>.yml, the recorded config, the profile,
+// the file, the environment and the flags. The returned settings tell where
+// each value came from.
+func Load(c Component, sources Sources) (Config, *config.Settings, error) {
+	cfg := c.NewConfig()
+	layers := []config.Layer{config.DefaultsLayer(c.Name())}
+	if sources.Recorded != "" {
+		layers = append(layers, config.FileLayer(sources.Recorded, "recorded config"))
+	}
+	if sources.Profile != "" {
+		layers = append(layers, config.FileLayer(sources.Profile, "profile "+sources.ProfileName))
+	}
+	if sources.File != "" {
+		layers = append(layers, config.FileLayer(sources.File, sources.File))
+	}
+	layers = append(layers, config.EnvLayer(c.Name(), sources.Environ))
+	if sources.Flags != nil {
+		layers = append(layers, config.FlagLayer(sources.Flags, func() { c.ApplyFlags(sources.Flags, cfg) }))
+	}
+
+	settings, err := config.Load(cfg, layers...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cfg, settings, nil
+}
@@ -0,0 +1,154 @@
+package component
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/config"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/plan"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/state"
+	"github.com/spf13/pflag"
+)
+
+// stubConfig is the config of stubComponent
+type stubConfig struct {
+	Nodes int    `yaml:"nodes"`
+	Disk  string `yaml:"disk"`
+}
+
+// stubComponent implements Component with a fixed name
+type stubComponent struct {
+	name string
+}
+
+func (s stubComponent) Name() string                                    { return s.name }
+func (s stubComponent) Title() string                                   { return strings.ToUpper(s.name) }
+func (s stubComponent) NewConfig() Config                               { return &stubConfig{Nodes: 1} }
+func (s stubComponent) AddFlags(fs *pflag.FlagSet)                      {}
+func (s stubComponent) ApplyFlags(fs *pflag.FlagSet, config Config)     {}
+func (s stubComponent) PrintConfig(config Config)                       {}
+func (s stubComponent) Prompt(config Config) error                      { return nil }
+func (s stubComponent) SetName(config Config, name string)              {}
+func (s stubComponent) Validate(config Config) error                    { return nil }
+func (s stubComponent) Preflight(config Config) error                   { return nil }
+func (s stubComponent) Provision(config Config) error                   { return nil }
+func (s stubComponent) Cleanup(config Config) error                     { return nil }
+func (s stubComponent) Status(config Config) (Status, error)            { return nil, nil }
+func (s stubComponent) PlanProvision(config Config) (*plan.Plan, error) { return nil, nil }
+func (s stubComponent) PlanCleanup(config Config) (*plan.Plan, error)   { return nil, nil }
+
+// withRegistry replaces the registry for the duration of a test
+func withRegistry(t *testing.T) {
+	t.Helper()
+	orig := registry
+	registry = map[string]Component{}
+	t.Cleanup(func() { registry = orig })
+}
+
+func TestRegistry(t *testing.T) {
+	withRegistry(t)
+	Register(stubComponent{name: "rqlite"})
+	Register(stubComponent{name: "kubernetes"})
+
+	if got := strings.Join(Titles(All()), ","); got != "KUBERNETES,RQLITE" {
+		t.Errorf("Titles(All()) = %s, want components sorted by name", got)
+	}
+
+	if c, ok := Get("rqlite"); !ok || c.Name() != "rqlite" {
+		t.Errorf("Get(rqlite) = %v, %t", c, ok)
+	}
+	if _, ok := Get("postgresql"); ok {
+		t.Errorf("Get(postgresql) found an unregistered component")
+	}
+}
+
+func TestRegisterTwicePanics(t *testing.T) {
+	withRegistry(t)
+	Register(stubComponent{name: "kubernetes"})
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Register() of a duplicate name did not panic")
+		}
+	}()
+	Register(stubComponent{name: "kubernetes"})
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "ansible", "defaults"), 0o755); err != nil {
+		t.Fatalf("failed to create defaults dir: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "scripts"), 0o755); err != nil {
+		t.Fatalf("failed to create scripts dir: %v", err)
+	}
+	t.Setenv(config.AssetsDirEnv, dir)
+	recorded := filepath.Join(dir, "recorded.yml")
+	file := filepath.Join(dir, "lab.yml")
+	for path, content := range map[string]string{
+		filepath.Join(dir, "ansible", "defaults", "stub.yml"): "disk: 5G\n",
+		recorded: "nodes: 3\ndisk: 10G\n",
+		file:     "disk: 20G\n",
+	} {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+	c := stubComponent{name: "stub"}
+
+	loaded, settings, err := Load(c, Sources{
+		Recorded: recorded,
+		File:     file,
+		Environ:  []string{"PROVISION_STUB_NODES=5"},
+	})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := *loaded.(*stubConfig); got != (stubConfig{Nodes: 5, Disk: "20G"}) {
+		t.Errorf("Load() = %+v, want the environment over the file over the recorded config", got)
+	}
+	if origin := settings.Origin("disk"); origin != file {
+		t.Errorf("Origin(disk) = %q, want %q", origin, file)
+	}
+
+	if loaded, _, err := Load(c, Sources{}); err != nil || *loaded.(*stubConfig) != (stubConfig{Nodes: 1, Disk: "5G"}) {
+		t.Errorf("Load() without sources = %+v, %v, want the defaults", loaded, err)
+	}
+}
+
+func TestValidateName(t *testing.T) {
+	for _, name := range []string{"k8s-1.31", "lab", "a1"} {
+		if err := ValidateName(name); err != nil {
+			t.Errorf("ValidateName(%q) error = %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "1.31", "-lab", "lab/dev", "lab_dev", "../lab"} {
+		if err := ValidateName(name); err == nil {
+			t.Errorf("ValidateName(%q) succeeded, want error", name)
+		}
+	}
+}
+
+func TestVMPrefix(t *testing.T) {
+	if got := VMPrefix("k8s-1.31"); got != "k8s-1-31-" {
+		t.Errorf("VMPrefix() = %q, want k8s-1-31-", got)
+	}
+}
+
+func TestCheckNameFree(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	if _, err := state.Begin("k8s-1-31", "kubernetes"); err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+
+	for _, name := range []string{"", "k8s-1-31", "k8s-1.32"} {
+		if err := CheckNameFree(name); err != nil {
+			t.Errorf("CheckNameFree(%q) error = %v", name, err)
+		}
+	}
+	if err := CheckNameFree("k8s-1.31"); err == nil || !strings.Contains(err.Error(), "cluster k8s-1-31") {
+		t.Errorf("CheckNameFree(k8s-1.31) error = %v, want the VM names of k8s-1-31 refused", err)
+	}
+}
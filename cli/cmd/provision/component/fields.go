@@ -0,0 +1,61 @@
+package component
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"path"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/vm"
+)
+
+// The checks below are shared by the components' settings. Their errors
+// read as continuations of the setting's YAML key, e.g.
+// "worker_memory must be a size such as 512M or 8G, got \"8X\"".
+
+// CheckCPUs rejects CPU counts a VM cannot have
+func CheckCPUs(cpus int) error {
+	if cpus < 1 {
+		return fmt.Errorf("must be at least 1, got %d", cpus)
+	}
+	return nil
+}
+
+// CheckSize rejects memory and disk sizes Multipass does not understand. It
+// accepts what the capacity check parses, see vm.ParseSize.
+func CheckSize(size string) error {
+	if _, err := vm.ParseSize(size); err != nil {
+		return fmt.Errorf("must be a size such as 512M or 8G, got %q", size)
+	}
+	return nil
+}
+
+// CheckPort rejects numbers that are not TCP ports
+func CheckPort(port int) error {
+	if port < 1 || port > 65535 {
+		return fmt.Errorf("must be a port between 1 and 65535, got %d", port)
+	}
+	return nil
+}
+
+// CheckAbsPath rejects paths that are not absolute. Ansible templates such
+// as {{ ansible_user }} are allowed.
+func CheckAbsPath(p string) error {
+	if !path.IsAbs(p) {
+		return fmt.Errorf("must be an absolute path, got %q", p)
+	}
+	return nil
+}
+
+// CheckDNSServers rejects empty lists and entries that are not IP addresses
+func CheckDNSServers(servers []string) error {
+	if len(servers) == 0 {
+		return errors.New("must list at least one server")
+	}
+	for _, server := range servers {
+		if net.ParseIP(server) == nil {
+			return fmt.Errorf("must list IP addresses, got %q", server)
+		}
+	}
+	return nil
+}
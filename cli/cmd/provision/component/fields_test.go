@@ -0,0 +1,36 @@
+package component
+
+import "testing"
+
+func TestFieldChecks(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantErr bool
+	}{
+		{"one CPU", CheckCPUs(1), false},
+		{"no CPUs", CheckCPUs(0), true},
+		{"size", CheckSize("512M"), false},
+		{"fractional size", CheckSize("1.5G"), false},
+		{"size with bytes", CheckSize("8GB"), false},
+		{"binary size", CheckSize("512MiB"), false},
+		{"terabytes", CheckSize("1T"), false},
+		{"size with an unknown unit", CheckSize("8X"), true},
+		{"empty size", CheckSize(""), true},
+		{"port", CheckPort(4001), false},
+		{"port out of range", CheckPort(70000), true},
+		{"templated path", CheckAbsPath("/home/{{ ansible_user }}/data"), false},
+		{"relative path", CheckAbsPath("data"), true},
+		{"DNS servers", CheckDNSServers([]string{"8.8.8.8", "2001:4860:4860::8888"}), false},
+		{"no DNS servers", CheckDNSServers(nil), true},
+		{"DNS server name", CheckDNSServers([]string{"dns.google"}), true},
+		{"default cluster", CheckName(""), false},
+		{"cluster name", CheckName("k8s-1.31"), false},
+		{"cluster name with a slash", CheckName("lab/dev"), true},
+	}
+	for _, tt := range tests {
+		if (tt.err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, want error %t", tt.name, tt.err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package component
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/state"
+)
+
+// namePattern matches cluster names: a letter followed by letters, digits,
+// dots and hyphens, so versions like k8s-1.31 can be part of the name
+var namePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9.-]*$`)
+
+// nameRule describes the names namePattern matches
+const nameRule = "must start with a letter and contain only letters, digits, dots and hyphens"
+
+// ValidateName rejects cluster names that cannot be used as a state
+// directory and as the prefix of VM names
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("cluster name %q %s", name, nameRule)
+	}
+	return nil
+}
+
+// CheckName rejects the cluster_name setting of a config when ValidateName
+// refuses it. The default cluster has no name.
+func CheckName(name string) error {
+	if name != "" && !namePattern.MatchString(name) {
+		return fmt.Errorf("%s, got %q", nameRule, name)
+	}
+	return nil
+}
+
+// VMPrefix returns the prefix of the VM names of the named cluster. Multipass
+// does not allow dots in instance names, so k8s-1.31 gives k8s-1-31-.
+func VMPrefix(name string) string {
+	return strings.ReplaceAll(name, ".", "-") + "-"
+}
+
+// CheckNameFree rejects a cluster name whose VM names are those of another
+// recorded cluster, such as k8s-1.31 next to k8s-1-31, so provisioning one
+// never takes over the VMs of the other. The default cluster has no name.
+func CheckNameFree(name string) error {
+	if name == "" {
+		return nil
+	}
+	clusters, err := state.List()
+	if err != nil {
+		return err
+	}
+	for _, cluster := range clusters {
+		if cluster.Name != name && VMPrefix(cluster.Name) == VMPrefix(name) {
+			return fmt.Errorf("cluster name %s gives the VM names of cluster %s, choose another name", name, cluster.Name)
+		}
+	}
+	return nil
+}
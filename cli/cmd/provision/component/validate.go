@@ -0,0 +1,125 @@
+package component
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/config"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/interactive"
+)
+
+// FieldError is an invalid setting, named by its YAML key. Err reads as a
+// continuation of the key, e.g. "must be a port between 1 and 65535".
+type FieldError struct {
+	Key string
+	Err error
+}
+
+func (e *FieldError) Error() string {
+	return e.Key + " " + e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError lists every invalid setting of a config, in the order the
+// settings are declared
+type ValidationError struct {
+	Fields []*FieldError
+}
+
+// Add records an invalid setting
+func (e *ValidationError) Add(key string, err error) {
+	e.Fields = append(e.Fields, &FieldError{Key: key, Err: err})
+}
+
+// Has reports whether the setting with the given key is invalid
+func (e *ValidationError) Has(key string) bool {
+	for _, field := range e.Fields {
+		if field.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// OrNil returns e, or nil when no setting is invalid
+func (e *ValidationError) OrNil() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		lines = append(lines, field.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		errs = append(errs, field)
+	}
+	return errs
+}
+
+// CheckFields runs the validation of the form fields on the settings of
+// cfg, a pointer to a config struct, so settings are checked the same way
+// whether they are entered or loaded. Skipped fields are not checked.
+func CheckFields(cfg interface{}, fields map[string]interactive.Field) *ValidationError {
+	errs := &ValidationError{}
+	v := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		key := config.YAMLKey(v.Type().Field(i))
+		field, ok := fields[key]
+		if !ok || (field.Skip != nil && field.Skip()) {
+			continue
+		}
+		if field.Validate != nil {
+			if err := field.Validate(v.Field(i).Interface()); err != nil {
+				errs.Add(key, err)
+			}
+		}
+		if len(field.Options) > 0 && !contains(field.Options, v.Field(i).String()) {
+			errs.Add(key, fmt.Errorf("must be one of %s, got %q", strings.Join(field.Options, ", "), v.Field(i).String()))
+		}
+	}
+	return errs
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// PromptForm asks for the settings of config with interactive.PromptForm
+// until validate accepts them. Settings are checked as they are entered, so
+// the form is only asked again, starting from the entered values, when
+// settings do not work together.
+func PromptForm(config interface{}, fields map[string]interactive.Field, validate func() error) error {
+	for {
+		if err := interactive.PromptForm(config, fields); err != nil {
+			return err
+		}
+		err := validate()
+		var invalid *ValidationError
+		if !errors.As(err, &invalid) {
+			return err
+		}
+		fmt.Printf("⚠ Please correct these settings:\n")
+		for _, field := range invalid.Fields {
+			fmt.Printf("  %v\n", field)
+		}
+	}
+}
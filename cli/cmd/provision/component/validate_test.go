@@ -0,0 +1,40 @@
+package component
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/interactive"
+)
+
+func TestCheckFields(t *testing.T) {
+	config := &struct {
+		Name  string `yaml:"name"`
+		Port  int    `yaml:"port"`
+		Mode  string `yaml:"mode"`
+		Extra string `yaml:"extra"`
+	}{Port: 0, Mode: "fast", Extra: "ignored"}
+
+	errs := CheckFields(config, map[string]interactive.Field{
+		"port":  {Validate: interactive.CheckInt(CheckPort)},
+		"mode":  {Options: []string{"safe", "slow"}},
+		"extra": {Validate: interactive.CheckString(CheckAbsPath), Skip: func() bool { return true }},
+	})
+
+	want := "port must be a port between 1 and 65535, got 0\nmode must be one of safe, slow, got \"fast\""
+	if err := errs.OrNil(); err == nil || err.Error() != want {
+		t.Fatalf("CheckFields() = %v, want:\n%s", err, want)
+	}
+	if !errs.Has("mode") || errs.Has("extra") {
+		t.Errorf("Has() does not match the invalid settings %v", errs)
+	}
+
+	var fieldErr *FieldError
+	if !errors.As(errs.OrNil(), &fieldErr) || fieldErr.Key != "port" {
+		t.Errorf("errors.As() = %v, want the first *FieldError", fieldErr)
+	}
+
+	if err := (&ValidationError{}).OrNil(); err != nil {
+		t.Errorf("OrNil() without invalid settings = %v, want nil", err)
+	}
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/assets"
+)
+
+// AssetsDirEnv names the environment variable pointing at a checkout whose
+// ansible, scripts and multipass directories are used instead of the
+// embedded ones, for developing the playbooks without rebuilding
+const AssetsDirEnv = "PROVISION_CLI_HOME"
+
+// Sources of the assets reported in Assets.Source
+const (
+	SourceFlag     = "--assets-dir"
+	SourceEnv      = AssetsDirEnv
+	SourceCheckout = "checkout"
+	SourceEmbedded = "embedded"
+)
+
+// assetsDir is the checkout given with --assets-dir
+var assetsDir string
+
+// embeddedAssets is a variable so tests can control whether the binary
+// carries assets
+var embeddedAssets = assets.Dir
+
+// SetAssetsDir makes LocateAssets use the assets of the checkout at dir. It
+// takes precedence over $PROVISION_CLI_HOME.
+func SetAssetsDir(dir string) {
+	assetsDir = dir
+}
+
+// Assets is the directory holding the ansible, scripts and multipass
+// directories and where it was found
+type Assets struct {
+	Dir    string
+	Source string
+}
+
+// AssetsNotFoundError lists every location searched for the assets
+type AssetsNotFoundError struct {
+	Searched []string
+}
+
+func (e *AssetsNotFoundError) Error() string {
+	return fmt.Sprintf("assets not found, searched:\n  %s\n"+
+		"Run from a checkout of the repository, point --assets-dir or %s at one, or build with build.sh to embed the assets",
+		strings.Join(e.Searched, "\n  "), AssetsDirEnv)
+}
+
+// LocateAssets finds the assets. A checkout given with --assets-dir or
+// $PROVISION_CLI_HOME is used as is, without falling back to other
+// locations. Otherwise the working directory and its parents are searched
+// for a checkout, and the assets embedded in the binary are used last.
+func LocateAssets() (*Assets, error) {
+	if dir, source := overrideDir(); dir != "" {
+		if err := checkRepoRoot(dir); err != nil {
+			return nil, &AssetsNotFoundError{Searched: []string{fmt.Sprintf("%s (%s): %v", dir, source, err)}}
+		}
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", source, err)
+		}
+		return &Assets{Dir: abs, Source: source}, nil
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current working directory: %w", err)
+	}
+
+	var searched []string
+	for dir := cwd; ; dir = filepath.Dir(dir) {
+		err := checkRepoRoot(dir)
+		if err == nil {
+			return &Assets{Dir: dir, Source: SourceCheckout}, nil
+		}
+		searched = append(searched, fmt.Sprintf("%s: %v", dir, err))
+
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+
+	dir, err := embeddedAssets()
+	if errors.Is(err, assets.ErrNotEmbedded) {
+		searched = append(searched, fmt.Sprintf("embedded assets: %v", err))
+		return nil, &AssetsNotFoundError{Searched: searched}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Assets{Dir: dir, Source: SourceEmbedded}, nil
+}
+
+// overrideDir returns the checkout given with --assets-dir or
+// $PROVISION_CLI_HOME and where it came from
+func overrideDir() (dir, source string) {
+	if assetsDir != "" {
+		return assetsDir, SourceFlag
+	}
+	if dir := os.Getenv(AssetsDirEnv); dir != "" {
+		return dir, SourceEnv
+	}
+	return "", ""
+}
+
+// checkRepoRoot verifies that dir contains the key asset directories, which
+// mark a checkout of the repository
+func checkRepoRoot(dir string) error {
+	for _, name := range []string{"ansible", "scripts"} {
+		if !dirExists(filepath.Join(dir, name)) {
+			return fmt.Errorf("%s directory not found", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/assets"
+)
+
+// makeCheckout creates a directory tree with the ansible and scripts
+// directories of a checkout and a nested cli/cmd directory, and returns its
+// root with symlinks resolved so it compares equal to os.Getwd
+func makeCheckout(t *testing.T) string {
+	t.Helper()
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to resolve temp dir: %v", err)
+	}
+	for _, dir := range []string{"ansible", "scripts", "cli/cmd"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatalf("Failed to create %s dir: %v", dir, err)
+		}
+	}
+	return root
+}
+
+// useAssetsEnv runs the test in dir without overrides. embedded replaces
+// the embedded assets, nil means the binary carries none.
+func useAssetsEnv(t *testing.T, dir string, embedded func() (string, error)) {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+	t.Setenv(AssetsDirEnv, "")
+
+	if embedded == nil {
+		embedded = func() (string, error) { return "", assets.ErrNotEmbedded }
+	}
+	origDir, origEmbedded := assetsDir, embeddedAssets
+	assetsDir, embeddedAssets = "", embedded
+	t.Cleanup(func() {
+		os.Chdir(wd)
+		assetsDir, embeddedAssets = origDir, origEmbedded
+	})
+}
+
+func TestLocateAssetsSearchesUpward(t *testing.T) {
+	root := makeCheckout(t)
+	useAssetsEnv(t, filepath.Join(root, "cli", "cmd"), nil)
+
+	found, err := LocateAssets()
+	if err != nil {
+		t.Fatalf("LocateAssets() error = %v", err)
+	}
+	if found.Dir != root || found.Source != SourceCheckout {
+		t.Errorf("LocateAssets() = %+v, want the checkout at %s", found, root)
+	}
+}
+
+func TestLocateAssetsOverrides(t *testing.T) {
+	root := makeCheckout(t)
+	other := makeCheckout(t)
+	useAssetsEnv(t, root, nil)
+
+	t.Setenv(AssetsDirEnv, other)
+	found, err := LocateAssets()
+	if err != nil || found.Dir != other || found.Source != SourceEnv {
+		t.Errorf("LocateAssets() = %+v (%v), want %s from %s", found, err, other, AssetsDirEnv)
+	}
+
+	// The flag takes precedence over the environment
+	SetAssetsDir(root)
+	found, err = LocateAssets()
+	if err != nil || found.Dir != root || found.Source != SourceFlag {
+		t.Errorf("LocateAssets() = %+v (%v), want %s from --assets-dir", found, err, root)
+	}
+
+	// An invalid override is reported instead of falling back to the checkout
+	missing := filepath.Join(root, "missing")
+	SetAssetsDir(missing)
+	_, err = LocateAssets()
+	var notFound *AssetsNotFoundError
+	if !errors.As(err, &notFound) || len(notFound.Searched) != 1 || !strings.HasPrefix(notFound.Searched[0], missing+" (--assets-dir)") {
+		t.Errorf("LocateAssets() error = %v, want only %s searched", err, missing)
+	}
+}
+
+func TestLocateAssetsReportsSearchedLocations(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to resolve temp dir: %v", err)
+	}
+	nested := filepath.Join(dir, "a", "b")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	useAssetsEnv(t, nested, nil)
+
+	_, err = LocateAssets()
+	var notFound *AssetsNotFoundError
+	if !errors.As(err, &notFound) {
+		t.Fatalf("LocateAssets() error = %v, want AssetsNotFoundError", err)
+	}
+	for i, want := range []string{nested, filepath.Join(dir, "a"), dir} {
+		if i >= len(notFound.Searched) || !strings.HasPrefix(notFound.Searched[i], want+":") {
+			t.Errorf("Searched = %v, want %s at position %d", notFound.Searched, want, i)
+		}
+	}
+	last := notFound.Searched[len(notFound.Searched)-1]
+	if !strings.HasPrefix(last, "embedded assets:") {
+		t.Errorf("last searched location = %q, want the embedded assets", last)
+	}
+	if !strings.Contains(err.Error(), nested) || !strings.Contains(err.Error(), AssetsDirEnv) {
+		t.Errorf("Error() = %q, want the searched locations and a hint", err.Error())
+	}
+}
+
+func TestLocateAssetsEmbedded(t *testing.T) {
+	extracted := t.TempDir()
+	useAssetsEnv(t, t.TempDir(), func() (string, error) { return extracted, nil })
+
+	found, err := LocateAssets()
+	if err != nil || found.Dir != extracted || found.Source != SourceEmbedded {
+		t.Errorf("LocateAssets() = %+v (%v), want the extracted assets", found, err)
+	}
+}
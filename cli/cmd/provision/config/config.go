@@ -6,29 +6,6 @@ import (
 	"path/filepath"
 )
 
-// GetRepoRoot simply returns the current working directory
-// Since we're assuming the binary runs from the project root
-func GetRepoRoot() (string, error) {
-	// Get current working directory
-	cwd, err := os.Getwd()
-	if err != nil {
-		return "", fmt.Errorf("failed to get current working directory: %w", err)
-	}
-
-	// Basic validation - check if key directories exist
-	ansibleDir := filepath.Join(cwd, "ansible")
-	if !dirExists(ansibleDir) {
-		return "", fmt.Errorf("ansible directory not found in %s - please run from project root", cwd)
-	}
-
-	scriptsDir := filepath.Join(cwd, "scripts")
-	if !dirExists(scriptsDir) {
-		return "", fmt.Errorf("scripts directory not found in %s - please run from project root", cwd)
-	}
-
-	return cwd, nil
-}
-
 // dirExists checks if a directory exists
 func dirExists(path string) bool {
 	info, err := os.Stat(path)
@@ -47,6 +24,16 @@ func fileExists(path string) bool {
 	return !info.IsDir()
 }
 
+// GetRepoRoot returns the directory holding the ansible, scripts and
+// multipass directories, see LocateAssets
+func GetRepoRoot() (string, error) {
+	found, err := LocateAssets()
+	if err != nil {
+		return "", err
+	}
+	return found.Dir, nil
+}
+
 // GetAnsiblePath returns the absolute path to an ansible resource
 func GetAnsiblePath(resourcePath string) (string, error) {
 	repoRoot, err := GetRepoRoot()
@@ -64,3 +51,51 @@ func GetScriptsPath(scriptName string) (string, error) {
 	}
 	return filepath.Join(repoRoot, "scripts", scriptName), nil
 }
+
+// GetMultipassPath returns the absolute path to a multipass resource such as
+// the cloud-init template
+func GetMultipassPath(resourcePath string) (string, error) {
+	repoRoot, err := GetRepoRoot()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(repoRoot, "multipass", resourcePath), nil
+}
+
+// GetStateRoot returns the directory holding the state of every cluster,
+// $XDG_STATE_HOME/provision-cli or ~/.local/state/provision-cli
+func GetStateRoot() (string, error) {
+	stateHome := os.Getenv("XDG_STATE_HOME")
+	if stateHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		stateHome = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(stateHome, "provision-cli"), nil
+}
+
+// GetStateDir returns the directory holding the generated files of a cluster
+// in the state root
+func GetStateDir(cluster string) (string, error) {
+	stateRoot, err := GetStateRoot()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateRoot, cluster), nil
+}
+
+// GetConfigRoot returns the directory holding the user's settings, such as
+// profiles, $XDG_CONFIG_HOME/provision-cli or ~/.config/provision-cli
+func GetConfigRoot() (string, error) {
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		configHome = filepath.Join(home, ".config")
+	}
+	return filepath.Join(configHome, "provision-cli"), nil
+}
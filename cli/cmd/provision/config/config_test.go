@@ -3,39 +3,24 @@ package config
 import (
 	"os"
 	"path/filepath"
-	"sync"
 	"testing"
 )
 
 func TestGetRepoRoot(t *testing.T) {
-	// Test basic functionality
-	root, err := GetRepoRoot()
-	if err != nil {
-		t.Fatalf("GetRepoRoot() error = %v", err)
-	}
-	if root == "" {
-		t.Fatalf("GetRepoRoot() returned empty string")
-	}
+	root := makeCheckout(t)
+	useAssetsEnv(t, filepath.Join(root, "cli"), nil)
 
-	// Reset the cache to test multiple calls
-	repoRootCache = ""
-	repoRootOnce = sync.Once{}
-
-	// First call
+	// Repeated calls find the same checkout
 	root1, err := GetRepoRoot()
 	if err != nil {
 		t.Fatalf("GetRepoRoot() first call error = %v", err)
 	}
-
-	// Second call should return the same result
 	root2, err := GetRepoRoot()
 	if err != nil {
 		t.Fatalf("GetRepoRoot() second call error = %v", err)
 	}
-
-	// Results should be the same (cached)
-	if root1 != root2 {
-		t.Errorf("GetRepoRoot() cache failed: got %q then %q", root1, root2)
+	if root1 != root || root2 != root {
+		t.Errorf("GetRepoRoot() = %q then %q, want %q", root1, root2, root)
 	}
 }
 
@@ -102,12 +87,42 @@ func TestFileExists(t *testing.T) {
 }
 
 func TestGetAnsiblePath(t *testing.T) {
-	// Skip this test as it depends on specific directory structure
-	t.Skip("Skipping path test that depends on specific directory structure")
+	root := makeCheckout(t)
+	useAssetsEnv(t, root, nil)
+
+	path, err := GetAnsiblePath("defaults/kubernetes.yml")
+	if err != nil {
+		t.Fatalf("GetAnsiblePath() error = %v", err)
+	}
+	if want := filepath.Join(root, "ansible", "defaults", "kubernetes.yml"); path != want {
+		t.Errorf("GetAnsiblePath() = %q, want %q", path, want)
+	}
 }
 
 func TestGetScriptsPath(t *testing.T) {
-	// Skip this test as it depends on specific directory structure
-	t.Skip("Skipping path test that depends on specific directory structure")
+	root := makeCheckout(t)
+	useAssetsEnv(t, filepath.Join(root, "scripts"), nil)
+
+	path, err := GetScriptsPath("provision-rqlite.sh")
+	if err != nil {
+		t.Fatalf("GetScriptsPath() error = %v", err)
+	}
+	if want := filepath.Join(root, "scripts", "provision-rqlite.sh"); path != want {
+		t.Errorf("GetScriptsPath() = %q, want %q", path, want)
+	}
 }
 
+func TestGetConfigRoot(t *testing.T) {
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	if root, err := GetConfigRoot(); err != nil || root != filepath.Join(configHome, "provision-cli") {
+		t.Errorf("GetConfigRoot() = %q, %v, want the provision-cli directory in $XDG_CONFIG_HOME", root, err)
+	}
+
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("HOME", home)
+	if root, err := GetConfigRoot(); err != nil || root != filepath.Join(home, ".config", "provision-cli") {
+		t.Errorf("GetConfigRoot() = %q, %v, want ~/.config/provision-cli", root, err)
+	}
+}
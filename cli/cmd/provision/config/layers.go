@@ -0,0 +1,230 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// OriginBuiltin is the origin of settings no layer has set
+const OriginBuiltin = "built-in default"
+
+// EnvPrefix starts the environment variables that set settings, named
+// PROVISION_<COMPONENT>_<KEY>, e.g. PROVISION_KUBERNETES_WORKER_COUNT
+const EnvPrefix = "PROVISION_"
+
+// Settings is the effective config of a component together with where each
+// of its settings came from
+type Settings struct {
+	// Config points to the config struct the layers were merged into
+	Config interface{}
+	// Warnings lists what the layers ignored, such as variables that name
+	// no setting
+	Warnings []string
+
+	fields  map[string]reflect.Value
+	origins map[string]string
+}
+
+// Layer sets some of the settings and records their origin
+type Layer func(s *Settings) error
+
+// Load merges layers into config, a pointer to a config struct holding the
+// built-in defaults. Later layers take precedence over earlier ones, so they
+// are passed lowest precedence first: the defaults file, a profile, a user
+// file, the environment and flags.
+func Load(config interface{}, layers ...Layer) (*Settings, error) {
+	v := reflect.ValueOf(config)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("settings need a pointer to a struct, got %T", config)
+	}
+
+	s := &Settings{Config: config, fields: map[string]reflect.Value{}, origins: map[string]string{}}
+	v = v.Elem()
+	for i := 0; i < v.NumField(); i++ {
+		key := YAMLKey(v.Type().Field(i))
+		if key == "-" {
+			continue
+		}
+		s.fields[key] = v.Field(i)
+	}
+
+	for _, layer := range layers {
+		if err := layer(s); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Origin returns where the setting of that key came from
+func (s *Settings) Origin(key string) string {
+	if origin, ok := s.origins[key]; ok {
+		return origin
+	}
+	return OriginBuiltin
+}
+
+// Set records that a layer set the setting of that key
+func (s *Settings) Set(key, origin string) {
+	s.origins[key] = origin
+}
+
+// Write writes the settings as YAML. With showOrigin, each setting is
+// followed by a comment naming where it came from.
+func (s *Settings) Write(w io.Writer, showOrigin bool) error {
+	var node yaml.Node
+	if err := node.Encode(s.Config); err != nil {
+		return fmt.Errorf("failed to marshal settings: %w", err)
+	}
+	if showOrigin {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			node.Content[i].LineComment = s.Origin(node.Content[i].Value)
+		}
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return fmt.Errorf("failed to write settings: %w", err)
+	}
+	return encoder.Close()
+}
+
+// DefaultsLayer sets the settings of ansible/defaults/<component>.yml
+func DefaultsLayer(component string) Layer {
+	return func(s *Settings) error {
+		name := "defaults/" + component + ".yml"
+		path, err := GetAnsiblePath(name)
+		if err != nil {
+			return fmt.Errorf("failed to locate defaults file: %w", err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read defaults file: %w", err)
+		}
+		if err := s.setYAML(data, "ansible/"+name); err != nil {
+			return fmt.Errorf("failed to parse defaults file: %w", err)
+		}
+		return nil
+	}
+}
+
+// FileLayer sets the settings found in the YAML file at path, which only
+// needs the keys it changes. origin describes the file, e.g. as a profile.
+func FileLayer(path, origin string) Layer {
+	return func(s *Settings) error {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read config file: %w", err)
+		}
+		if err := s.setYAML(data, origin); err != nil {
+			return fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+		return nil
+	}
+}
+
+// EnvLayer sets the settings of the PROVISION_<COMPONENT>_<KEY> variables in
+// environ, a list of KEY=value pairs as returned by os.Environ. Lists are
+// given comma-separated. A variable with the component's prefix that names
+// no setting, such as a typo or a setting that is not read from files, is
+// skipped with a warning.
+func EnvLayer(component string, environ []string) Layer {
+	prefix := EnvPrefix + strings.ToUpper(component) + "_"
+	return func(s *Settings) error {
+		for _, entry := range environ {
+			name, value, _ := strings.Cut(entry, "=")
+			if !strings.HasPrefix(name, prefix) {
+				continue
+			}
+			key := strings.ToLower(strings.TrimPrefix(name, prefix))
+			field, ok := s.fields[key]
+			if !ok {
+				s.Warnings = append(s.Warnings, fmt.Sprintf("$%s does not name a %s setting and is ignored", name, component))
+				continue
+			}
+			parsed, err := ParseValue(field.Type(), value)
+			if err != nil {
+				return fmt.Errorf("invalid $%s: %w", name, err)
+			}
+			field.Set(parsed)
+			s.Set(key, "$"+name)
+		}
+		return nil
+	}
+}
+
+// FlagLayer runs apply, which copies the flags set explicitly in fs onto the
+// config, and records the settings of the flags named after their keys, e.g.
+// --worker-count for worker_count
+func FlagLayer(fs *pflag.FlagSet, apply func()) Layer {
+	return func(s *Settings) error {
+		apply()
+		fs.Visit(func(flag *pflag.Flag) {
+			key := strings.ReplaceAll(flag.Name, "-", "_")
+			if _, ok := s.fields[key]; ok {
+				s.Set(key, "flag --"+flag.Name)
+			}
+		})
+		return nil
+	}
+}
+
+// setYAML sets the settings of a YAML document, recording the keys it holds
+func (s *Settings) setYAML(data []byte, origin string) error {
+	if err := yaml.Unmarshal(data, s.Config); err != nil {
+		return err
+	}
+	values := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	for key := range values {
+		if _, ok := s.fields[key]; ok {
+			s.Set(key, origin)
+		}
+	}
+	return nil
+}
+
+// ParseValue converts the text of a string, int or string list setting to
+// a value of type t. Lists are given comma-separated.
+func ParseValue(t reflect.Type, text string) (reflect.Value, error) {
+	text = strings.TrimSpace(text)
+
+	switch {
+	case t.Kind() == reflect.String:
+		return reflect.ValueOf(text).Convert(t), nil
+	case t.Kind() == reflect.Int:
+		n, err := strconv.Atoi(text)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("%q is not a number", text)
+		}
+		return reflect.ValueOf(n).Convert(t), nil
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.String:
+		items := []string{}
+		for _, item := range strings.Split(text, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return reflect.ValueOf(items).Convert(t), nil
+	}
+	return reflect.Value{}, fmt.Errorf("settings of type %s cannot be given as text", t)
+}
+
+// YAMLKey returns the key a struct field is stored under in YAML files
+func YAMLKey(field reflect.StructField) string {
+	key, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if key == "" {
+		return strings.ToLower(field.Name)
+	}
+	return key
+}
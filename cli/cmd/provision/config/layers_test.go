@@ -0,0 +1,189 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+// layered stands in for the config of a component
+type layered struct {
+	Name        string   `yaml:"cluster_name,omitempty"`
+	Version     string   `yaml:"version"`
+	WorkerCount int      `yaml:"worker_count"`
+	NodeMemory  string   `yaml:"node_memory"`
+	DNSServers  []string `yaml:"dns_servers"`
+}
+
+// writeLayer writes a YAML file to dir and returns its path
+func writeLayer(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	root := makeCheckout(t)
+	useAssetsEnv(t, root, nil)
+	if err := os.MkdirAll(filepath.Join(root, "ansible", "defaults"), 0o755); err != nil {
+		t.Fatalf("failed to create defaults dir: %v", err)
+	}
+	writeLayer(t, filepath.Join(root, "ansible", "defaults"), "demo.yml",
+		"version: \"1.0\"\nworker_count: 3\nnode_memory: 8G\n# not a setting of the CLI\nextra: 1\n")
+	dir := t.TempDir()
+	profilePath := writeLayer(t, dir, "small.yml", "worker_count: 1\nnode_memory: 4G\n")
+	userPath := writeLayer(t, dir, "lab.yml", "node_memory: 2G\n")
+
+	fs := pflag.NewFlagSet("demo", pflag.ContinueOnError)
+	var flags layered
+	fs.IntVar(&flags.WorkerCount, "worker-count", 0, "")
+	fs.StringVar(&flags.Version, "version", "", "")
+	fs.Bool("yes", false, "")
+	if err := fs.Parse([]string{"--worker-count", "5", "--yes"}); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	config := &layered{DNSServers: []string{"8.8.8.8"}}
+	environ := []string{
+		"PROVISION_DEMO_WORKER_COUNT=4",
+		"PROVISION_DEMO_DNS_SERVERS=1.1.1.1, 9.9.9.9",
+		"PROVISION_OTHER_WORKER_COUNT=9",
+		"HOME=/home/user",
+	}
+	settings, err := Load(config,
+		DefaultsLayer("demo"),
+		FileLayer(profilePath, "profile small"),
+		FileLayer(userPath, userPath),
+		EnvLayer("demo", environ),
+		FlagLayer(fs, func() {
+			if fs.Changed("worker-count") {
+				config.WorkerCount = flags.WorkerCount
+			}
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	want := &layered{Version: "1.0", WorkerCount: 5, NodeMemory: "2G", DNSServers: []string{"1.1.1.1", "9.9.9.9"}}
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("config = %+v, want %+v", config, want)
+	}
+	for key, origin := range map[string]string{
+		"cluster_name": OriginBuiltin,
+		"version":      "ansible/defaults/demo.yml",
+		"worker_count": "flag --worker-count",
+		"node_memory":  userPath,
+		"dns_servers":  "$PROVISION_DEMO_DNS_SERVERS",
+	} {
+		if got := settings.Origin(key); got != origin {
+			t.Errorf("Origin(%s) = %q, want %q", key, got, origin)
+		}
+	}
+}
+
+func TestEnvLayerErrors(t *testing.T) {
+	env := "PROVISION_DEMO_WORKER_COUNT=two"
+	_, err := Load(&layered{}, EnvLayer("demo", []string{env}))
+	if want := `"two" is not a number`; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("Load() with %s error = %v, want %q", env, err, want)
+	}
+}
+
+func TestEnvLayerSkipsUnknownKeys(t *testing.T) {
+	config := &layered{WorkerCount: 1}
+	settings, err := Load(config, EnvLayer("demo", []string{"PROVISION_DEMO_WORKERS=2", "PROVISION_DEMO_WORKER_COUNT=3"}))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if config.WorkerCount != 3 {
+		t.Errorf("WorkerCount = %d, want the known variable applied", config.WorkerCount)
+	}
+	if len(settings.Warnings) != 1 || !strings.Contains(settings.Warnings[0], "$PROVISION_DEMO_WORKERS does not name a demo setting") {
+		t.Errorf("Warnings = %q, want one naming the unknown variable", settings.Warnings)
+	}
+}
+
+func TestFileLayerErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := Load(&layered{}, FileLayer(filepath.Join(dir, "missing.yml"), "missing")); err == nil {
+		t.Errorf("Load() with a missing file returned nil error")
+	}
+	bad := writeLayer(t, dir, "bad.yml", "worker_count: [1\n")
+	if _, err := Load(&layered{}, FileLayer(bad, bad)); err == nil || !strings.Contains(err.Error(), bad) {
+		t.Errorf("Load() with invalid YAML error = %v, want one naming the file", err)
+	}
+	if _, err := Load(layered{}); err == nil {
+		t.Errorf("Load() of a struct value returned nil error")
+	}
+}
+
+func TestSettingsWrite(t *testing.T) {
+	dir := t.TempDir()
+	path := writeLayer(t, dir, "lab.yml", "worker_count: 2\n")
+	settings, err := Load(&layered{Version: "1.0", DNSServers: []string{"8.8.8.8"}}, FileLayer(path, "lab.yml"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	var plain bytes.Buffer
+	if err := settings.Write(&plain, false); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if want := "version: \"1.0\"\nworker_count: 2\nnode_memory: \"\"\ndns_servers:\n  - 8.8.8.8\n"; plain.String() != want {
+		t.Errorf("Write() = %q, want %q", plain.String(), want)
+	}
+
+	var withOrigin bytes.Buffer
+	if err := settings.Write(&withOrigin, true); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	for _, line := range []string{
+		"version: \"1.0\" # built-in default",
+		"worker_count: 2 # lab.yml",
+		"dns_servers: # built-in default",
+	} {
+		if !strings.Contains(withOrigin.String(), line+"\n") {
+			t.Errorf("Write() with origins = %q, want line %q", withOrigin.String(), line)
+		}
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	for _, tc := range []struct {
+		t    reflect.Type
+		text string
+		want interface{}
+	}{
+		{reflect.TypeOf(""), " 8G ", "8G"},
+		{reflect.TypeOf(0), "3", 3},
+		{reflect.TypeOf([]string{}), "1.1.1.1, ,9.9.9.9", []string{"1.1.1.1", "9.9.9.9"}},
+		{reflect.TypeOf([]string{}), "", []string{}},
+	} {
+		got, err := ParseValue(tc.t, tc.text)
+		if err != nil || !reflect.DeepEqual(got.Interface(), tc.want) {
+			t.Errorf("ParseValue(%s, %q) = %v, %v, want %v", tc.t, tc.text, got, err, tc.want)
+		}
+	}
+	if _, err := ParseValue(reflect.TypeOf(true), "true"); err == nil {
+		t.Errorf("ParseValue() of a bool returned nil error")
+	}
+}
+
+func TestYAMLKey(t *testing.T) {
+	fieldType := reflect.TypeOf(layered{})
+	for name, want := range map[string]string{"Name": "cluster_name", "Version": "version"} {
+		field, _ := fieldType.FieldByName(name)
+		if got := YAMLKey(field); got != want {
+			t.Errorf("YAMLKey(%s) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+// Package doctor checks that the host has the tools and setup provisioning
+// needs, and tells how to fix what is missing
+package doctor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MinAnsibleVersion is the oldest ansible-core release the playbooks are
+// tested with
+const MinAnsibleVersion = "2.14"
+
+// Status is the outcome of a check
+type Status int
+
+const (
+	OK Status = iota
+	// Warning is a problem that only affects some uses of the CLI
+	Warning
+	// Failure is a problem that makes provisioning fail
+	Failure
+)
+
+// Result is the outcome of one check
+type Result struct {
+	Name   string
+	Status Status
+	// Detail is the version found or what is wrong
+	Detail string
+	// Fix tells how to resolve a warning or failure
+	Fix string
+}
+
+// Options select the checks that only matter for some clusters
+type Options struct {
+	// Kubernetes adds kubectl and helm, which are used with the
+	// kubeconfig of a provisioned Kubernetes cluster
+	Kubernetes bool
+}
+
+// lookPath, run and the paths the virtualization check reads are variables
+// so tests can fake the installed tools and the host
+var (
+	lookPath = exec.LookPath
+	run      = func(name string, args ...string) ([]byte, error) {
+		return exec.Command(name, args...).CombinedOutput()
+	}
+	kvmDevice   = "/dev/kvm"
+	cpuinfoPath = "/proc/cpuinfo"
+)
+
+// Run runs every check and returns the results in order
+func Run(opts Options) []Result {
+	results := []Result{checkMultipass()}
+	if results[0].Status == OK {
+		results = append(results, checkMultipassService())
+	}
+	results = append(results, checkVirtualization())
+	results = append(results, checkAnsible())
+
+	python := checkPython()
+	results = append(results, python)
+	if python.Status == OK {
+		results = append(results, checkPyYAML())
+	}
+
+	results = append(results, checkTool("ssh-keygen", Failure,
+		"Install the OpenSSH client tools: sudo apt install openssh-client"))
+
+	if opts.Kubernetes {
+		results = append(results,
+			checkTool("kubectl", Warning,
+				"Install kubectl to use the cluster from the host: sudo snap install kubectl --classic"),
+			checkTool("helm", Warning,
+				"Install Helm to deploy the charts in helm/: sudo snap install helm --classic"))
+	}
+	return results
+}
+
+// Failed reports whether any check failed
+func Failed(results []Result) bool {
+	for _, result := range results {
+		if result.Status == Failure {
+			return true
+		}
+	}
+	return false
+}
+
+// Print writes the results with the fix of each warning and failure
+func Print(w io.Writer, results []Result) {
+	symbols := map[Status]string{OK: "✓", Warning: "⚠", Failure: "✗"}
+	for _, result := range results {
+		fmt.Fprintf(w, "%s %s", symbols[result.Status], result.Name)
+		if result.Detail != "" {
+			fmt.Fprintf(w, ": %s", result.Detail)
+		}
+		fmt.Fprintln(w)
+		if result.Status != OK && result.Fix != "" {
+			for _, line := range strings.Split(result.Fix, "\n") {
+				fmt.Fprintf(w, "    %s\n", line)
+			}
+		}
+	}
+
+	failures, warnings := 0, 0
+	for _, result := range results {
+		switch result.Status {
+		case Failure:
+			failures++
+		case Warning:
+			warnings++
+		}
+	}
+	switch {
+	case failures > 0:
+		fmt.Fprintf(w, "\n%d check(s) failed, %d warning(s)\n", failures, warnings)
+	case warnings > 0:
+		fmt.Fprintf(w, "\nReady to provision, %d warning(s)\n", warnings)
+	default:
+		fmt.Fprintln(w, "\nReady to provision")
+	}
+}
+
+// checkTool checks that name is in PATH, failing with status if it is not
+func checkTool(name string, status Status, fix string) Result {
+	path, err := lookPath(name)
+	if err != nil {
+		return Result{Name: name, Status: status, Detail: "not found in PATH", Fix: fix}
+	}
+	return Result{Name: name, Status: OK, Detail: path}
+}
+
+func checkMultipass() Result {
+	result := checkTool("multipass", Failure,
+		"Install Multipass: sudo snap install multipass\nSee https://canonical.com/multipass/install")
+	if result.Status != OK {
+		return result
+	}
+
+	out, err := run("multipass", "version")
+	if err != nil {
+		return Result{Name: "multipass", Status: Failure, Detail: commandError(out, err),
+			Fix: "Reinstall Multipass: sudo snap refresh multipass"}
+	}
+	result.Detail = firstLine(out)
+	return result
+}
+
+// checkMultipassService checks that the daemon is reachable and accepts
+// this client, the "multipass authenticate" problem from the README
+func checkMultipassService() Result {
+	out, err := run("multipass", "list", "--format", "json")
+	if err == nil {
+		return Result{Name: "multipass service", Status: OK, Detail: "reachable and authenticated"}
+	}
+
+	if strings.Contains(string(out), "not authenticated") {
+		return Result{
+			Name:   "multipass service",
+			Status: Failure,
+			Detail: "the client is not authenticated with the Multipass service",
+			Fix: "Trust this client's certificate and restart the daemon:\n" +
+				"cat ~/snap/multipass/current/data/multipass-client-certificate/multipass_cert.pem | " +
+				"sudo tee -a /var/snap/multipass/common/data/multipassd/authenticated-certs/multipass_client_certs.pem > /dev/null\n" +
+				"sudo snap restart multipass",
+		}
+	}
+	return Result{Name: "multipass service", Status: Failure, Detail: commandError(out, err),
+		Fix: "Restart the daemon: sudo snap restart multipass\nSee https://canonical.com/multipass/docs/troubleshooting"}
+}
+
+func checkAnsible() Result {
+	fix := fmt.Sprintf("Install ansible-core %s or newer: pipx install --include-deps ansible\n"+
+		"See https://docs.ansible.com/ansible/latest/installation_guide/index.html", MinAnsibleVersion)
+
+	result := checkTool("ansible-playbook", Failure, fix)
+	if result.Status != OK {
+		return result
+	}
+
+	out, err := run("ansible-playbook", "--version")
+	if err != nil {
+		return Result{Name: "ansible-playbook", Status: Failure, Detail: commandError(out, err), Fix: fix}
+	}
+	version := firstLine(out)
+	ok, err := atLeast(version, MinAnsibleVersion)
+	if err != nil {
+		return Result{Name: "ansible-playbook", Status: Warning, Detail: fmt.Sprintf("%s (%v)", version, err), Fix: fix}
+	}
+	if !ok {
+		return Result{Name: "ansible-playbook", Status: Failure,
+			Detail: fmt.Sprintf("%s is older than %s", version, MinAnsibleVersion), Fix: fix}
+	}
+	return Result{Name: "ansible-playbook", Status: OK, Detail: version}
+}
+
+func checkPython() Result {
+	fix := "Install Python 3, which Ansible needs: sudo apt install python3"
+	result := checkTool("python3", Failure, fix)
+	if result.Status != OK {
+		return result
+	}
+
+	out, err := run("python3", "--version")
+	if err != nil {
+		return Result{Name: "python3", Status: Failure, Detail: commandError(out, err), Fix: fix}
+	}
+	result.Detail = firstLine(out)
+	return result
+}
+
+func checkPyYAML() Result {
+	out, err := run("python3", "-c", "import yaml; print(yaml.__version__)")
+	if err != nil {
+		return Result{Name: "PyYAML", Status: Failure, Detail: "python3 cannot import yaml",
+			Fix: "Install PyYAML: sudo apt install python3-yaml"}
+	}
+	return Result{Name: "PyYAML", Status: OK, Detail: firstLine(out)}
+}
+
+var versionPattern = regexp.MustCompile(`(\d+)\.(\d+)`)
+
+// atLeast reports whether the first major.minor version in text is at
+// least min
+func atLeast(text, min string) (bool, error) {
+	found := versionPattern.FindStringSubmatch(text)
+	if found == nil {
+		return false, errors.New("no version number found")
+	}
+	want := versionPattern.FindStringSubmatch(min)
+
+	for i := 1; i <= 2; i++ {
+		have, _ := strconv.Atoi(found[i])
+		need, _ := strconv.Atoi(want[i])
+		if have != need {
+			return have > need, nil
+		}
+	}
+	return true, nil
+}
+
+// commandError describes a failed command by its output, or by the error
+// when there is none
+func commandError(out []byte, err error) string {
+	if line := firstLine(out); line != "" {
+		return line
+	}
+	return err.Error()
+}
+
+func firstLine(out []byte) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	return strings.TrimSpace(line)
+}
@@ -0,0 +1,186 @@
+package doctor
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeHost replaces the installed tools with those in outputs, keyed by the
+// command line. A tool is installed when any of its command lines is listed.
+func fakeHost(t *testing.T, outputs map[string]string, failing map[string]bool) {
+	t.Helper()
+	origLookPath, origRun := lookPath, run
+	t.Cleanup(func() { lookPath, run = origLookPath, origRun })
+
+	lookPath = func(name string) (string, error) {
+		for command := range outputs {
+			if strings.HasPrefix(command+" ", name+" ") {
+				return "/usr/bin/" + name, nil
+			}
+		}
+		return "", errors.New("executable file not found in $PATH")
+	}
+	run = func(name string, args ...string) ([]byte, error) {
+		command := strings.Join(append([]string{name}, args...), " ")
+		out, ok := outputs[command]
+		if !ok || failing[command] {
+			return []byte(out), errors.New("exit status 1")
+		}
+		return []byte(out), nil
+	}
+}
+
+// healthyOutputs are the outputs of a host with every tool installed
+func healthyOutputs() map[string]string {
+	return map[string]string{
+		"multipass version":                               "multipass   1.14.0\nmultipassd  1.14.0",
+		"multipass list --format json":                    `{"list": []}`,
+		"ansible-playbook --version":                      "ansible-playbook [core 2.16.3]\n  config file = None",
+		"python3 --version":                               "Python 3.12.3",
+		"python3 -c import yaml; print(yaml.__version__)": "6.0.1",
+		"ssh-keygen":                                      "",
+		"kubectl":                                         "",
+		"helm":                                            "",
+	}
+}
+
+// withKVM points the virtualization check at a KVM device that exists
+func withKVM(t *testing.T) {
+	t.Helper()
+	origDevice := kvmDevice
+	t.Cleanup(func() { kvmDevice = origDevice })
+	kvmDevice = filepath.Join(t.TempDir(), "kvm")
+	if err := os.WriteFile(kvmDevice, nil, 0o600); err != nil {
+		t.Fatalf("Failed to create fake KVM device: %v", err)
+	}
+}
+
+// byName returns the result of the named check
+func byName(t *testing.T, results []Result, name string) Result {
+	t.Helper()
+	for _, result := range results {
+		if result.Name == name {
+			return result
+		}
+	}
+	t.Fatalf("no %s check in %+v", name, results)
+	return Result{}
+}
+
+func TestRunHealthy(t *testing.T) {
+	fakeHost(t, healthyOutputs(), nil)
+	withKVM(t)
+
+	results := Run(Options{Kubernetes: true})
+	for _, result := range results {
+		if result.Status != OK {
+			t.Errorf("%s = %+v, want OK", result.Name, result)
+		}
+	}
+	if Failed(results) {
+		t.Errorf("Failed() = true, want false")
+	}
+	if got := byName(t, results, "ansible-playbook").Detail; got != "ansible-playbook [core 2.16.3]" {
+		t.Errorf("ansible-playbook detail = %q, want the version line", got)
+	}
+
+	// kubectl and helm are only checked for Kubernetes
+	for _, result := range Run(Options{}) {
+		if result.Name == "kubectl" || result.Name == "helm" {
+			t.Errorf("%s checked without Kubernetes", result.Name)
+		}
+	}
+}
+
+func TestRunProblems(t *testing.T) {
+	outputs := healthyOutputs()
+	outputs["multipass list --format json"] = "list failed: The client is not authenticated with the Multipass service."
+	outputs["ansible-playbook --version"] = "ansible-playbook 2.9.6"
+	delete(outputs, "helm")
+	fakeHost(t, outputs, map[string]bool{
+		"multipass list --format json":                    true,
+		"python3 -c import yaml; print(yaml.__version__)": true,
+	})
+	withKVM(t)
+
+	results := Run(Options{Kubernetes: true})
+	if !Failed(results) {
+		t.Errorf("Failed() = false, want true")
+	}
+
+	service := byName(t, results, "multipass service")
+	if service.Status != Failure || !strings.Contains(service.Fix, "authenticated-certs") {
+		t.Errorf("multipass service = %+v, want the authentication workaround", service)
+	}
+	if ansible := byName(t, results, "ansible-playbook"); ansible.Status != Failure || !strings.Contains(ansible.Detail, "older than 2.14") {
+		t.Errorf("ansible-playbook = %+v, want a too old failure", ansible)
+	}
+	if pyyaml := byName(t, results, "PyYAML"); pyyaml.Status != Failure || !strings.Contains(pyyaml.Fix, "python3-yaml") {
+		t.Errorf("PyYAML = %+v, want a failure with the package to install", pyyaml)
+	}
+	if helm := byName(t, results, "helm"); helm.Status != Warning {
+		t.Errorf("helm = %+v, want only a warning", helm)
+	}
+}
+
+func TestRunWithoutMultipass(t *testing.T) {
+	outputs := healthyOutputs()
+	delete(outputs, "multipass version")
+	delete(outputs, "multipass list --format json")
+	fakeHost(t, outputs, nil)
+	withKVM(t)
+
+	results := Run(Options{})
+	if multipass := byName(t, results, "multipass"); multipass.Status != Failure || !strings.Contains(multipass.Fix, "snap install multipass") {
+		t.Errorf("multipass = %+v, want a failure with install steps", multipass)
+	}
+	for _, result := range results {
+		if result.Name == "multipass service" {
+			t.Errorf("multipass service checked without multipass")
+		}
+	}
+}
+
+func TestAtLeast(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"ansible-playbook [core 2.16.3]", true},
+		{"ansible-playbook [core 2.14.0]", true},
+		{"ansible-playbook 2.9.6", false},
+		{"ansible-playbook [core 3.0.0]", true},
+	}
+	for _, tt := range tests {
+		if got, err := atLeast(tt.text, "2.14"); err != nil || got != tt.want {
+			t.Errorf("atLeast(%q, 2.14) = %t, %v, want %t", tt.text, got, err, tt.want)
+		}
+	}
+	if _, err := atLeast("ansible-playbook", "2.14"); err == nil {
+		t.Errorf("atLeast() error = nil, want an error without a version")
+	}
+}
+
+func TestPrint(t *testing.T) {
+	var buf bytes.Buffer
+	Print(&buf, []Result{
+		{Name: "multipass", Status: OK, Detail: "multipass 1.14.0"},
+		{Name: "helm", Status: Warning, Detail: "not found in PATH", Fix: "Install Helm"},
+		{Name: "PyYAML", Status: Failure, Detail: "python3 cannot import yaml", Fix: "Install PyYAML\nthen retry"},
+	})
+	out := buf.String()
+	for _, want := range []string{
+		"✓ multipass: multipass 1.14.0\n",
+		"⚠ helm: not found in PATH\n    Install Helm\n",
+		"✗ PyYAML: python3 cannot import yaml\n    Install PyYAML\n    then retry\n",
+		"1 check(s) failed, 1 warning(s)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Print() output does not contain %q:\n%s", want, out)
+		}
+	}
+}
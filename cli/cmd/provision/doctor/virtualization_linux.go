@@ -0,0 +1,25 @@
+package doctor
+
+import (
+	"os"
+	"strings"
+)
+
+// checkVirtualization checks that KVM is available, which Multipass uses to
+// run the VMs on Linux
+func checkVirtualization() Result {
+	if _, err := os.Stat(kvmDevice); err == nil {
+		return Result{Name: "virtualization", Status: OK, Detail: "KVM is available"}
+	}
+
+	cpuinfo, err := os.ReadFile(cpuinfoPath)
+	if err == nil && !strings.Contains(string(cpuinfo), " vmx") && !strings.Contains(string(cpuinfo), " svm") {
+		return Result{Name: "virtualization", Status: Failure,
+			Detail: "the CPU does not report hardware virtualization (vmx or svm)",
+			Fix: "Enable Intel VT-x or AMD-V in the BIOS/UEFI settings.\n" +
+				"In a VM or WSL, enable nested virtualization on the host."}
+	}
+	return Result{Name: "virtualization", Status: Failure, Detail: kvmDevice + " does not exist",
+		Fix: "Load the KVM module: sudo modprobe kvm_intel (or kvm_amd)\n" +
+			"If that fails, enable Intel VT-x or AMD-V in the BIOS/UEFI settings."}
+}
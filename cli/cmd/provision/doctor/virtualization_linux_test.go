@@ -0,0 +1,29 @@
+package doctor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckVirtualization(t *testing.T) {
+	dir := t.TempDir()
+	origDevice, origCpuinfo := kvmDevice, cpuinfoPath
+	t.Cleanup(func() { kvmDevice, cpuinfoPath = origDevice, origCpuinfo })
+	kvmDevice = filepath.Join(dir, "kvm")
+	cpuinfoPath = filepath.Join(dir, "cpuinfo")
+
+	for cpuinfo, wantFix := range map[string]string{
+		"flags\t\t: fpu vme de pse\n":     "BIOS",
+		"flags\t\t: fpu vme de pse vmx\n": "modprobe",
+	} {
+		if err := os.WriteFile(cpuinfoPath, []byte(cpuinfo), 0o644); err != nil {
+			t.Fatalf("Failed to write cpuinfo: %v", err)
+		}
+		result := checkVirtualization()
+		if result.Status != Failure || !strings.Contains(result.Fix, wantFix) {
+			t.Errorf("checkVirtualization() with cpuinfo %q = %+v, want a failure mentioning %s", cpuinfo, result, wantFix)
+		}
+	}
+}
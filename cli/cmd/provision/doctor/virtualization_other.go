@@ -0,0 +1,9 @@
+//go:build !linux
+
+package doctor
+
+// checkVirtualization is only implemented on Linux, the supported host OS.
+// Elsewhere, a working "multipass list" is the best sign available.
+func checkVirtualization() Result {
+	return Result{Name: "virtualization", Status: Warning, Detail: "only checked on Linux"}
+}
@@ -0,0 +1,131 @@
+package interactive
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/config"
+)
+
+// Field customizes how PromptForm asks for one setting
+type Field struct {
+	// Options turns the question into a selection from these values
+	Options []string
+	// Validate rejects an answer, which is then asked for again. It gets
+	// the answer converted to the type of the setting, see CheckString,
+	// CheckInt and CheckList.
+	Validate func(value interface{}) error
+	// Skip leaves the setting unchanged when it returns true. It is called
+	// once the settings declared before it have been asked for.
+	Skip func() bool
+}
+
+// askOne is a variable so tests can answer the questions
+var askOne = survey.AskOne
+
+// PromptForm asks for the settings of the struct cfg points to, in the
+// order they are declared, offering their current values as defaults. Only
+// fields with a prompt tag, which holds the question, are asked for. fields
+// customizes the questions by the settings' YAML keys. String, int and
+// string list settings are supported; lists are entered comma-separated.
+func PromptForm(cfg interface{}, fields map[string]Field) error {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("form needs a pointer to a struct, got %T", cfg)
+	}
+	v = v.Elem()
+
+	for i := 0; i < v.NumField(); i++ {
+		structField := v.Type().Field(i)
+		message, ok := structField.Tag.Lookup("prompt")
+		if !ok {
+			continue
+		}
+		field := fields[config.YAMLKey(structField)]
+		if field.Skip != nil && field.Skip() {
+			continue
+		}
+		if err := askField(message, v.Field(i), field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckString adapts a check of a string setting to Field.Validate
+func CheckString(check func(string) error) func(interface{}) error {
+	return func(value interface{}) error { return check(value.(string)) }
+}
+
+// CheckInt adapts a check of an int setting to Field.Validate
+func CheckInt(check func(int) error) func(interface{}) error {
+	return func(value interface{}) error { return check(value.(int)) }
+}
+
+// CheckList adapts a check of a string list setting to Field.Validate
+func CheckList(check func([]string) error) func(interface{}) error {
+	return func(value interface{}) error { return check(value.([]string)) }
+}
+
+// askField asks for one setting and stores the answer in value
+func askField(message string, value reflect.Value, field Field) error {
+	current := formatValue(value)
+
+	var prompt survey.Prompt
+	if len(field.Options) > 0 {
+		selectPrompt := &survey.Select{Message: message, Options: field.Options}
+		for _, option := range field.Options {
+			if option == current {
+				selectPrompt.Default = current
+			}
+		}
+		prompt = selectPrompt
+	} else {
+		prompt = &survey.Input{Message: message, Default: current}
+	}
+
+	validate := func(answer interface{}) error {
+		parsed, err := config.ParseValue(value.Type(), answerString(answer))
+		if err != nil {
+			return err
+		}
+		if field.Validate != nil {
+			return field.Validate(parsed.Interface())
+		}
+		return nil
+	}
+
+	var answer string
+	if err := askOne(prompt, &answer, survey.WithValidator(validate)); err != nil {
+		return err
+	}
+	parsed, err := config.ParseValue(value.Type(), answer)
+	if err != nil {
+		return err
+	}
+	value.Set(parsed)
+	return nil
+}
+
+// answerString returns the text of an answer passed to a validator, which
+// is an option for select questions
+func answerString(answer interface{}) string {
+	if option, ok := answer.(survey.OptionAnswer); ok {
+		return option.Value
+	}
+	return fmt.Sprint(answer)
+}
+
+// formatValue returns the text a setting is entered as
+func formatValue(value reflect.Value) string {
+	switch value.Kind() {
+	case reflect.Int:
+		return strconv.Itoa(int(value.Int()))
+	case reflect.Slice:
+		return strings.Join(value.Interface().([]string), ", ")
+	}
+	return value.String()
+}
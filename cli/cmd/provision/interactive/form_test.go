@@ -0,0 +1,120 @@
+package interactive
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/AlecAivazis/survey/v2"
+)
+
+type formConfig struct {
+	Name    string   `yaml:"name,omitempty"`
+	Version string   `yaml:"version" prompt:"Version"`
+	Plugin  string   `yaml:"plugin" prompt:"Plugin"`
+	Extra   string   `yaml:"extra" prompt:"Extra"`
+	CPUs    int      `yaml:"cpus" prompt:"CPUs"`
+	Servers []string `yaml:"servers" prompt:"Servers"`
+}
+
+// question is a question asked by a form
+type question struct {
+	message string
+	def     string
+}
+
+// answerForm replaces the prompts with the given answers, asking again
+// while an answer is rejected by the validators like survey does, and
+// records the questions and the rejections
+func answerForm(t *testing.T, answers ...string) (questions *[]question, rejected *[]string) {
+	t.Helper()
+	orig := askOne
+	t.Cleanup(func() { askOne = orig })
+
+	questions, rejected = new([]question), new([]string)
+	askOne = func(prompt survey.Prompt, response interface{}, opts ...survey.AskOpt) error {
+		options := &survey.AskOptions{}
+		for _, opt := range opts {
+			if err := opt(options); err != nil {
+				return err
+			}
+		}
+
+		switch p := prompt.(type) {
+		case *survey.Input:
+			*questions = append(*questions, question{p.Message, p.Default})
+		case *survey.Select:
+			*questions = append(*questions, question{p.Message, fmt.Sprint(p.Default)})
+		}
+
+	answers:
+		for len(answers) > 0 {
+			answer := answers[0]
+			answers = answers[1:]
+			var value interface{} = answer
+			if _, ok := prompt.(*survey.Select); ok {
+				value = survey.OptionAnswer{Value: answer}
+			}
+			for _, validate := range options.Validators {
+				if err := validate(value); err != nil {
+					*rejected = append(*rejected, err.Error())
+					continue answers
+				}
+			}
+			*response.(*string) = answer
+			return nil
+		}
+		return errors.New("no answers left")
+	}
+	return questions, rejected
+}
+
+func TestPromptForm(t *testing.T) {
+	questions, rejected := answerForm(t, "1.31", "b", "0", "x", "2", "1.1.1.1, 9.9.9.9")
+
+	config := &formConfig{Name: "lab", Version: "1.32", Plugin: "a", Extra: "kept", CPUs: 4, Servers: []string{"8.8.8.8", "8.8.4.4"}}
+	fields := map[string]Field{
+		"plugin": {Options: []string{"a", "b"}},
+		"extra":  {Skip: func() bool { return config.Plugin == "b" }},
+		"cpus": {Validate: CheckInt(func(cpus int) error {
+			if cpus < 1 {
+				return errors.New("must be at least 1")
+			}
+			return nil
+		})},
+	}
+	if err := PromptForm(config, fields); err != nil {
+		t.Fatalf("PromptForm() error = %v", err)
+	}
+
+	want := &formConfig{Name: "lab", Version: "1.31", Plugin: "b", Extra: "kept", CPUs: 2, Servers: []string{"1.1.1.1", "9.9.9.9"}}
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("config = %+v, want %+v", config, want)
+	}
+
+	// Fields without a prompt tag and skipped fields are not asked for, and
+	// the current values are the defaults
+	wantQuestions := []question{
+		{"Version", "1.32"},
+		{"Plugin", "a"},
+		{"CPUs", "4"},
+		{"Servers", "8.8.8.8, 8.8.4.4"},
+	}
+	if !reflect.DeepEqual(*questions, wantQuestions) {
+		t.Errorf("questions = %v, want %v", *questions, wantQuestions)
+	}
+
+	// A rejected answer is asked for again
+	if len(*rejected) != 2 || (*rejected)[0] != "must be at least 1" || !strings.Contains((*rejected)[1], "not a number") {
+		t.Errorf("rejected answers = %q, want the zero CPUs and x", *rejected)
+	}
+}
+
+func TestPromptFormNeedsStruct(t *testing.T) {
+	answerForm(t)
+	if err := PromptForm(formConfig{}, nil); err == nil {
+		t.Errorf("PromptForm() with a struct value returned nil error, want a pointer to be required")
+	}
+}
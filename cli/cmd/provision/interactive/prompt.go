@@ -2,12 +2,20 @@ package interactive
 
 import (
 	"fmt"
+	"os"
 	"strconv"
 	"strings"
 
 	"github.com/AlecAivazis/survey/v2"
+	"golang.org/x/term"
 )
 
+// IsTerminal reports whether stdin is attached to a terminal, i.e. whether
+// there is someone to answer prompts
+func IsTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
 // PromptText asks the user for text input
 func PromptText(message string, defaultValue string) (string, error) {
 	var result string
@@ -46,6 +54,25 @@ func PromptSelect(message string, options []string) (string, error) {
 	return result, err
 }
 
+// PromptSelectDefault asks the user to select from a list of options,
+// preselecting defaultValue if it is one of them
+func PromptSelectDefault(message string, options []string, defaultValue string) (string, error) {
+	var result string
+
+	prompt := &survey.Select{
+		Message: message,
+		Options: options,
+	}
+	for _, option := range options {
+		if option == defaultValue {
+			prompt.Default = defaultValue
+		}
+	}
+
+	err := survey.AskOne(prompt, &result)
+	return result, err
+}
+
 // PromptConfirm asks the user for confirmation (yes/no)
 func PromptConfirm(message string) (bool, error) {
 	var result bool
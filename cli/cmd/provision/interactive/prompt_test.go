@@ -12,6 +12,7 @@ func TestPromptFunctionsExist(t *testing.T) {
 	var _ func(string, string) (string, error) = PromptText
 	var _ func(string) (string, error) = PromptPassword
 	var _ func(string, []string) (string, error) = PromptSelect
+	var _ func(string, []string, string) (string, error) = PromptSelectDefault
 	var _ func(string) (bool, error) = PromptConfirm
 	var _ func(string, int) (int, error) = PromptInt
 	var _ func(string, int, int, int) (int, error) = PromptIntWithRange
@@ -0,0 +1,126 @@
+package kubernetes
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+)
+
+// CNI plugins that provisioning can install, see
+// ansible/tasks/kubernetes/cni-*.yml
+const (
+	CNICalico  = "calico"
+	CNIFlannel = "flannel"
+	CNICilium  = "cilium"
+)
+
+// CNIPlugins lists the supported CNI plugins, the default first
+var CNIPlugins = []string{CNICalico, CNIFlannel, CNICilium}
+
+// nodeCIDRSize is the prefix length of the pod subnet the controller manager
+// set up by kubeadm assigns to each node. It refuses a larger pod_cidr
+// prefix.
+const nodeCIDRSize = 24
+
+// cniBlockSizes are the prefix lengths of the pod subnets the plugins hand
+// out to nodes: Calico allocates /26 blocks as needed, Flannel uses the
+// node's subnet and Cilium is installed with a /24 per node
+var cniBlockSizes = map[string]int{
+	CNICalico:  26,
+	CNIFlannel: nodeCIDRSize,
+	CNICilium:  24,
+}
+
+// cniVersionPattern matches plugin versions, with or without a leading v
+var cniVersionPattern = regexp.MustCompile(`^v?[0-9]+\.[0-9]+\.[0-9]+$`)
+
+// cniVersion returns the version field of the named plugin, or nil if the
+// plugin is not supported
+func (c *Config) cniVersion(plugin string) *string {
+	switch plugin {
+	case CNICalico:
+		return &c.CalicoVersion
+	case CNIFlannel:
+		return &c.FlannelVersion
+	case CNICilium:
+		return &c.CiliumVersion
+	}
+	return nil
+}
+
+// CNIVersion returns the version of the chosen CNI plugin
+func (c *Config) CNIVersion() string {
+	if version := c.cniVersion(c.CNIPlugin); version != nil {
+		return *version
+	}
+	return ""
+}
+
+// checkPodCapacity rejects pod CIDRs without a subnet of the CNI plugin for
+// every node. The pod CIDR and plugin must be valid.
+func (c *Config) checkPodCapacity() error {
+	_, podNet, _ := net.ParseCIDR(c.PodCIDR)
+	prefix, _ := podNet.Mask.Size()
+	nodes := len(c.ControlPlaneNames()) + c.WorkerCount
+	blockSize := cniBlockSizes[c.CNIPlugin]
+	if subnets := 1 << (blockSize - prefix); subnets < nodes {
+		return fmt.Errorf("must hold a /%d %s subnet per node, %s holds %d for %d nodes", blockSize, c.CNIPlugin, c.PodCIDR, subnets, nodes)
+	}
+	return nil
+}
+
+// checkCNIPlugin rejects plugins provisioning cannot install
+func checkCNIPlugin(plugin string) error {
+	for _, supported := range CNIPlugins {
+		if plugin == supported {
+			return nil
+		}
+	}
+	return fmt.Errorf("must be one of %s, got %q", strings.Join(CNIPlugins, ", "), plugin)
+}
+
+// checkCNIVersion rejects plugin versions that are not releases
+func checkCNIVersion(version string) error {
+	if !cniVersionPattern.MatchString(version) {
+		return fmt.Errorf("must look like v1.2.3, got %q", version)
+	}
+	return nil
+}
+
+// checkPodCIDR rejects pod CIDRs kubeadm cannot split into node subnets
+func checkPodCIDR(cidr string) error {
+	podNet, err := parseIPv4CIDR(cidr)
+	if err != nil {
+		return err
+	}
+	if prefix, _ := podNet.Mask.Size(); prefix > nodeCIDRSize {
+		return fmt.Errorf("must be a /%d or larger as kubeadm gives each node a /%d, got %s", nodeCIDRSize, nodeCIDRSize, cidr)
+	}
+	return nil
+}
+
+// checkServiceCIDR rejects service CIDRs that overlap the pod CIDR
+func (c *Config) checkServiceCIDR(cidr string) error {
+	serviceNet, err := parseIPv4CIDR(cidr)
+	if err != nil {
+		return err
+	}
+	_, podNet, err := net.ParseCIDR(c.PodCIDR)
+	if err == nil && (podNet.Contains(serviceNet.IP) || serviceNet.Contains(podNet.IP)) {
+		return fmt.Errorf("must not overlap pod_cidr %s, got %s", c.PodCIDR, cidr)
+	}
+	return nil
+}
+
+// parseIPv4CIDR parses an IPv4 CIDR
+func parseIPv4CIDR(cidr string) (*net.IPNet, error) {
+	ip, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("must be a CIDR like 10.244.0.0/16, got %q", cidr)
+	}
+	if ip.To4() == nil {
+		return nil, fmt.Errorf("must be an IPv4 CIDR, got %s", cidr)
+	}
+	return ipNet, nil
+}
@@ -0,0 +1,53 @@
+package kubernetes
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateNetwork(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		want   string
+	}{
+		{"calico", Config{CNIPlugin: "calico", CalicoVersion: "v3.29.1"}, ""},
+		{"flannel without v", Config{CNIPlugin: "flannel", FlannelVersion: "0.26.2"}, ""},
+		{"cilium", Config{CNIPlugin: "cilium", CiliumVersion: "1.16.5"}, ""},
+		{"unsupported plugin", Config{CNIPlugin: "weave"}, "cni_plugin must be one of calico, flannel, cilium"},
+		{"invalid version", Config{CNIPlugin: "cilium", CiliumVersion: "latest"}, "cilium_version must look like v1.2.3"},
+		{"invalid pod CIDR", Config{PodCIDR: "192.168.0.0"}, "pod_cidr must be a CIDR"},
+		{"IPv6 service CIDR", Config{ServiceCIDR: "fd00::/108"}, "service_cidr must be an IPv4 CIDR"},
+		{"overlapping CIDRs", Config{PodCIDR: "10.0.0.0/8"}, "must not overlap pod_cidr"},
+		{"pod CIDR too small", Config{PodCIDR: "192.168.0.0/25"}, "must be a /24 or larger"},
+		{"calico fits four nodes in a /24", Config{PodCIDR: "192.168.0.0/24", WorkerCount: 3}, ""},
+		{"flannel needs a /24 per node", Config{CNIPlugin: "flannel", FlannelVersion: "v0.26.2", PodCIDR: "192.168.0.0/23", WorkerCount: 2}, "must hold a /24 flannel subnet per node, 192.168.0.0/23 holds 2 for 3 nodes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.ControlPlaneCount = 1
+			err := withDefaults(&tt.config).Validate()
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestProvisionRejectsUnsupportedCNI(t *testing.T) {
+	_, provider, playbookArgs := setupProvision(t)
+
+	err := Provision(withDefaults(&Config{ControlPlaneCount: 1, CNIPlugin: "weave"}))
+	if err == nil || !strings.Contains(err.Error(), "cni_plugin") {
+		t.Errorf("Provision() error = %v, want the CNI plugin to be refused", err)
+	}
+	if len(provider.CallsTo("launch")) != 0 || len(*playbookArgs) != 0 {
+		t.Errorf("Provision() launched VMs or ran the playbook")
+	}
+}
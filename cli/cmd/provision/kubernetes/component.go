@@ -0,0 +1,82 @@
+package kubernetes
+
+import (
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/component"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/plan"
+	"github.com/spf13/pflag"
+)
+
+func init() {
+	component.Register(&clusterComponent{})
+}
+
+var _ component.Converger = (*clusterComponent)(nil)
+
+// clusterComponent makes the Kubernetes cluster available to the CLI
+// commands. flags holds the values of the flags registered by AddFlags.
+type clusterComponent struct {
+	flags Config
+}
+
+func (c *clusterComponent) Name() string  { return ComponentName }
+func (c *clusterComponent) Title() string { return "Kubernetes Cluster" }
+
+func (c *clusterComponent) NewConfig() component.Config {
+	return BuiltinConfig()
+}
+
+func (c *clusterComponent) AddFlags(fs *pflag.FlagSet) {
+	addFlags(fs, &c.flags)
+}
+
+func (c *clusterComponent) ApplyFlags(fs *pflag.FlagSet, config component.Config) {
+	applyFlags(fs, &c.flags, config.(*Config))
+}
+
+func (c *clusterComponent) PrintConfig(config component.Config) {
+	PrintConfig(config.(*Config))
+}
+
+func (c *clusterComponent) Prompt(config component.Config) error {
+	return PromptConfig(config.(*Config))
+}
+
+func (c *clusterComponent) SetName(config component.Config, name string) {
+	config.(*Config).SetName(name)
+}
+
+func (c *clusterComponent) Validate(config component.Config) error {
+	return config.(*Config).Validate()
+}
+
+func (c *clusterComponent) Preflight(config component.Config) error {
+	return Preflight(config.(*Config))
+}
+
+func (c *clusterComponent) Provision(config component.Config) error {
+	return Provision(config.(*Config))
+}
+
+func (c *clusterComponent) Cleanup(config component.Config) error {
+	return Cleanup(config.(*Config))
+}
+
+func (c *clusterComponent) Status(config component.Config) (component.Status, error) {
+	return GetStatus(config.(*Config))
+}
+
+func (c *clusterComponent) PlanProvision(config component.Config) (*plan.Plan, error) {
+	return PlanProvision(config.(*Config))
+}
+
+func (c *clusterComponent) PlanCleanup(config component.Config) (*plan.Plan, error) {
+	return PlanCleanup(config.(*Config))
+}
+
+func (c *clusterComponent) Tags() []string {
+	return Tags
+}
+
+func (c *clusterComponent) Converge(config component.Config, opts component.ConvergeOptions) error {
+	return Converge(config.(*Config), opts)
+}
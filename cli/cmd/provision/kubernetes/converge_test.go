@@ -0,0 +1,110 @@
+package kubernetes
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/component"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/state"
+)
+
+func TestConverge(t *testing.T) {
+	_, provider, playbookArgs := setupProvision(t)
+	provider.ExecFunc = func(name string, command []string) ([]byte, error) {
+		return []byte(adminConf), nil
+	}
+
+	k8sConfig := withDefaults(&Config{KubernetesVersion: "1.31", ControlPlaneCount: 1, WorkerCount: 2})
+	if err := Provision(k8sConfig); err != nil {
+		t.Fatalf("Provision() error = %v", err)
+	}
+	*playbookArgs = nil
+	launched := len(provider.CallsTo("launch"))
+
+	if err := Converge(k8sConfig, component.ConvergeOptions{Tags: []string{"workers", "validate"}, Limit: []string{"node02"}}); err != nil {
+		t.Fatalf("Converge() error = %v", err)
+	}
+
+	args := strings.Join(*playbookArgs, " ")
+	if !strings.Contains(args, "--tags workers,validate --limit node02") {
+		t.Errorf("playbook args = %v, want the tags and limit", *playbookArgs)
+	}
+	if len(provider.CallsTo("launch")) != launched {
+		t.Errorf("launch calls = %v, want none while converging", provider.CallsTo("launch"))
+	}
+	cluster, err := state.Load(ClusterName)
+	if err != nil {
+		t.Fatalf("state.Load() error = %v", err)
+	}
+	if cluster.Status != state.StatusReady {
+		t.Errorf("recorded status = %s, want ready", cluster.Status)
+	}
+}
+
+func TestConvergeRejectsScope(t *testing.T) {
+	_, provider, playbookArgs := setupProvision(t)
+	provider.ExecFunc = func(name string, command []string) ([]byte, error) {
+		return []byte(adminConf), nil
+	}
+
+	k8sConfig := withDefaults(&Config{ControlPlaneCount: 1, WorkerCount: 1})
+	if err := Provision(k8sConfig); err != nil {
+		t.Fatalf("Provision() error = %v", err)
+	}
+	*playbookArgs = nil
+
+	tests := []struct {
+		name string
+		opts component.ConvergeOptions
+		want string
+	}{
+		{"unknown tag", component.ConvergeOptions{Tags: []string{"dns"}}, "unknown tag(s) dns"},
+		{"unknown host", component.ConvergeOptions{Limit: []string{"node02"}}, "unknown host(s) or group(s) node02"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Converge(k8sConfig, tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Converge() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+	if len(*playbookArgs) != 0 {
+		t.Errorf("playbook ran with an invalid scope")
+	}
+}
+
+func TestConvergeNotRecorded(t *testing.T) {
+	_, _, playbookArgs := setupProvision(t)
+
+	err := Converge(&Config{ControlPlaneCount: 1, WorkerCount: 1}, component.ConvergeOptions{})
+	if !errors.Is(err, state.ErrNotFound) {
+		t.Errorf("Converge() error = %v, want ErrNotFound", err)
+	}
+	if len(*playbookArgs) != 0 {
+		t.Errorf("playbook ran for an unrecorded cluster")
+	}
+}
+
+func TestConvergeStoppedVM(t *testing.T) {
+	_, provider, playbookArgs := setupProvision(t)
+	provider.ExecFunc = func(name string, command []string) ([]byte, error) {
+		return []byte(adminConf), nil
+	}
+
+	k8sConfig := withDefaults(&Config{ControlPlaneCount: 1, WorkerCount: 1})
+	if err := Provision(k8sConfig); err != nil {
+		t.Fatalf("Provision() error = %v", err)
+	}
+	*playbookArgs = nil
+	provider.SetState("node01", "Stopped")
+
+	err := Converge(k8sConfig, component.ConvergeOptions{})
+	if err == nil || !strings.Contains(err.Error(), "multipass start node01") {
+		t.Errorf("Converge() error = %v, want a hint to start node01", err)
+	}
+	if len(*playbookArgs) != 0 {
+		t.Errorf("playbook ran with a stopped VM")
+	}
+}
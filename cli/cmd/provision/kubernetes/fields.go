@@ -0,0 +1,70 @@
+package kubernetes
+
+import (
+	"errors"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/component"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/interactive"
+)
+
+// KubernetesVersions are the minor versions with a package repository on
+// pkgs.k8s.io that the playbooks support, newest first
+var KubernetesVersions = []string{"1.34", "1.33", "1.32", "1.31", "1.30"}
+
+// Validate checks every setting of c, including that the pod CIDR holds a
+// subnet for every node. It returns a *component.ValidationError listing the
+// invalid settings by YAML key.
+func (c *Config) Validate() error {
+	errs := component.CheckFields(c, c.formFields())
+	if !errs.Has("pod_cidr") && !errs.Has("cni_plugin") && !errs.Has("control_plane_count") && !errs.Has("worker_count") {
+		if err := c.checkPodCapacity(); err != nil {
+			errs.Add("pod_cidr", err)
+		}
+	}
+	return errs.OrNil()
+}
+
+// formFields customizes the questions PromptConfig asks for the settings of
+// c. The version of a CNI plugin is only asked for when it is chosen, and
+// the service CIDR is checked against the pod CIDR entered before it.
+func (c *Config) formFields() map[string]interactive.Field {
+	cniVersion := func(plugin string) interactive.Field {
+		return interactive.Field{
+			Validate: interactive.CheckString(checkCNIVersion),
+			Skip:     func() bool { return c.CNIPlugin != plugin },
+		}
+	}
+	cpus := interactive.Field{Validate: interactive.CheckInt(component.CheckCPUs)}
+	size := interactive.Field{Validate: interactive.CheckString(component.CheckSize)}
+
+	return map[string]interactive.Field{
+		"cluster_name":         {Validate: interactive.CheckString(component.CheckName)},
+		"kubernetes_version":   {Options: KubernetesVersions},
+		"name_prefix":          {Validate: interactive.CheckString(checkNamePrefix)},
+		"control_plane_count":  {Validate: interactive.CheckInt(checkControlPlaneCount)},
+		"worker_count":         {Validate: interactive.CheckInt(checkWorkerCount)},
+		"pod_cidr":             {Validate: interactive.CheckString(checkPodCIDR)},
+		"service_cidr":         {Validate: interactive.CheckString(c.checkServiceCIDR)},
+		"cni_plugin":           {Options: CNIPlugins},
+		"calico_version":       cniVersion(CNICalico),
+		"flannel_version":      cniVersion(CNIFlannel),
+		"cilium_version":       cniVersion(CNICilium),
+		"control_plane_cpus":   cpus,
+		"control_plane_memory": size,
+		"control_plane_disk":   size,
+		"worker_cpus":          cpus,
+		"worker_memory":        size,
+		"worker_disk":          size,
+		"dns_servers":          {Validate: interactive.CheckList(component.CheckDNSServers)},
+		"kubernetes_packages":  {Validate: interactive.CheckList(checkPackages)},
+	}
+}
+
+// checkPackages rejects an empty package list, which leaves the nodes
+// without kubeadm
+func checkPackages(packages []string) error {
+	if len(packages) == 0 {
+		return errors.New("must list at least one package")
+	}
+	return nil
+}
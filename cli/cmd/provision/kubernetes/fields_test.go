@@ -0,0 +1,102 @@
+package kubernetes
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/component"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/config"
+)
+
+func TestFormFields(t *testing.T) {
+	k8sConfig := withDefaults(&Config{ControlPlaneCount: 1})
+	fields := k8sConfig.formFields()
+
+	// Every setting that is asked for is validated
+	configType := reflect.TypeOf(*k8sConfig)
+	for i := 0; i < configType.NumField(); i++ {
+		field := configType.Field(i)
+		if _, ok := field.Tag.Lookup("prompt"); !ok {
+			continue
+		}
+		key := config.YAMLKey(field)
+		if fields[key].Validate == nil && fields[key].Options == nil {
+			t.Errorf("%s is asked for without validation", key)
+		}
+	}
+
+	// Only the version of the chosen plugin is asked for
+	k8sConfig.CNIPlugin = CNIFlannel
+	for key, skip := range map[string]bool{"calico_version": true, "flannel_version": false, "cilium_version": true} {
+		if got := fields[key].Skip(); got != skip {
+			t.Errorf("%s skipped = %t with flannel, want %t", key, got, skip)
+		}
+	}
+
+	tests := []struct {
+		key   string
+		value interface{}
+		valid bool
+	}{
+		{"name_prefix", "lab-", true},
+		{"name_prefix", "1lab", false},
+		{"control_plane_count", 2, false},
+		{"worker_count", -1, false},
+		{"pod_cidr", "10.244.0.0/16", true},
+		{"pod_cidr", "10.244.0.0/25", false},
+		{"service_cidr", "192.168.10.0/24", false},
+		{"flannel_version", "v0.26.2", true},
+		{"worker_memory", "lots", false},
+		{"dns_servers", []string{"1.1.1.1"}, true},
+		{"kubernetes_packages", []string{}, false},
+	}
+	for _, tt := range tests {
+		err := fields[tt.key].Validate(tt.value)
+		if (err == nil) != tt.valid {
+			t.Errorf("%s = %v: error = %v, want valid %t", tt.key, tt.value, err, tt.valid)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() *Config {
+		return withDefaults(&Config{
+			KubernetesVersion: "1.32", ControlPlaneCount: 1, WorkerCount: 3,
+			ControlPlaneCPUs: 4, ControlPlaneMemory: "8G", ControlPlaneDisk: "40G",
+			WorkerCPUs: 4, WorkerMemory: "8G", WorkerDisk: "40G",
+			DNSServers: []string{"8.8.8.8"}, KubernetesPackages: []string{"kubeadm"},
+		})
+	}
+	if err := valid().Validate(); err != nil {
+		t.Fatalf("Validate() error = %v, want nil", err)
+	}
+
+	k8sConfig := valid()
+	k8sConfig.KubernetesVersion = "1.12"
+	k8sConfig.PodCIDR = "10.96.0.0/12"
+	k8sConfig.WorkerMemory = "8X"
+	k8sConfig.DNSServers = []string{"8.8.8.8", "dns"}
+	err := k8sConfig.Validate()
+
+	var invalid *component.ValidationError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("Validate() error = %v, want a *component.ValidationError", err)
+	}
+	var keys []string
+	for _, field := range invalid.Fields {
+		keys = append(keys, field.Key)
+	}
+	want := []string{"kubernetes_version", "service_cidr", "worker_memory", "dns_servers"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("invalid settings = %v, want %v:\n%v", keys, want, err)
+	}
+
+	// Settings that only fail together are reported too
+	k8sConfig = valid()
+	k8sConfig.CNIPlugin, k8sConfig.FlannelVersion = CNIFlannel, "v0.26.2"
+	k8sConfig.PodCIDR = "10.244.0.0/23"
+	if err := k8sConfig.Validate(); err == nil || err.Error() != "pod_cidr must hold a /24 flannel subnet per node, 10.244.0.0/23 holds 2 for 4 nodes" {
+		t.Errorf("Validate() error = %v, want the pod CIDR to be too small", err)
+	}
+}
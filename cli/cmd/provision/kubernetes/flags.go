@@ -0,0 +1,84 @@
+package kubernetes
+
+import "github.com/spf13/pflag"
+
+// addFlags registers one flag per Config field, storing the values in flags
+func addFlags(fs *pflag.FlagSet, flags *Config) {
+	fs.StringVar(&flags.KubernetesVersion, "kubernetes-version", "", "Kubernetes minor version (e.g. 1.32)")
+	fs.StringVar(&flags.NamePrefix, "name-prefix", "", "Prefix for the VM names (e.g. lab-)")
+	fs.IntVar(&flags.ControlPlaneCount, "control-plane-count", 0, "Number of control plane VMs, 1 or at least 3 for a highly-available cluster")
+	fs.IntVar(&flags.WorkerCount, "worker-count", 0, "Number of worker VMs, 0 for a single-node cluster")
+	fs.StringVar(&flags.PodCIDR, "pod-cidr", "", "Pod network CIDR")
+	fs.StringVar(&flags.ServiceCIDR, "service-cidr", "", "Service network CIDR")
+	fs.StringVar(&flags.CNIPlugin, "cni-plugin", "", "CNI plugin: calico, flannel or cilium")
+	fs.StringVar(&flags.CalicoVersion, "calico-version", "", "Calico version, used with --cni-plugin calico")
+	fs.StringVar(&flags.FlannelVersion, "flannel-version", "", "Flannel version, used with --cni-plugin flannel")
+	fs.StringVar(&flags.CiliumVersion, "cilium-version", "", "Cilium version, used with --cni-plugin cilium")
+	fs.IntVar(&flags.ControlPlaneCPUs, "control-plane-cpus", 0, "CPUs for the control plane VM")
+	fs.StringVar(&flags.ControlPlaneMemory, "control-plane-memory", "", "Memory for the control plane VM (e.g. 8G)")
+	fs.StringVar(&flags.ControlPlaneDisk, "control-plane-disk", "", "Disk for the control plane VM (e.g. 40G)")
+	fs.IntVar(&flags.WorkerCPUs, "worker-cpus", 0, "CPUs for each worker VM")
+	fs.StringVar(&flags.WorkerMemory, "worker-memory", "", "Memory for each worker VM (e.g. 8G)")
+	fs.StringVar(&flags.WorkerDisk, "worker-disk", "", "Disk for each worker VM (e.g. 40G)")
+	fs.StringSliceVar(&flags.DNSServers, "dns-servers", nil, "DNS servers for the VMs")
+	fs.StringSliceVar(&flags.KubernetesPackages, "kubernetes-packages", nil, "Kubernetes packages to install")
+}
+
+// applyFlags copies the flags that were set explicitly from flags onto
+// k8sConfig
+func applyFlags(fs *pflag.FlagSet, flags, k8sConfig *Config) {
+	if fs.Changed("kubernetes-version") {
+		k8sConfig.KubernetesVersion = flags.KubernetesVersion
+	}
+	if fs.Changed("name-prefix") {
+		k8sConfig.NamePrefix = flags.NamePrefix
+	}
+	if fs.Changed("control-plane-count") {
+		k8sConfig.ControlPlaneCount = flags.ControlPlaneCount
+	}
+	if fs.Changed("worker-count") {
+		k8sConfig.WorkerCount = flags.WorkerCount
+	}
+	if fs.Changed("pod-cidr") {
+		k8sConfig.PodCIDR = flags.PodCIDR
+	}
+	if fs.Changed("service-cidr") {
+		k8sConfig.ServiceCIDR = flags.ServiceCIDR
+	}
+	if fs.Changed("cni-plugin") {
+		k8sConfig.CNIPlugin = flags.CNIPlugin
+	}
+	if fs.Changed("calico-version") {
+		k8sConfig.CalicoVersion = flags.CalicoVersion
+	}
+	if fs.Changed("flannel-version") {
+		k8sConfig.FlannelVersion = flags.FlannelVersion
+	}
+	if fs.Changed("cilium-version") {
+		k8sConfig.CiliumVersion = flags.CiliumVersion
+	}
+	if fs.Changed("control-plane-cpus") {
+		k8sConfig.ControlPlaneCPUs = flags.ControlPlaneCPUs
+	}
+	if fs.Changed("control-plane-memory") {
+		k8sConfig.ControlPlaneMemory = flags.ControlPlaneMemory
+	}
+	if fs.Changed("control-plane-disk") {
+		k8sConfig.ControlPlaneDisk = flags.ControlPlaneDisk
+	}
+	if fs.Changed("worker-cpus") {
+		k8sConfig.WorkerCPUs = flags.WorkerCPUs
+	}
+	if fs.Changed("worker-memory") {
+		k8sConfig.WorkerMemory = flags.WorkerMemory
+	}
+	if fs.Changed("worker-disk") {
+		k8sConfig.WorkerDisk = flags.WorkerDisk
+	}
+	if fs.Changed("dns-servers") {
+		k8sConfig.DNSServers = flags.DNSServers
+	}
+	if fs.Changed("kubernetes-packages") {
+		k8sConfig.KubernetesPackages = flags.KubernetesPackages
+	}
+}
@@ -0,0 +1,66 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestApplyFlags(t *testing.T) {
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	var flags Config
+	addFlags(fs, &flags)
+
+	if err := fs.Parse([]string{"--kubernetes-version", "1.31", "--worker-cpus", "2", "--worker-count", "0", "--dns-servers", "1.1.1.1,9.9.9.9"}); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	k8sConfig := &Config{
+		KubernetesVersion: "1.32",
+		PodCIDR:           "192.168.0.0/16",
+		WorkerCPUs:        4,
+		WorkerCount:       3,
+		DNSServers:        []string{"8.8.8.8"},
+	}
+	applyFlags(fs, &flags, k8sConfig)
+
+	// An explicit zero is a single-node cluster, not an unset flag
+	if k8sConfig.WorkerCount != 0 {
+		t.Errorf("WorkerCount = %d, want 0", k8sConfig.WorkerCount)
+	}
+
+	if k8sConfig.KubernetesVersion != "1.31" {
+		t.Errorf("KubernetesVersion = %q, want \"1.31\"", k8sConfig.KubernetesVersion)
+	}
+	if k8sConfig.WorkerCPUs != 2 {
+		t.Errorf("WorkerCPUs = %d, want 2", k8sConfig.WorkerCPUs)
+	}
+	if len(k8sConfig.DNSServers) != 2 || k8sConfig.DNSServers[0] != "1.1.1.1" {
+		t.Errorf("DNSServers = %v, want [1.1.1.1 9.9.9.9]", k8sConfig.DNSServers)
+	}
+
+	// Flags that were not set must not override the loaded values
+	if k8sConfig.PodCIDR != "192.168.0.0/16" {
+		t.Errorf("PodCIDR = %q, want it unchanged", k8sConfig.PodCIDR)
+	}
+}
+
+func TestApplyFlagsCNIVersions(t *testing.T) {
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	var flags Config
+	addFlags(fs, &flags)
+
+	if err := fs.Parse([]string{"--cni-plugin", "flannel", "--flannel-version", "v0.25.0"}); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	k8sConfig := &Config{CNIPlugin: "calico", CalicoVersion: "v3.29.1", CiliumVersion: "1.16.5"}
+	applyFlags(fs, &flags, k8sConfig)
+
+	if k8sConfig.CNIPlugin != "flannel" || k8sConfig.CNIVersion() != "v0.25.0" {
+		t.Errorf("CNI = %s %s, want flannel v0.25.0", k8sConfig.CNIPlugin, k8sConfig.CNIVersion())
+	}
+	if k8sConfig.CalicoVersion != "v3.29.1" || k8sConfig.CiliumVersion != "1.16.5" {
+		t.Errorf("versions of the other plugins changed: %+v", k8sConfig)
+	}
+}
@@ -0,0 +1,35 @@
+package kubernetes
+
+import (
+	"fmt"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/ansible"
+)
+
+// buildInventory describes the cluster VMs as the k8s_cluster group with
+// control_plane and workers children. The load balancer of a highly-available
+// cluster gets its own load_balancer group and becomes the
+// control_plane_endpoint.
+func buildInventory(vms clusterVMs, keyPath string) *ansible.Inventory {
+	inventory := ansible.NewInventory()
+	cluster := inventory.All().AddChild("k8s_cluster")
+
+	controlPlaneGroup := cluster.AddChild("control_plane")
+	for _, controlPlane := range vms.ControlPlanes {
+		controlPlaneGroup.AddHost(controlPlane.Name, map[string]interface{}{"ansible_host": controlPlane.IP()})
+	}
+
+	workersGroup := cluster.AddChild("workers")
+	for _, worker := range vms.Workers {
+		workersGroup.AddHost(worker.Name, map[string]interface{}{"ansible_host": worker.IP()})
+	}
+
+	if vms.LoadBalancer != nil {
+		lb := vms.LoadBalancer
+		inventory.All().AddChild("load_balancer").AddHost(lb.Name, map[string]interface{}{"ansible_host": lb.IP()})
+		cluster.SetVar("control_plane_endpoint", fmt.Sprintf("%s:%d", lb.IP(), apiServerPort))
+	}
+
+	inventory.All().Vars = ansible.SSHVars(keyPath)
+	return inventory
+}
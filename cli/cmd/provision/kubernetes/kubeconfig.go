@@ -0,0 +1,227 @@
+package kubernetes
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// kubeconfig is the part of a kubeconfig file the CLI edits. Unknown keys
+// are kept so merging into an existing file does not lose settings.
+type kubeconfig struct {
+	APIVersion     string                 `yaml:"apiVersion"`
+	Kind           string                 `yaml:"kind"`
+	Clusters       []namedEntry           `yaml:"clusters"`
+	Contexts       []namedEntry           `yaml:"contexts"`
+	Users          []namedEntry           `yaml:"users"`
+	CurrentContext string                 `yaml:"current-context"`
+	Extra          map[string]interface{} `yaml:",inline"`
+}
+
+// namedEntry is a cluster, context or user with its settings
+type namedEntry struct {
+	Name   string                 `yaml:"name"`
+	Fields map[string]interface{} `yaml:",inline"`
+}
+
+// ContextName returns the name used for the cluster, user and context of
+// the cluster in the host kubeconfig: provision-<cluster name> for a named
+// cluster, provision-<name_prefix>kubernetes otherwise
+func (c *Config) ContextName() string {
+	if c.Name != "" {
+		return "provision-" + c.Name
+	}
+	return "provision-" + c.NamePrefix + ClusterName
+}
+
+// DefaultKubeconfigPath returns the first file in $KUBECONFIG, or
+// ~/.kube/config like kubectl
+func DefaultKubeconfigPath() (string, error) {
+	if paths := filepath.SplitList(os.Getenv("KUBECONFIG")); len(paths) > 0 && paths[0] != "" {
+		return paths[0], nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".kube", "config"), nil
+}
+
+// MergeKubeconfig adds the cluster's admin credentials to the kubeconfig at
+// path, replacing entries of the same name, and makes it the current context
+func MergeKubeconfig(k8sConfig *Config, path string) error {
+	cluster, err := fetchKubeconfig(k8sConfig)
+	if err != nil {
+		return err
+	}
+
+	existing, err := readKubeconfig(path)
+	if err != nil {
+		return err
+	}
+
+	existing.remove(k8sConfig.ContextName())
+	existing.Clusters = append(existing.Clusters, cluster.Clusters...)
+	existing.Contexts = append(existing.Contexts, cluster.Contexts...)
+	existing.Users = append(existing.Users, cluster.Users...)
+	existing.CurrentContext = k8sConfig.ContextName()
+
+	return writeKubeconfig(existing, path)
+}
+
+// WriteKubeconfig writes a kubeconfig with only the cluster's admin
+// credentials to path, replacing the file
+func WriteKubeconfig(k8sConfig *Config, path string) error {
+	cluster, err := fetchKubeconfig(k8sConfig)
+	if err != nil {
+		return err
+	}
+	return writeKubeconfig(cluster, path)
+}
+
+// RemoveKubeconfig deletes the cluster's entries from the kubeconfig at path.
+// A missing file is not an error.
+func RemoveKubeconfig(k8sConfig *Config, path string) error {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	existing, err := readKubeconfig(path)
+	if err != nil {
+		return err
+	}
+
+	if !existing.remove(k8sConfig.ContextName()) {
+		return nil
+	}
+	return writeKubeconfig(existing, path)
+}
+
+// fetchKubeconfig reads admin.conf from the first control plane and
+// rewrites it for use from the host: the server points at the control plane
+// (or the load balancer of a highly-available cluster) and the cluster,
+// user and context are renamed to ContextName
+func fetchKubeconfig(k8sConfig *Config) (*kubeconfig, error) {
+	provider := newProvider()
+
+	controlPlane := k8sConfig.ControlPlaneNames()[0]
+	out, err := provider.Exec(controlPlane, "sudo", "cat", "/etc/kubernetes/admin.conf")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read admin.conf from %s: %w", controlPlane, err)
+	}
+
+	var admin kubeconfig
+	if err := yaml.Unmarshal(out, &admin); err != nil {
+		return nil, fmt.Errorf("failed to parse admin.conf: %w", err)
+	}
+	if len(admin.Clusters) != 1 || len(admin.Users) != 1 {
+		return nil, fmt.Errorf("admin.conf has %d clusters and %d users, want one of each", len(admin.Clusters), len(admin.Users))
+	}
+
+	endpoint := controlPlane
+	if k8sConfig.HighlyAvailable() {
+		endpoint = k8sConfig.LoadBalancerName()
+	}
+	instance, err := provider.Info(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the address of %s: %w", endpoint, err)
+	}
+	if instance.IP() == "" {
+		return nil, fmt.Errorf("VM %s has no IP address", endpoint)
+	}
+
+	name := k8sConfig.ContextName()
+
+	cluster := admin.Clusters[0]
+	cluster.Name = name
+	settings, ok := cluster.Fields["cluster"].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("admin.conf cluster has no settings")
+	}
+	settings["server"] = fmt.Sprintf("https://%s:%d", instance.IP(), apiServerPort)
+
+	user := admin.Users[0]
+	user.Name = name
+
+	return &kubeconfig{
+		APIVersion: "v1",
+		Kind:       "Config",
+		Clusters:   []namedEntry{cluster},
+		Users:      []namedEntry{user},
+		Contexts: []namedEntry{{
+			Name: name,
+			Fields: map[string]interface{}{
+				"context": map[string]interface{}{"cluster": name, "user": name},
+			},
+		}},
+		CurrentContext: name,
+	}, nil
+}
+
+// remove deletes the cluster, context and user called name and reports
+// whether anything was removed
+func (k *kubeconfig) remove(name string) bool {
+	removed := false
+	without := func(entries []namedEntry) []namedEntry {
+		kept := entries[:0]
+		for _, entry := range entries {
+			if entry.Name == name {
+				removed = true
+				continue
+			}
+			kept = append(kept, entry)
+		}
+		return kept
+	}
+
+	k.Clusters = without(k.Clusters)
+	k.Contexts = without(k.Contexts)
+	k.Users = without(k.Users)
+	if k.CurrentContext == name {
+		k.CurrentContext = ""
+	}
+	return removed
+}
+
+// readKubeconfig parses the kubeconfig at path, or returns an empty one if
+// the file does not exist
+func readKubeconfig(path string) (*kubeconfig, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &kubeconfig{APIVersion: "v1", Kind: "Config"}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read kubeconfig: %w", err)
+	}
+
+	config := &kubeconfig{}
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("failed to parse kubeconfig %s: %w", path, err)
+	}
+	if config.APIVersion == "" {
+		config.APIVersion, config.Kind = "v1", "Config"
+	}
+	return config, nil
+}
+
+// writeKubeconfig writes config to path, readable only by the user since it
+// holds credentials
+func writeKubeconfig(config *kubeconfig, path string) error {
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal kubeconfig: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create kubeconfig directory: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write kubeconfig: %w", err)
+	}
+	return nil
+}
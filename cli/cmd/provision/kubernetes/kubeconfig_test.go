@@ -0,0 +1,189 @@
+package kubernetes
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const adminConf = `apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    certificate-authority-data: Q0EK
+    server: https://10.0.0.10:6443
+  name: kubernetes
+contexts:
+- context:
+    cluster: kubernetes
+    user: kubernetes-admin
+  name: kubernetes-admin@kubernetes
+current-context: kubernetes-admin@kubernetes
+users:
+- name: kubernetes-admin
+  user:
+    client-certificate-data: Q0VSVAo=
+    client-key-data: S0VZCg==
+`
+
+const existingKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    server: https://prod.example.com
+  name: prod
+contexts:
+- context:
+    cluster: prod
+    user: prod-user
+  name: prod
+current-context: prod
+preferences: {}
+users:
+- name: prod-user
+  user:
+    exec:
+      command: prod-login
+`
+
+// serveAdminConf makes the fake control plane answer `sudo cat admin.conf`
+func serveAdminConf(t *testing.T) {
+	t.Helper()
+
+	provider := useFakeProvider(t)
+	provider.AddInstance("controlplane01")
+	provider.AddInstance("controlplane02")
+	provider.AddInstance("controlplane03")
+	lbIP := provider.AddInstance("loadbalancer")
+	provider.AddInstance("controlplane")
+	if lbIP != "10.0.0.13" {
+		t.Fatalf("load balancer IP = %s, want 10.0.0.13", lbIP)
+	}
+
+	provider.ExecFunc = func(name string, command []string) ([]byte, error) {
+		if strings.Join(command, " ") != "sudo cat /etc/kubernetes/admin.conf" {
+			t.Errorf("unexpected command on %s: %v", name, command)
+		}
+		return []byte(adminConf), nil
+	}
+}
+
+func TestMergeKubeconfig(t *testing.T) {
+	serveAdminConf(t)
+
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte(existingKubeconfig), 0o600); err != nil {
+		t.Fatalf("Failed to write kubeconfig: %v", err)
+	}
+
+	k8sConfig := &Config{ControlPlaneCount: 1}
+	if err := MergeKubeconfig(k8sConfig, path); err != nil {
+		t.Fatalf("MergeKubeconfig() error = %v", err)
+	}
+	// Merging twice replaces the entries instead of duplicating them
+	if err := MergeKubeconfig(k8sConfig, path); err != nil {
+		t.Fatalf("MergeKubeconfig() error = %v", err)
+	}
+
+	merged := readTestKubeconfig(t, path)
+	if merged.CurrentContext != "provision-kubernetes" {
+		t.Errorf("current-context = %q, want provision-kubernetes", merged.CurrentContext)
+	}
+	if len(merged.Clusters) != 2 || len(merged.Contexts) != 2 || len(merged.Users) != 2 {
+		t.Fatalf("merged kubeconfig has %d clusters, %d contexts, %d users, want 2 of each",
+			len(merged.Clusters), len(merged.Contexts), len(merged.Users))
+	}
+
+	cluster := merged.Clusters[1]
+	settings := cluster.Fields["cluster"].(map[string]interface{})
+	if cluster.Name != "provision-kubernetes" || settings["server"] != "https://10.0.0.14:6443" {
+		t.Errorf("cluster = %+v, want provision-kubernetes at the control plane IP", cluster)
+	}
+	if settings["certificate-authority-data"] != "Q0EK" {
+		t.Errorf("certificate-authority-data was not kept: %v", settings)
+	}
+	context := merged.Contexts[1].Fields["context"].(map[string]interface{})
+	if context["cluster"] != "provision-kubernetes" || context["user"] != "provision-kubernetes" {
+		t.Errorf("context = %v, want cluster and user provision-kubernetes", context)
+	}
+
+	// Entries and keys of other clusters are preserved
+	if merged.Users[0].Name != "prod-user" || merged.Users[0].Fields["user"] == nil {
+		t.Errorf("existing user was not preserved: %+v", merged.Users[0])
+	}
+	if _, ok := merged.Extra["preferences"]; !ok {
+		t.Errorf("preferences key was dropped")
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("kubeconfig mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	if err := RemoveKubeconfig(k8sConfig, path); err != nil {
+		t.Fatalf("RemoveKubeconfig() error = %v", err)
+	}
+	removed := readTestKubeconfig(t, path)
+	if len(removed.Clusters) != 1 || removed.Clusters[0].Name != "prod" || removed.CurrentContext != "" {
+		t.Errorf("after removal clusters = %v, current-context = %q", removed.Clusters, removed.CurrentContext)
+	}
+}
+
+func TestWriteKubeconfigHighlyAvailable(t *testing.T) {
+	serveAdminConf(t)
+
+	path := filepath.Join(t.TempDir(), "nested", "lab.yaml")
+	if err := WriteKubeconfig(&Config{ControlPlaneCount: 3}, path); err != nil {
+		t.Fatalf("WriteKubeconfig() error = %v", err)
+	}
+
+	written := readTestKubeconfig(t, path)
+	if len(written.Clusters) != 1 || written.CurrentContext != "provision-kubernetes" {
+		t.Fatalf("written kubeconfig = %+v", written)
+	}
+	server := written.Clusters[0].Fields["cluster"].(map[string]interface{})["server"]
+	if server != "https://10.0.0.13:6443" {
+		t.Errorf("server = %v, want the load balancer https://10.0.0.13:6443", server)
+	}
+}
+
+func TestRemoveKubeconfigMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	if err := RemoveKubeconfig(&Config{}, path); err != nil {
+		t.Errorf("RemoveKubeconfig() error = %v, want nil for a missing file", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("RemoveKubeconfig() created %s", path)
+	}
+}
+
+func TestDefaultKubeconfigPath(t *testing.T) {
+	t.Setenv("KUBECONFIG", "/tmp/first:/tmp/second")
+	if path, _ := DefaultKubeconfigPath(); path != "/tmp/first" {
+		t.Errorf("DefaultKubeconfigPath() = %q, want the first $KUBECONFIG entry", path)
+	}
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("KUBECONFIG", "")
+	if path, _ := DefaultKubeconfigPath(); path != filepath.Join(home, ".kube", "config") {
+		t.Errorf("DefaultKubeconfigPath() = %q, want ~/.kube/config", path)
+	}
+}
+
+func readTestKubeconfig(t *testing.T, path string) *kubeconfig {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read kubeconfig: %v", err)
+	}
+	config := &kubeconfig{}
+	if err := yaml.Unmarshal(data, config); err != nil {
+		t.Fatalf("kubeconfig is not valid YAML: %v\n%s", err, data)
+	}
+	return config
+}
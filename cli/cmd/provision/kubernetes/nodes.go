@@ -0,0 +1,223 @@
+package kubernetes
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/state"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/vm"
+)
+
+// Resources of the HAProxy VM in front of a highly-available control plane
+const (
+	loadBalancerCPUs   = 1
+	loadBalancerMemory = "1G"
+	loadBalancerDisk   = "10G"
+)
+
+// apiServerPort is the port of the Kubernetes API on the control planes and
+// the load balancer
+const apiServerPort = 6443
+
+// namePattern matches the characters Multipass allows in instance names
+var namePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9-]*$`)
+
+// clusterVMs groups the instances of a cluster by role
+type clusterVMs struct {
+	ControlPlanes []vm.Instance
+	LoadBalancer  *vm.Instance
+	Workers       []vm.Instance
+}
+
+// HighlyAvailable reports whether the cluster has several control planes
+// behind a load balancer
+func (c *Config) HighlyAvailable() bool {
+	return c.ControlPlaneCount > 1
+}
+
+// ControlPlaneNames returns the VM names of the control planes, the one that
+// initializes the cluster first. A single control plane keeps the name
+// controlplane, several are numbered controlplane01 up to ControlPlaneCount.
+func (c *Config) ControlPlaneNames() []string {
+	if !c.HighlyAvailable() {
+		return []string{c.NamePrefix + "controlplane"}
+	}
+	return numberedNames(c.NamePrefix+"controlplane", c.ControlPlaneCount)
+}
+
+// LoadBalancerName returns the VM name of the API server load balancer,
+// which only exists for a highly-available cluster
+func (c *Config) LoadBalancerName() string {
+	return c.NamePrefix + "loadbalancer"
+}
+
+// WorkerNames returns the VM names of the workers, node01 up to WorkerCount
+func (c *Config) WorkerNames() []string {
+	return numberedNames(c.NamePrefix+"node", c.WorkerCount)
+}
+
+// numberedNames returns base01 up to base<count>
+func numberedNames(base string, count int) []string {
+	names := make([]string, 0, count)
+	for i := 1; i <= count; i++ {
+		names = append(names, fmt.Sprintf("%s%02d", base, i))
+	}
+	return names
+}
+
+// isClusterVM reports whether name follows the cluster's naming scheme,
+// including nodes beyond the current counts and the load balancer
+func (c *Config) isClusterVM(name string) bool {
+	if name == c.NamePrefix+"controlplane" || name == c.LoadBalancerName() {
+		return true
+	}
+	return isNumbered(name, c.NamePrefix+"controlplane") || isNumbered(name, c.NamePrefix+"node")
+}
+
+// isNumbered reports whether name is base followed by at least two digits
+func isNumbered(name, base string) bool {
+	suffix, ok := strings.CutPrefix(name, base)
+	if !ok || len(suffix) < 2 {
+		return false
+	}
+	_, err := strconv.Atoi(suffix)
+	return err == nil
+}
+
+// checkControlPlaneCount rejects control plane counts without etcd quorum
+func checkControlPlaneCount(count int) error {
+	if count != 1 && count < 3 {
+		return fmt.Errorf("must be 1 or at least 3 for etcd quorum, got %d", count)
+	}
+	return nil
+}
+
+// checkWorkerCount rejects negative worker counts
+func checkWorkerCount(count int) error {
+	if count < 0 {
+		return fmt.Errorf("must not be negative, got %d", count)
+	}
+	return nil
+}
+
+// checkNamePrefix rejects prefixes that give VM names Multipass refuses
+func checkNamePrefix(prefix string) error {
+	if !namePattern.MatchString(prefix + "controlplane") {
+		return fmt.Errorf("must start with a letter and contain only letters, digits and hyphens, got %q", prefix)
+	}
+	return nil
+}
+
+// launchOptions describes the control planes, the load balancer of a
+// highly-available cluster and the workers, in that order
+func launchOptions(k8sConfig *Config, cloudInitPath string) []vm.LaunchOptions {
+	var specs []vm.LaunchOptions
+	for _, name := range k8sConfig.ControlPlaneNames() {
+		specs = append(specs, vm.LaunchOptions{
+			Name:      name,
+			CPUs:      k8sConfig.ControlPlaneCPUs,
+			Memory:    k8sConfig.ControlPlaneMemory,
+			Disk:      k8sConfig.ControlPlaneDisk,
+			CloudInit: cloudInitPath,
+		})
+	}
+
+	if k8sConfig.HighlyAvailable() {
+		specs = append(specs, vm.LaunchOptions{
+			Name:      k8sConfig.LoadBalancerName(),
+			CPUs:      loadBalancerCPUs,
+			Memory:    loadBalancerMemory,
+			Disk:      loadBalancerDisk,
+			CloudInit: cloudInitPath,
+		})
+	}
+
+	for _, name := range k8sConfig.WorkerNames() {
+		specs = append(specs, vm.LaunchOptions{
+			Name:      name,
+			CPUs:      k8sConfig.WorkerCPUs,
+			Memory:    k8sConfig.WorkerMemory,
+			Disk:      k8sConfig.WorkerDisk,
+			CloudInit: cloudInitPath,
+		})
+	}
+	return specs
+}
+
+// vmNames returns the names of the VMs described by launchOptions
+func vmNames(k8sConfig *Config) []string {
+	names := append([]string{}, k8sConfig.ControlPlaneNames()...)
+	if k8sConfig.HighlyAvailable() {
+		names = append(names, k8sConfig.LoadBalancerName())
+	}
+	return append(names, k8sConfig.WorkerNames()...)
+}
+
+// splitInstances groups instances returned in launchOptions order by role
+func splitInstances(k8sConfig *Config, instances []vm.Instance) clusterVMs {
+	count := len(k8sConfig.ControlPlaneNames())
+	vms := clusterVMs{ControlPlanes: instances[:count]}
+	rest := instances[count:]
+
+	if k8sConfig.HighlyAvailable() {
+		vms.LoadBalancer = &rest[0]
+		rest = rest[1:]
+	}
+	vms.Workers = rest
+	return vms
+}
+
+// nodes lists the cluster VMs with their roles for the cluster state
+func (vms clusterVMs) nodes() []state.Node {
+	var nodes []state.Node
+	for _, instance := range vms.ControlPlanes {
+		nodes = append(nodes, state.Node{Name: instance.Name, Role: "control-plane", IP: instance.IP()})
+	}
+	if vms.LoadBalancer != nil {
+		nodes = append(nodes, state.Node{Name: vms.LoadBalancer.Name, Role: "load-balancer", IP: vms.LoadBalancer.IP()})
+	}
+	for _, instance := range vms.Workers {
+		nodes = append(nodes, state.Node{Name: instance.Name, Role: "worker", IP: instance.IP()})
+	}
+	return nodes
+}
+
+// clusterInstances lists the existing VMs of the cluster, see clusterVMFilter
+func clusterInstances(provider vm.Provider, k8sConfig *Config) ([]vm.Instance, error) {
+	isClusterVM, err := clusterVMFilter(k8sConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	instances, err := provider.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list VMs: %w", err)
+	}
+
+	var matching []vm.Instance
+	for _, instance := range instances {
+		if isClusterVM(instance.Name) {
+			matching = append(matching, instance)
+		}
+	}
+	return matching, nil
+}
+
+// clusterVMFilter returns a filter matching the VMs recorded in the cluster
+// state and the VMs that follow the cluster's naming scheme
+func clusterVMFilter(k8sConfig *Config) (func(name string) bool, error) {
+	recorded, err := state.Load(k8sConfig.clusterName())
+	if errors.Is(err, state.ErrNotFound) {
+		return k8sConfig.isClusterVM, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return func(name string) bool {
+		return recorded.HasNode(name) || k8sConfig.isClusterVM(name)
+	}, nil
+}
@@ -0,0 +1,132 @@
+package kubernetes
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/ansible"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/component"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/config"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/plan"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/state"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/vm"
+)
+
+// PlanProvision describes what Provision would do for k8sConfig without
+// changing anything. Existing VMs are listed to tell which would be reused.
+func PlanProvision(k8sConfig *Config) (*plan.Plan, error) {
+	if err := k8sConfig.Validate(); err != nil {
+		return nil, err
+	}
+	if err := component.CheckNameFree(k8sConfig.Name); err != nil {
+		return nil, err
+	}
+	if err := state.CheckNodesFree(k8sConfig.clusterName(), vmNames(k8sConfig)); err != nil {
+		return nil, err
+	}
+
+	p := &plan.Plan{Action: plan.Provision, Cluster: k8sConfig.clusterName(), Component: ComponentName}
+
+	stateDir, err := config.GetStateDir(k8sConfig.clusterName())
+	if err != nil {
+		return nil, err
+	}
+	keyPath, err := vm.DefaultSSHKeyPath()
+	if err != nil {
+		return nil, err
+	}
+
+	templatePath, err := config.GetMultipassPath("cloud-init/common.yaml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate cloud-init template: %w", err)
+	}
+	if p.CloudInit, err = vm.CloudInit(templatePath, vm.PlannedPublicKey(keyPath)); err != nil {
+		return nil, err
+	}
+
+	specs := launchOptions(k8sConfig, "")
+	instances, launch, err := vm.PlanInstances(newProvider(), specs)
+	if err != nil {
+		p.Warnings = append(p.Warnings, fmt.Sprintf("%v, assuming no VMs exist", err))
+	}
+	if host, err := readHost(); err != nil {
+		p.Warnings = append(p.Warnings, fmt.Sprintf("%v, the capacity check is skipped", err))
+	} else if err := vm.CheckCapacity(host, specs, launch); err != nil {
+		p.Warnings = append(p.Warnings, err.Error())
+	}
+	vms := splitInstances(k8sConfig, instances)
+	p.VMs = plan.LaunchVMs(specs, vms.nodes(), launch)
+
+	inventory, err := buildInventory(vms, keyPath).Marshal()
+	if err != nil {
+		return nil, err
+	}
+	p.Inventory = string(inventory)
+
+	playbookPath, err := config.GetAnsiblePath("playbooks/kubernetes.yml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate playbook: %w", err)
+	}
+	inventoryPath := filepath.Join(stateDir, state.InventoryFile)
+	configPath := filepath.Join(stateDir, state.ConfigFile)
+	p.Commands = append(p.Commands, append([]string{"ansible-playbook"},
+		ansible.PlaybookArgs(playbookPath, inventoryPath, ansible.ExtraVarsFile(configPath))...))
+
+	kubeconfigPath, err := DefaultKubeconfigPath()
+	if err != nil {
+		return nil, err
+	}
+	p.Steps = append(p.Steps,
+		fmt.Sprintf("Record the cluster, its config and inventory in %s", stateDir),
+		fmt.Sprintf("Merge context %s into %s and make it the current context", k8sConfig.ContextName(), kubeconfigPath))
+	return p, nil
+}
+
+// PlanCleanup describes what Cleanup would do for k8sConfig without
+// changing anything
+func PlanCleanup(k8sConfig *Config) (*plan.Plan, error) {
+	p := &plan.Plan{Action: plan.Cleanup, Cluster: k8sConfig.clusterName(), Component: ComponentName}
+
+	recorded, err := state.LoadOrNew(k8sConfig.clusterName(), ComponentName)
+	if err != nil {
+		return nil, err
+	}
+
+	instances, err := clusterInstances(newProvider(), k8sConfig)
+	if err != nil {
+		p.Warnings = append(p.Warnings, fmt.Sprintf("%v, the VMs to delete are unknown", err))
+	}
+	for _, instance := range instances {
+		planned := plan.VM{Name: instance.Name, Action: plan.Delete, CPUs: instance.CPUs, IP: instance.IP()}
+		for _, node := range recorded.Nodes {
+			if node.Name == instance.Name {
+				planned.Role = node.Role
+			}
+		}
+		p.VMs = append(p.VMs, planned)
+	}
+
+	kubeconfigPath, err := DefaultKubeconfigPath()
+	if err != nil {
+		return nil, err
+	}
+	p.Steps = append(p.Steps,
+		fmt.Sprintf("Remove context %s from %s", k8sConfig.ContextName(), kubeconfigPath),
+		fmt.Sprintf("Remove the state directory %s", recorded.Dir()))
+	return p, nil
+}
+
+// Preflight checks that the host has the CPUs, memory and disk for the VMs
+// Provision would launch. It returns a *vm.CapacityError when they do not
+// fit, and an error wrapping vm.ErrCapacityUnknown when that cannot be told.
+func Preflight(k8sConfig *Config) error {
+	specs := launchOptions(k8sConfig, "")
+	// A failure to list the VMs is reported by Provision itself
+	_, launch, _ := vm.PlanInstances(newProvider(), specs)
+
+	host, err := readHost()
+	if err != nil {
+		return err
+	}
+	return vm.CheckCapacity(host, specs, launch)
+}
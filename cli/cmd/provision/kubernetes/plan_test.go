@@ -0,0 +1,147 @@
+package kubernetes
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/plan"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/state"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/vm"
+)
+
+func TestPlanProvision(t *testing.T) {
+	_, provider, playbookArgs := setupProvision(t)
+	controlPlaneIP := provider.AddInstance("lab-controlplane")
+
+	k8sConfig := withDefaults(&Config{ControlPlaneCount: 1, WorkerCount: 2, WorkerCPUs: 2, WorkerMemory: "4G", WorkerDisk: "20G"})
+	k8sConfig.SetName("lab")
+	p, err := PlanProvision(k8sConfig)
+	if err != nil {
+		t.Fatalf("PlanProvision() error = %v", err)
+	}
+
+	// Nothing is launched, run or recorded
+	if len(provider.CallsTo("launch")) != 0 || len(*playbookArgs) != 0 {
+		t.Errorf("PlanProvision() launched VMs or ran the playbook")
+	}
+	if _, err := state.Load("lab"); !errors.Is(err, state.ErrNotFound) {
+		t.Errorf("state.Load() error = %v, want the cluster not recorded", err)
+	}
+
+	want := []plan.VM{
+		{Name: "lab-controlplane", Role: "control-plane", Action: plan.Reuse, CPUs: 4, Memory: "8G", Disk: "40G", IP: controlPlaneIP},
+		{Name: "lab-node01", Role: "worker", Action: plan.Launch, CPUs: 2, Memory: "4G", Disk: "20G"},
+		{Name: "lab-node02", Role: "worker", Action: plan.Launch, CPUs: 2, Memory: "4G", Disk: "20G"},
+	}
+	if len(p.VMs) != len(want) {
+		t.Fatalf("VMs = %+v, want %+v", p.VMs, want)
+	}
+	for i := range want {
+		if p.VMs[i] != want[i] {
+			t.Errorf("VMs[%d] = %+v, want %+v", i, p.VMs[i], want[i])
+		}
+	}
+
+	if !strings.Contains(p.CloudInit, "ssh-rsa AAAA test") {
+		t.Errorf("cloud-init does not contain the public key:\n%s", p.CloudInit)
+	}
+	if !strings.Contains(p.Inventory, "ansible_host: "+controlPlaneIP) || !strings.Contains(p.Inventory, "<lab-node01 IP>") {
+		t.Errorf("inventory does not contain the known and planned addresses:\n%s", p.Inventory)
+	}
+	if len(p.Commands) != 1 || p.Commands[0][0] != "ansible-playbook" || !strings.HasSuffix(p.Commands[0][len(p.Commands[0])-1], "kubernetes.yml") {
+		t.Errorf("commands = %v, want the kubernetes playbook run", p.Commands)
+	}
+	if !strings.Contains(strings.Join(p.Steps, "\n"), "provision-lab") {
+		t.Errorf("steps = %v, want the kubeconfig context", p.Steps)
+	}
+}
+
+func TestPlanProvisionWithoutVMList(t *testing.T) {
+	_, provider, _ := setupProvision(t)
+	provider.FailOn("list", "", errors.New("multipass is not running"))
+
+	p, err := PlanProvision(withDefaults(&Config{ControlPlaneCount: 1, WorkerCount: 1}))
+	if err != nil {
+		t.Fatalf("PlanProvision() error = %v", err)
+	}
+	if len(p.Warnings) != 1 || !strings.Contains(p.Warnings[0], "multipass is not running") {
+		t.Errorf("warnings = %v, want the list failure", p.Warnings)
+	}
+	for _, planned := range p.VMs {
+		if planned.Action != plan.Launch {
+			t.Errorf("VM %s action = %s, want launch", planned.Name, planned.Action)
+		}
+	}
+}
+
+func TestPlanCleanup(t *testing.T) {
+	_, provider, _ := setupProvision(t)
+	for _, name := range []string{"controlplane", "node01", "lab-controlplane"} {
+		provider.AddInstance(name)
+	}
+
+	p, err := PlanCleanup(&Config{ControlPlaneCount: 1})
+	if err != nil {
+		t.Fatalf("PlanCleanup() error = %v", err)
+	}
+	if len(provider.CallsTo("delete")) != 0 || len(provider.Names()) != 3 {
+		t.Errorf("PlanCleanup() deleted VMs")
+	}
+
+	var names []string
+	for _, planned := range p.VMs {
+		if planned.Action != plan.Delete {
+			t.Errorf("VM %s action = %s, want delete", planned.Name, planned.Action)
+		}
+		names = append(names, planned.Name)
+	}
+	if strings.Join(names, ",") != "controlplane,node01" {
+		t.Errorf("VMs to delete = %v, want controlplane,node01", names)
+	}
+	if !strings.Contains(strings.Join(p.Steps, "\n"), "provision-kubernetes") {
+		t.Errorf("steps = %v, want the kubeconfig context removal", p.Steps)
+	}
+}
+
+func TestPreflight(t *testing.T) {
+	_, provider, _ := setupProvision(t)
+	readHost = func() (vm.Host, error) {
+		return vm.Host{CPUs: 8, Memory: 16 << 30, FreeDisk: 100 << 30, StoragePath: "/var/snap/multipass"}, nil
+	}
+	k8sConfig := withDefaults(&Config{
+		ControlPlaneCount: 1, ControlPlaneCPUs: 4, ControlPlaneMemory: "8G", ControlPlaneDisk: "40G",
+		WorkerCount: 1, WorkerCPUs: 4, WorkerMemory: "8G", WorkerDisk: "40G",
+	})
+	if err := Preflight(k8sConfig); err != nil {
+		t.Errorf("Preflight() error = %v, want two nodes to fit", err)
+	}
+
+	k8sConfig.WorkerCount = 3
+	var capacityErr *vm.CapacityError
+	if err := Preflight(k8sConfig); !errors.As(err, &capacityErr) {
+		t.Errorf("Preflight() error = %v, want a *vm.CapacityError", err)
+	}
+
+	// Existing VMs are not launched again, so they do not count
+	provider.AddInstance("node01")
+	provider.AddInstance("node02")
+	if err := Preflight(k8sConfig); err != nil {
+		t.Errorf("Preflight() error = %v, want the two missing nodes to fit", err)
+	}
+
+	// The dry-run plan warns instead
+	k8sConfig.WorkerCount = 4
+	p, err := PlanProvision(k8sConfig)
+	if err != nil {
+		t.Fatalf("PlanProvision() error = %v", err)
+	}
+	if len(p.Warnings) != 1 || !strings.Contains(p.Warnings[0], "do not fit") {
+		t.Errorf("warnings = %v, want the capacity shortfall", p.Warnings)
+	}
+
+	readHost = func() (vm.Host, error) { return vm.Host{}, vm.ErrCapacityUnknown }
+	if err := Preflight(k8sConfig); !errors.Is(err, vm.ErrCapacityUnknown) {
+		t.Errorf("Preflight() error = %v, want vm.ErrCapacityUnknown", err)
+	}
+}
@@ -1,173 +1,301 @@
 package kubernetes
 
 import (
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
+	"strings"
 
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/ansible"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/component"
 	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/config"
 	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/interactive"
-	"gopkg.in/yaml.v3"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/state"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/vm"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/vm/multipass"
 )
 
 // Config holds Kubernetes cluster configuration
 type Config struct {
-	KubernetesVersion  string   `yaml:"kubernetes_version"`
-	PodCIDR            string   `yaml:"pod_cidr"`
-	ServiceCIDR        string   `yaml:"service_cidr"`
-	CNIPlugin          string   `yaml:"cni_plugin"`
-	CalicoVersion      string   `yaml:"calico_version"`
-	ControlPlaneCPUs   int      `yaml:"control_plane_cpus"`
-	ControlPlaneMemory string   `yaml:"control_plane_memory"`
-	ControlPlaneDisk   string   `yaml:"control_plane_disk"`
-	WorkerCPUs         int      `yaml:"worker_cpus"`
-	WorkerMemory       string   `yaml:"worker_memory"`
-	WorkerDisk         string   `yaml:"worker_disk"`
-	DNSServers         []string `yaml:"dns_servers"`
-	KubernetesPackages []string `yaml:"kubernetes_packages"`
+	Name               string   `yaml:"cluster_name,omitempty"`
+	KubernetesVersion  string   `yaml:"kubernetes_version" prompt:"Kubernetes Version"`
+	NamePrefix         string   `yaml:"name_prefix" prompt:"VM Name Prefix"`
+	ControlPlaneCount  int      `yaml:"control_plane_count" prompt:"Control Plane Count (1, or 3 and more for HA)"`
+	WorkerCount        int      `yaml:"worker_count" prompt:"Worker Count (0 for a single node)"`
+	PodCIDR            string   `yaml:"pod_cidr" prompt:"Pod CIDR"`
+	ServiceCIDR        string   `yaml:"service_cidr" prompt:"Service CIDR"`
+	CNIPlugin          string   `yaml:"cni_plugin" prompt:"CNI Plugin"`
+	CalicoVersion      string   `yaml:"calico_version" prompt:"Calico Version"`
+	FlannelVersion     string   `yaml:"flannel_version" prompt:"Flannel Version"`
+	CiliumVersion      string   `yaml:"cilium_version" prompt:"Cilium Version"`
+	ControlPlaneCPUs   int      `yaml:"control_plane_cpus" prompt:"Control Plane CPUs"`
+	ControlPlaneMemory string   `yaml:"control_plane_memory" prompt:"Control Plane Memory (e.g., 8G)"`
+	ControlPlaneDisk   string   `yaml:"control_plane_disk" prompt:"Control Plane Disk (e.g., 40G)"`
+	WorkerCPUs         int      `yaml:"worker_cpus" prompt:"Worker CPUs"`
+	WorkerMemory       string   `yaml:"worker_memory" prompt:"Worker Memory (e.g., 8G)"`
+	WorkerDisk         string   `yaml:"worker_disk" prompt:"Worker Disk (e.g., 40G)"`
+	DNSServers         []string `yaml:"dns_servers" prompt:"DNS Servers (comma-separated)"`
+	KubernetesPackages []string `yaml:"kubernetes_packages" prompt:"Kubernetes Packages (comma-separated)"`
 }
 
-// LoadDefaultConfig loads the default Kubernetes configuration
+// ComponentName identifies the component in the registry, the defaults
+// file and the recorded state of its clusters
+const ComponentName = "kubernetes"
+
+// ClusterName names the default cluster, the one provisioned without a name
+const ClusterName = ComponentName
+
+// newProvider, runPlaybook and readHost are variables so tests can replace
+// the VMs, external commands and host and inspect how they are used
+var (
+	newProvider = func() vm.Provider { return multipass.New() }
+	runPlaybook = ansible.RunPlaybookWithLog
+	readHost    = func() (vm.Host, error) { return vm.ReadHost(multipass.StoragePath()) }
+)
+
+// BuiltinConfig returns the settings used where ansible/defaults/kubernetes.yml
+// does not set them, e.g. in an older checkout given with --assets-dir
+func BuiltinConfig() *Config {
+	return &Config{
+		KubernetesVersion:  "1.32",
+		ControlPlaneCount:  1,
+		WorkerCount:        3,
+		PodCIDR:            "192.168.0.0/16",
+		ServiceCIDR:        "10.96.0.0/16",
+		CNIPlugin:          CNICalico,
+		CalicoVersion:      "v3.29.1",
+		FlannelVersion:     "v0.26.2",
+		CiliumVersion:      "1.16.5",
+		ControlPlaneCPUs:   4,
+		ControlPlaneMemory: "8G",
+		ControlPlaneDisk:   "40G",
+		WorkerCPUs:         4,
+		WorkerMemory:       "8G",
+		WorkerDisk:         "40G",
+		DNSServers:         []string{"8.8.8.8", "8.8.4.4"},
+		KubernetesPackages: []string{"kubelet", "kubeadm", "kubectl"},
+	}
+}
+
+// LoadDefaultConfig loads the default Kubernetes configuration from the defaults
+// file on top of the built-in one
 func LoadDefaultConfig() (*Config, error) {
-	defaultsPath, err := config.GetAnsiblePath("defaults/kubernetes.yml")
-	if err != nil {
-		return nil, fmt.Errorf("failed to locate defaults file: %w", err)
+	defaults := BuiltinConfig()
+	if _, err := config.Load(defaults, config.DefaultsLayer(ComponentName)); err != nil {
+		return nil, err
 	}
+	return defaults, nil
+}
 
-	data, err := os.ReadFile(defaultsPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read defaults file: %w", err)
+// SetName makes the config describe the named cluster. Unless a name
+// prefix is set, the VM names are prefixed with the cluster name.
+func (c *Config) SetName(name string) {
+	c.Name = name
+	if c.NamePrefix == "" {
+		c.NamePrefix = component.VMPrefix(name)
 	}
+}
 
-	config := &Config{}
-	err = yaml.Unmarshal(data, config)
+// clusterName returns the name the cluster is recorded under
+func (c *Config) clusterName() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return ClusterName
+}
+
+// PrintConfig displays the settings that will be used for provisioning
+func PrintConfig(config *Config) {
+	if config.Name != "" {
+		fmt.Printf("Cluster Name: %s\n", config.Name)
+	}
+	fmt.Printf("Kubernetes Version: %s\n", config.KubernetesVersion)
+	fmt.Printf("Pod CIDR: %s\n", config.PodCIDR)
+	fmt.Printf("Service CIDR: %s\n", config.ServiceCIDR)
+	fmt.Printf("CNI Plugin: %s %s\n", config.CNIPlugin, config.CNIVersion())
+	fmt.Printf("Control Plane: %d x %d CPUs, %s Memory, %s Disk\n",
+		config.ControlPlaneCount,
+		config.ControlPlaneCPUs,
+		config.ControlPlaneMemory,
+		config.ControlPlaneDisk)
+	fmt.Printf("Worker Nodes: %d x %d CPUs, %s Memory, %s Disk\n",
+		config.WorkerCount,
+		config.WorkerCPUs,
+		config.WorkerMemory,
+		config.WorkerDisk)
+}
+
+// PromptConfig asks whether to keep the settings in k8sConfig and, if not,
+// prompts for every setting, rejecting invalid values as they are entered
+func PromptConfig(k8sConfig *Config) error {
+	useDefaults, err := interactive.PromptConfirm("Do you want to use these default settings?")
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse defaults file: %w", err)
+		return err
+	}
+	if useDefaults {
+		return nil
 	}
 
-	return config, nil
+	return component.PromptForm(k8sConfig, k8sConfig.formFields(), k8sConfig.Validate)
 }
 
-// SaveConfig saves the configuration to a temp file
-func SaveConfig(config *Config) (string, error) {
-	data, err := yaml.Marshal(config)
+// Provision creates the Kubernetes cluster described by k8sConfig without
+// asking any questions. The config, nodes, inventory and playbook output are
+// recorded in the cluster's state directory.
+func Provision(k8sConfig *Config) (err error) {
+	if err := k8sConfig.Validate(); err != nil {
+		return err
+	}
+
+	if err := component.CheckNameFree(k8sConfig.Name); err != nil {
+		return err
+	}
+	if err := state.CheckNodesFree(k8sConfig.clusterName(), vmNames(k8sConfig)); err != nil {
+		return err
+	}
+
+	cluster, err := state.Begin(k8sConfig.clusterName(), ComponentName)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal config: %w", err)
+		return err
 	}
+	defer func() { err = cluster.Finish(err) }()
 
-	// Create a temporary file in the system temp directory
-	tempFile, err := os.CreateTemp("", "k8s-config-*.yml")
+	// The recorded config is passed to the playbook as extra vars so the
+	// user's values override ansible/defaults/kubernetes.yml
+	configPath, err := cluster.SaveConfig(k8sConfig)
 	if err != nil {
-		return "", fmt.Errorf("failed to create temp file: %w", err)
+		return err
 	}
-	defer tempFile.Close()
 
-	// Write the config data
-	if _, err := tempFile.Write(data); err != nil {
-		return "", fmt.Errorf("failed to write config to temp file: %w", err)
+	keyPath, err := vm.DefaultSSHKeyPath()
+	if err != nil {
+		return err
 	}
+	cluster.SSHKey = keyPath
 
-	return tempFile.Name(), nil
-}
+	templatePath, err := config.GetMultipassPath("cloud-init/common.yaml")
+	if err != nil {
+		return fmt.Errorf("failed to locate cloud-init template: %w", err)
+	}
 
-// ProvisionInteractive handles interactive Kubernetes cluster provisioning
-func ProvisionInteractive() error {
-	// Load default configuration
-	defaultConfig, err := LoadDefaultConfig()
+	cloudInitPath, err := vm.PrepareCloudInit(templatePath, keyPath)
 	if err != nil {
 		return err
 	}
+	defer os.Remove(cloudInitPath)
 
-	// Display default settings
-	fmt.Println("\nCurrent default settings:")
-	fmt.Printf("Kubernetes Version: %s\n", defaultConfig.KubernetesVersion)
-	fmt.Printf("Pod CIDR: %s\n", defaultConfig.PodCIDR)
-	fmt.Printf("Service CIDR: %s\n", defaultConfig.ServiceCIDR)
-	fmt.Printf("CNI Plugin: %s\n", defaultConfig.CNIPlugin)
-	fmt.Printf("Control Plane: %d CPUs, %s Memory, %s Disk\n",
-		defaultConfig.ControlPlaneCPUs,
-		defaultConfig.ControlPlaneMemory,
-		defaultConfig.ControlPlaneDisk)
-	fmt.Printf("Worker Nodes: %d CPUs, %s Memory, %s Disk\n",
-		defaultConfig.WorkerCPUs,
-		defaultConfig.WorkerMemory,
-		defaultConfig.WorkerDisk)
-
-	// Ask if user wants to use default settings
-	useDefaults, err := interactive.PromptConfirm("Do you want to use these default settings?")
+	// Create the VMs that don't exist yet
+	fmt.Println("Checking for existing VMs...")
+	instances, err := vm.EnsureInstances(newProvider(), launchOptions(k8sConfig, cloudInitPath))
 	if err != nil {
 		return err
 	}
 
-	k8sConfig := defaultConfig
-	if !useDefaults {
-		// If user doesn't want defaults, prompt for custom values
-		// This part of the code remains mostly the same, but could be simplified further
-		k8sVersion, err := interactive.PromptText("Kubernetes Version", k8sConfig.KubernetesVersion)
-		if err != nil {
-			return err
-		}
-		k8sConfig.KubernetesVersion = k8sVersion
+	vms := splitInstances(k8sConfig, instances)
+	cluster.Nodes = vms.nodes()
+	if err := cluster.Save(); err != nil {
+		return err
+	}
 
-		// Add more prompts for other values here...
-		// For brevity, I'm just showing a simplified version
+	inventoryPath := cluster.Path(state.InventoryFile)
+	if err := buildInventory(vms, keyPath).WriteFile(inventoryPath); err != nil {
+		return err
 	}
+	fmt.Printf("✓ Ansible inventory created at %s\n", inventoryPath)
 
-	// Save configuration to a temporary file
-	configPath, err := SaveConfig(k8sConfig)
+	playbookPath, err := config.GetAnsiblePath("playbooks/kubernetes.yml")
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to locate playbook: %w", err)
 	}
-	defer os.Remove(configPath) // Clean up temp file when done
 
-	// Confirm before proceeding
-	proceed, err := interactive.PromptConfirm("Do you want to proceed with provisioning?")
+	log, err := cluster.OpenLog("provision")
 	if err != nil {
 		return err
 	}
+	defer log.Close()
 
-	if !proceed {
-		fmt.Println("Provisioning cancelled.")
-		return nil
+	fmt.Println("Running Ansible playbook for Kubernetes deployment...")
+	if err := runPlaybook(playbookPath, inventoryPath, ansible.ExtraVarsFile(configPath), log); err != nil {
+		return fmt.Errorf("%w (see %s)", err, cluster.Path(state.LogFile))
 	}
 
-	// Run the provision script
-	fmt.Println("Running provisioning script...")
+	fmt.Println("✓ Kubernetes environment setup complete.")
 
-	// Get the path to the provision script
-	scriptPath, err := config.GetScriptsPath("provision-kubernetes.sh")
+	// The cluster works without a host kubeconfig, so only warn on failure
+	kubeconfigPath, err := DefaultKubeconfigPath()
+	if err == nil {
+		err = MergeKubeconfig(k8sConfig, kubeconfigPath)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to locate provision script: %w", err)
+		fmt.Printf("⚠ Could not export the kubeconfig: %v\n", err)
+		retry := "provision-cli kubeconfig"
+		if k8sConfig.Name != "" {
+			retry += " --name " + k8sConfig.Name
+		}
+		fmt.Printf("Run '%s' to retry, or use:\n", retry)
+		fmt.Printf("multipass shell %s\n", k8sConfig.ControlPlaneNames()[0])
+		return nil
 	}
 
-	// Execute the script directly (simpler approach)
-	cmd := exec.Command(scriptPath)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin
-
-	return cmd.Run()
+	fmt.Printf("✓ Kubeconfig context '%s' added to %s\n", k8sConfig.ContextName(), kubeconfigPath)
+	fmt.Println("\nTo access the cluster, run:")
+	fmt.Printf("kubectl --context %s get nodes\n", k8sConfig.ContextName())
+	return nil
 }
 
-// Cleanup handles Kubernetes cluster cleanup
-func Cleanup() error {
-	// Get path to cleanup script
-	scriptPath, err := config.GetScriptsPath("cleanup-kubernetes.sh")
+// Cleanup deletes the VMs of the cluster described by k8sConfig, its host
+// kubeconfig context and its recorded state. Besides the recorded nodes,
+// workers are matched by name, so nodes left over from a larger cluster are
+// removed too.
+func Cleanup(k8sConfig *Config) error {
+	provider := newProvider()
+
+	instances, err := clusterInstances(provider, k8sConfig)
 	if err != nil {
-		return fmt.Errorf("failed to locate cleanup script: %w", err)
+		return err
+	}
+
+	var names []string
+	for _, instance := range instances {
+		names = append(names, instance.Name)
 	}
 
-	// Check if script exists
-	if _, err := os.Stat(scriptPath); os.IsNotExist(err) {
-		return fmt.Errorf("cleanup script does not exist: %s", scriptPath)
+	if len(names) == 0 {
+		fmt.Println("No Kubernetes VMs found")
+	} else {
+		fmt.Printf("Deleting Kubernetes VMs: %s\n", strings.Join(names, ", "))
+		for _, name := range names {
+			err := provider.Delete(name)
+			if errors.Is(err, vm.ErrNotFound) {
+				fmt.Printf("VM '%s' does not exist, skipping\n", name)
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("failed to delete VM %s: %w", name, err)
+			}
+		}
+		fmt.Println("✓ VMs deleted")
 	}
 
-	// Run the cleanup script
-	cmd := exec.Command(scriptPath)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin
+	removeHostKubeconfig(k8sConfig)
 
-	return cmd.Run()
+	if err := state.Remove(k8sConfig.clusterName()); err != nil {
+		return err
+	}
+	fmt.Println("✓ Cluster state removed")
+	return nil
 }
 
+// removeHostKubeconfig removes the cluster's context from the host
+// kubeconfig, warning instead of failing since the VMs are already gone
+func removeHostKubeconfig(k8sConfig *Config) {
+	kubeconfigPath, err := DefaultKubeconfigPath()
+	if err == nil {
+		err = RemoveKubeconfig(k8sConfig, kubeconfigPath)
+	}
+	if err != nil {
+		fmt.Printf("⚠ Could not remove context '%s' from the kubeconfig: %v\n", k8sConfig.ContextName(), err)
+		return
+	}
+	fmt.Printf("✓ Kubeconfig context '%s' removed\n", k8sConfig.ContextName())
+}
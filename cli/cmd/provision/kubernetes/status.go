@@ -0,0 +1,224 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/vm"
+)
+
+// Status describes the VMs of a cluster and, when the control plane is
+// reachable, its nodes and the pods that are not running
+type Status struct {
+	VMs            []VMStatus   `json:"vms"`
+	Nodes          []NodeStatus `json:"nodes"`
+	NonRunningPods []PodStatus  `json:"non_running_pods"`
+	// Error explains why nodes and pods could not be queried
+	Error string `json:"error,omitempty"`
+}
+
+// VMStatus is the state of a single cluster VM
+type VMStatus struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
+	IP    string `json:"ip"`
+}
+
+// NodeStatus is the Ready condition of a Kubernetes node
+type NodeStatus struct {
+	Name  string `json:"name"`
+	Ready bool   `json:"ready"`
+}
+
+// PodStatus is a pod that is neither running nor completed
+type PodStatus struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Phase     string `json:"phase"`
+}
+
+// Healthy reports whether every VM is running, every node is Ready and all
+// pods are running or completed
+func (s *Status) Healthy() bool {
+	if len(s.VMs) == 0 || s.Error != "" || len(s.NonRunningPods) > 0 {
+		return false
+	}
+	for _, v := range s.VMs {
+		if v.State != "Running" {
+			return false
+		}
+	}
+	for _, node := range s.Nodes {
+		if !node.Ready {
+			return false
+		}
+	}
+	return true
+}
+
+// kubectl returns a kubectl command that uses the admin kubeconfig of a
+// control plane
+func kubectl(args ...string) []string {
+	return append([]string{"sudo", "kubectl", "--kubeconfig=/etc/kubernetes/admin.conf"}, args...)
+}
+
+// GetStatus collects the status of the cluster described by k8sConfig. It
+// performs the same checks as ansible/tasks/kubernetes/validate-cluster.yml.
+func GetStatus(k8sConfig *Config) (*Status, error) {
+	provider := newProvider()
+
+	isClusterVM, err := clusterVMFilter(k8sConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	instances, err := provider.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list VMs: %w", err)
+	}
+
+	status := &Status{}
+	running := map[string]bool{}
+	for _, instance := range instances {
+		if !isClusterVM(instance.Name) {
+			continue
+		}
+		status.VMs = append(status.VMs, VMStatus{Name: instance.Name, State: instance.State, IP: instance.IP()})
+		running[instance.Name] = instance.Running()
+	}
+
+	if len(status.VMs) == 0 {
+		return status, nil
+	}
+
+	controlPlane := k8sConfig.ControlPlaneNames()[0]
+	if !running[controlPlane] {
+		status.Error = fmt.Sprintf("control plane %s is not running", controlPlane)
+		return status, nil
+	}
+
+	if status.Nodes, err = getNodes(provider, controlPlane); err != nil {
+		status.Error = err.Error()
+		return status, nil
+	}
+	if status.NonRunningPods, err = getNonRunningPods(provider, controlPlane); err != nil {
+		status.Error = err.Error()
+	}
+	return status, nil
+}
+
+// getNodes returns the Ready condition of every node
+func getNodes(provider vm.Provider, controlPlane string) ([]NodeStatus, error) {
+	out, err := provider.Exec(controlPlane, kubectl("get", "nodes", "-o", "json")...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nodes: %w", err)
+	}
+
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+			Status struct {
+				Conditions []struct {
+					Type   string `json:"type"`
+					Status string `json:"status"`
+				} `json:"conditions"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse kubectl get nodes output: %w", err)
+	}
+
+	nodes := make([]NodeStatus, 0, len(list.Items))
+	for _, item := range list.Items {
+		node := NodeStatus{Name: item.Metadata.Name}
+		for _, condition := range item.Status.Conditions {
+			if condition.Type == "Ready" {
+				node.Ready = condition.Status == "True"
+			}
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// getNonRunningPods returns the pods that are neither running nor completed
+func getNonRunningPods(provider vm.Provider, controlPlane string) ([]PodStatus, error) {
+	out, err := provider.Exec(controlPlane, kubectl("get", "pods", "--all-namespaces", "-o", "json")...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pods: %w", err)
+	}
+
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Namespace string `json:"namespace"`
+				Name      string `json:"name"`
+			} `json:"metadata"`
+			Status struct {
+				Phase string `json:"phase"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse kubectl get pods output: %w", err)
+	}
+
+	var pods []PodStatus
+	for _, item := range list.Items {
+		if item.Status.Phase == "Running" || item.Status.Phase == "Succeeded" {
+			continue
+		}
+		pods = append(pods, PodStatus{
+			Namespace: item.Metadata.Namespace,
+			Name:      item.Metadata.Name,
+			Phase:     item.Status.Phase,
+		})
+	}
+	return pods, nil
+}
+
+// Print writes the status as tables
+func (s *Status) Print(w io.Writer) {
+	if len(s.VMs) == 0 {
+		fmt.Fprintln(w, "No Kubernetes VMs found")
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VM\tSTATE\tIP")
+	for _, v := range s.VMs {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", v.Name, v.State, v.IP)
+	}
+	tw.Flush()
+
+	if s.Error != "" {
+		fmt.Fprintf(w, "\nCluster not reachable: %s\n", s.Error)
+		return
+	}
+
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NODE\tREADY")
+	for _, node := range s.Nodes {
+		fmt.Fprintf(tw, "%s\t%t\n", node.Name, node.Ready)
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w)
+	if len(s.NonRunningPods) == 0 {
+		fmt.Fprintln(w, "All pods are in Running or Completed state")
+		return
+	}
+
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAMESPACE\tPOD\tPHASE")
+	for _, pod := range s.NonRunningPods {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", pod.Namespace, pod.Name, pod.Phase)
+	}
+	tw.Flush()
+}
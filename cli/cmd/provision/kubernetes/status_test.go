@@ -0,0 +1,111 @@
+package kubernetes
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/vm"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/vm/fake"
+)
+
+const nodesJSON = `{"items": [
+  {"metadata": {"name": "controlplane"}, "status": {"conditions": [{"type": "MemoryPressure", "status": "False"}, {"type": "Ready", "status": "True"}]}},
+  {"metadata": {"name": "node01"}, "status": {"conditions": [{"type": "Ready", "status": "False"}]}}
+]}`
+
+const podsJSON = `{"items": [
+  {"metadata": {"namespace": "kube-system", "name": "coredns-1"}, "status": {"phase": "Running"}},
+  {"metadata": {"namespace": "default", "name": "job-1"}, "status": {"phase": "Succeeded"}},
+  {"metadata": {"namespace": "calico-system", "name": "calico-node-x"}, "status": {"phase": "Pending"}}
+]}`
+
+// useFakeProvider replaces the VM provider for the duration of a test
+func useFakeProvider(t *testing.T) *fake.Provider {
+	t.Helper()
+
+	orig := newProvider
+	t.Cleanup(func() { newProvider = orig })
+
+	provider := fake.New()
+	newProvider = func() vm.Provider { return provider }
+	return provider
+}
+
+func TestGetStatus(t *testing.T) {
+	provider := useFakeProvider(t)
+	provider.AddInstance("controlplane")
+	provider.AddInstance("node01")
+	provider.AddInstance("rqlite1")
+	provider.ExecFunc = func(name string, command []string) ([]byte, error) {
+		switch strings.Join(command, " ") {
+		case "sudo kubectl --kubeconfig=/etc/kubernetes/admin.conf get nodes -o json":
+			return []byte(nodesJSON), nil
+		case "sudo kubectl --kubeconfig=/etc/kubernetes/admin.conf get pods --all-namespaces -o json":
+			return []byte(podsJSON), nil
+		}
+		t.Errorf("unexpected command on %s: %v", name, command)
+		return nil, nil
+	}
+
+	status, err := GetStatus(&Config{ControlPlaneCount: 1, WorkerCount: 1})
+	if err != nil {
+		t.Fatalf("GetStatus() error = %v", err)
+	}
+
+	if len(status.VMs) != 2 {
+		t.Errorf("VMs = %v, want controlplane and node01 only", status.VMs)
+	}
+	if len(status.Nodes) != 2 || !status.Nodes[0].Ready || status.Nodes[1].Ready {
+		t.Errorf("Nodes = %v, want controlplane Ready and node01 not Ready", status.Nodes)
+	}
+	if len(status.NonRunningPods) != 1 || status.NonRunningPods[0].Name != "calico-node-x" {
+		t.Errorf("NonRunningPods = %v, want only calico-node-x", status.NonRunningPods)
+	}
+	if status.Healthy() {
+		t.Errorf("Healthy() = true with a NotReady node")
+	}
+
+	var out bytes.Buffer
+	status.Print(&out)
+	for _, want := range []string{"controlplane", "node01", "false", "calico-system", "Pending"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Print() output does not contain %q:\n%s", want, out.String())
+		}
+	}
+
+	if _, err := json.Marshal(status); err != nil {
+		t.Errorf("status cannot be encoded as JSON: %v", err)
+	}
+}
+
+func TestGetStatusControlPlaneUnreachable(t *testing.T) {
+	provider := useFakeProvider(t)
+	provider.AddInstance("controlplane")
+	provider.FailOn("exec", "controlplane", errors.New("kubectl: connection refused"))
+
+	status, err := GetStatus(&Config{ControlPlaneCount: 1})
+	if err != nil {
+		t.Fatalf("GetStatus() error = %v", err)
+	}
+	if status.Error == "" || status.Healthy() {
+		t.Errorf("status = %+v, want an error and unhealthy", status)
+	}
+}
+
+func TestGetStatusNoVMs(t *testing.T) {
+	useFakeProvider(t)
+
+	status, err := GetStatus(&Config{ControlPlaneCount: 1})
+	if err != nil {
+		t.Fatalf("GetStatus() error = %v", err)
+	}
+
+	var out bytes.Buffer
+	status.Print(&out)
+	if !strings.Contains(out.String(), "No Kubernetes VMs found") {
+		t.Errorf("Print() = %q, want a not found message", out.String())
+	}
+}
@@ -0,0 +1,152 @@
+// Package plan describes what provisioning or cleaning up a cluster would
+// do, so it can be reviewed with --dry-run before anything changes
+package plan
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/state"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/vm"
+)
+
+// Actions of a plan
+const (
+	Provision = "provision"
+	Cleanup   = "cleanup"
+)
+
+// Actions on a VM
+const (
+	Launch = "launch"
+	Reuse  = "reuse"
+	Delete = "delete"
+)
+
+// Plan is what a provision or cleanup run would do
+type Plan struct {
+	Action    string `json:"action"`
+	Cluster   string `json:"cluster"`
+	Component string `json:"component"`
+	VMs       []VM   `json:"vms"`
+	// CloudInit is the rendered cloud-init of the VMs to launch
+	CloudInit string `json:"cloud_init,omitempty"`
+	// Inventory is the generated Ansible inventory
+	Inventory string `json:"inventory,omitempty"`
+	// Commands are the external commands run, in order
+	Commands [][]string `json:"commands,omitempty"`
+	// Steps are the other changes, such as files written or removed
+	Steps    []string `json:"steps,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// VM is a VM the plan launches, reuses or deletes
+type VM struct {
+	Name   string `json:"name"`
+	Role   string `json:"role,omitempty"`
+	Action string `json:"action"`
+	CPUs   int    `json:"cpus,omitempty"`
+	Memory string `json:"memory,omitempty"`
+	Disk   string `json:"disk,omitempty"`
+	IP     string `json:"ip,omitempty"`
+}
+
+// LaunchVMs describes the VMs of specs, with the role recorded for each in
+// nodes and whether it is launched or reused. All three are in the same order.
+func LaunchVMs(specs []vm.LaunchOptions, nodes []state.Node, launch []bool) []VM {
+	vms := make([]VM, 0, len(specs))
+	for i, spec := range specs {
+		planned := VM{Name: spec.Name, Role: nodes[i].Role, Action: Reuse, CPUs: spec.CPUs, Memory: spec.Memory, Disk: spec.Disk, IP: nodes[i].IP}
+		if launch[i] {
+			planned.Action = Launch
+			planned.IP = ""
+		}
+		vms = append(vms, planned)
+	}
+	return vms
+}
+
+// Include adds the VMs, commands, steps and warnings of other after those
+// of p, and its cloud-init and inventory when p has none
+func (p *Plan) Include(other *Plan) {
+	p.VMs = append(p.VMs, other.VMs...)
+	p.Commands = append(p.Commands, other.Commands...)
+	p.Steps = append(p.Steps, other.Steps...)
+	p.Warnings = append(p.Warnings, other.Warnings...)
+	if p.CloudInit == "" {
+		p.CloudInit = other.CloudInit
+	}
+	if p.Inventory == "" {
+		p.Inventory = other.Inventory
+	}
+}
+
+// Print writes the plan as text
+func (p *Plan) Print(w io.Writer) {
+	fmt.Fprintf(w, "Plan: %s %s cluster %s\n", p.Action, p.Component, p.Cluster)
+
+	for _, warning := range p.Warnings {
+		fmt.Fprintf(w, "⚠ %s\n", warning)
+	}
+
+	fmt.Fprintln(w, "\nVMs:")
+	if len(p.VMs) == 0 {
+		fmt.Fprintln(w, "  none")
+	} else {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "  ACTION\tNAME\tROLE\tCPUS\tMEMORY\tDISK\tIP")
+		for _, planned := range p.VMs {
+			cpus := ""
+			if planned.CPUs > 0 {
+				cpus = fmt.Sprint(planned.CPUs)
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				planned.Action, planned.Name, planned.Role, cpus, planned.Memory, planned.Disk, planned.IP)
+		}
+		tw.Flush()
+	}
+
+	if p.CloudInit != "" {
+		fmt.Fprintln(w, "\nCloud-init:")
+		printIndented(w, p.CloudInit)
+	}
+	if p.Inventory != "" {
+		fmt.Fprintln(w, "\nInventory:")
+		printIndented(w, p.Inventory)
+	}
+
+	if len(p.Commands) > 0 {
+		fmt.Fprintln(w, "\nCommands:")
+		for _, command := range p.Commands {
+			fmt.Fprintf(w, "  %s\n", quote(command))
+		}
+	}
+
+	if len(p.Steps) > 0 {
+		fmt.Fprintln(w, "\nSteps:")
+		for _, step := range p.Steps {
+			fmt.Fprintf(w, "  - %s\n", step)
+		}
+	}
+}
+
+// printIndented writes text indented by two spaces
+func printIndented(w io.Writer, text string) {
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		fmt.Fprintf(w, "  %s\n", line)
+	}
+}
+
+// quote joins a command line, quoting the arguments a shell would split
+func quote(command []string) string {
+	quoted := make([]string, 0, len(command))
+	for _, arg := range command {
+		if arg == "" || strings.ContainsAny(arg, " \t\"'$<>") {
+			arg = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+		quoted = append(quoted, arg)
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,80 @@
+package plan
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/state"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/vm"
+)
+
+func TestLaunchVMs(t *testing.T) {
+	specs := []vm.LaunchOptions{
+		{Name: "controlplane", CPUs: 4, Memory: "8G", Disk: "40G"},
+		{Name: "node01", CPUs: 2, Memory: "4G", Disk: "20G"},
+	}
+	nodes := []state.Node{
+		{Name: "controlplane", Role: "control-plane", IP: "<controlplane IP>"},
+		{Name: "node01", Role: "worker", IP: "10.0.0.10"},
+	}
+
+	vms := LaunchVMs(specs, nodes, []bool{true, false})
+	want := []VM{
+		{Name: "controlplane", Role: "control-plane", Action: Launch, CPUs: 4, Memory: "8G", Disk: "40G"},
+		{Name: "node01", Role: "worker", Action: Reuse, CPUs: 2, Memory: "4G", Disk: "20G", IP: "10.0.0.10"},
+	}
+	for i := range want {
+		if vms[i] != want[i] {
+			t.Errorf("LaunchVMs()[%d] = %+v, want %+v", i, vms[i], want[i])
+		}
+	}
+}
+
+func TestPrint(t *testing.T) {
+	p := &Plan{
+		Action:    Provision,
+		Cluster:   "lab",
+		Component: "kubernetes",
+		VMs:       []VM{{Name: "lab-controlplane", Role: "control-plane", Action: Launch, CPUs: 4, Memory: "8G", Disk: "40G"}},
+		CloudInit: "#cloud-config\nusers: []\n",
+		Inventory: "---\nall: {}\n",
+		Commands:  [][]string{{"ansible-playbook", "-i", "/state/inventory.yml", "/repo/my playbook.yml"}},
+		Steps:     []string{"Record the cluster in /state"},
+		Warnings:  []string{"could not list the existing VMs"},
+	}
+
+	var buf bytes.Buffer
+	p.Print(&buf)
+	out := buf.String()
+	for _, want := range []string{
+		"Plan: provision kubernetes cluster lab",
+		"⚠ could not list the existing VMs",
+		"launch  lab-controlplane  control-plane  4     8G      40G",
+		"  #cloud-config\n  users: []\n",
+		"  ---\n  all: {}\n",
+		"  ansible-playbook -i /state/inventory.yml '/repo/my playbook.yml'\n",
+		"  - Record the cluster in /state\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Print() output does not contain %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestInclude(t *testing.T) {
+	teardown := &Plan{Action: Cleanup, Inventory: "teardown inventory", Commands: [][]string{{"ansible-playbook", "teardown.yml"}}}
+	teardown.Include(&Plan{
+		Action:   Cleanup,
+		VMs:      []VM{{Name: "rqlite1", Action: Delete}},
+		Steps:    []string{"Remove the state directory"},
+		Warnings: []string{"leader not running"},
+	})
+
+	if len(teardown.VMs) != 1 || len(teardown.Commands) != 1 || len(teardown.Steps) != 1 || len(teardown.Warnings) != 1 {
+		t.Errorf("Include() = %+v, want the VMs, commands, steps and warnings of both", teardown)
+	}
+	if teardown.Inventory != "teardown inventory" {
+		t.Errorf("Inventory = %q, want the one of the including plan", teardown.Inventory)
+	}
+}
@@ -0,0 +1,239 @@
+// Package profile stores named sets of settings, e.g. small, ci or ha, in the
+// user's config directory so they can be reused when provisioning. A profile
+// only holds the settings that differ from the defaults, so it keeps
+// following the defaults it does not override.
+package profile
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/config"
+	"gopkg.in/yaml.v3"
+)
+
+// fileExt is the extension of profile files
+const fileExt = ".yml"
+
+// ErrNotFound is returned when no profile of that name is stored
+var ErrNotFound = errors.New("profile not found")
+
+// namePattern matches profile names, which are used as file names
+var namePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// omittedKeys are settings a profile never holds, since they describe one
+// cluster rather than a kind of cluster
+var omittedKeys = map[string]bool{"cluster_name": true}
+
+// Profile is a stored profile of a component
+type Profile struct {
+	Component string `json:"component"`
+	Name      string `json:"name"`
+	Path      string `json:"path"`
+}
+
+// ValidateName rejects profile names that cannot be used as a file name
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("profile name %q must start with a letter or digit and contain only letters, digits, dots, hyphens and underscores", name)
+	}
+	return nil
+}
+
+// Dir returns the directory holding the profiles of every component,
+// profiles in the config root
+func Dir() (string, error) {
+	root, err := config.GetConfigRoot()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, "profiles"), nil
+}
+
+// Path returns the file a profile of a component is stored in, whether it
+// exists or not
+func Path(component, name string) (string, error) {
+	if err := ValidateName(name); err != nil {
+		return "", err
+	}
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, component, name+fileExt), nil
+}
+
+// Find returns the file of a stored profile, or ErrNotFound
+func Find(component, name string) (string, error) {
+	path, err := Path(component, name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("%w: %s profile %s", ErrNotFound, component, name)
+	} else if err != nil {
+		return "", fmt.Errorf("failed to read profile %s: %w", name, err)
+	}
+	return path, nil
+}
+
+// Read returns the contents of a stored profile
+func Read(component, name string) ([]byte, error) {
+	path, err := Find(component, name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profile %s: %w", name, err)
+	}
+	return data, nil
+}
+
+// Save stores the settings of settings that differ from those of defaults
+// as a profile of a component, replacing a profile of the same name, and
+// returns the path of the file. Both are marshalled to YAML and compared
+// key by key, so they are usually configs of the same type.
+func Save(component, name string, settings, defaults interface{}) (string, error) {
+	path, err := Path(component, name)
+	if err != nil {
+		return "", err
+	}
+
+	overrides, err := diff(settings, defaults)
+	if err != nil {
+		return "", err
+	}
+	overrides.HeadComment = fmt.Sprintf("%s profile %s, settings that override ansible/defaults/%s.yml",
+		component, name, component)
+
+	data, err := yaml.Marshal(overrides)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal profile: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create profile directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write profile %s: %w", name, err)
+	}
+	return path, nil
+}
+
+// Differs reports whether a profile saved from settings and defaults would
+// override any setting
+func Differs(settings, defaults interface{}) (bool, error) {
+	overrides, err := diff(settings, defaults)
+	if err != nil {
+		return false, err
+	}
+	return len(overrides.Content) > 0, nil
+}
+
+// Delete removes a stored profile
+func Delete(component, name string) error {
+	path, err := Find(component, name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to delete profile %s: %w", name, err)
+	}
+	return nil
+}
+
+// List returns the stored profiles sorted by component and name
+func List() ([]Profile, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*", "*"+fileExt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list profiles: %w", err)
+	}
+
+	var profiles []Profile
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), fileExt)
+		if ValidateName(name) != nil {
+			continue
+		}
+		profiles = append(profiles, Profile{
+			Component: filepath.Base(filepath.Dir(path)),
+			Name:      name,
+			Path:      path,
+		})
+	}
+
+	sort.Slice(profiles, func(i, j int) bool {
+		if profiles[i].Component != profiles[j].Component {
+			return profiles[i].Component < profiles[j].Component
+		}
+		return profiles[i].Name < profiles[j].Name
+	})
+	return profiles, nil
+}
+
+// PrintList writes one line per profile
+func PrintList(w io.Writer, profiles []Profile) {
+	if len(profiles) == 0 {
+		fmt.Fprintln(w, "No profiles found")
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "COMPONENT\tNAME\tPATH")
+	for _, p := range profiles {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", p.Component, p.Name, p.Path)
+	}
+	tw.Flush()
+}
+
+// diff returns a YAML mapping of the settings of settings whose values
+// differ from those of defaults, in the order settings declares them
+func diff(settings, defaults interface{}) (*yaml.Node, error) {
+	var node yaml.Node
+	if err := node.Encode(settings); err != nil {
+		return nil, fmt.Errorf("failed to marshal profile: %w", err)
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("profile settings must be a mapping, got %T", settings)
+	}
+
+	data, err := yaml.Marshal(defaults)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal defaults: %w", err)
+	}
+	defaultValues := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &defaultValues); err != nil {
+		return nil, fmt.Errorf("failed to parse defaults: %w", err)
+	}
+
+	overrides := &yaml.Node{Kind: yaml.MappingNode}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if omittedKeys[key.Value] {
+			continue
+		}
+
+		var decoded interface{}
+		if err := value.Decode(&decoded); err != nil {
+			return nil, fmt.Errorf("failed to marshal %s: %w", key.Value, err)
+		}
+		if defaultValue, ok := defaultValues[key.Value]; ok && reflect.DeepEqual(decoded, defaultValue) {
+			continue
+		}
+		overrides.Content = append(overrides.Content, key, value)
+	}
+	return overrides, nil
+}
@@ -0,0 +1,167 @@
+package profile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// settings stands in for the config of a component
+type settings struct {
+	Name        string   `yaml:"cluster_name,omitempty"`
+	NamePrefix  string   `yaml:"name_prefix"`
+	WorkerCount int      `yaml:"worker_count"`
+	NodeMemory  string   `yaml:"node_memory"`
+	DNSServers  []string `yaml:"dns_servers"`
+}
+
+// useConfigHome points the config directory at a temporary directory
+func useConfigHome(t *testing.T) string {
+	t.Helper()
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	return configHome
+}
+
+func TestSaveKeepsOnlyOverrides(t *testing.T) {
+	configHome := useConfigHome(t)
+
+	defaults := &settings{Name: "lab", NamePrefix: "lab-", WorkerCount: 2, NodeMemory: "2G", DNSServers: []string{"8.8.8.8"}}
+	ci := *defaults
+	ci.WorkerCount = 1
+	ci.DNSServers = []string{"1.1.1.1"}
+
+	path, err := Save("kubernetes", "ci", &ci, defaults)
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if want := filepath.Join(configHome, "provision-cli", "profiles", "kubernetes", "ci.yml"); path != want {
+		t.Errorf("Save() path = %q, want %q", path, want)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read profile: %v", err)
+	}
+	text := string(data)
+	if !strings.HasPrefix(text, "# kubernetes profile ci") {
+		t.Errorf("profile = %q, want a header naming it", text)
+	}
+	for _, unwanted := range []string{"cluster_name", "name_prefix", "node_memory"} {
+		if strings.Contains(text, unwanted) {
+			t.Errorf("profile = %q, should not hold %s", text, unwanted)
+		}
+	}
+
+	loaded := *defaults
+	if err := yaml.Unmarshal(data, &loaded); err != nil {
+		t.Fatalf("failed to parse profile: %v", err)
+	}
+	if loaded.WorkerCount != 1 || loaded.NodeMemory != "2G" || len(loaded.DNSServers) != 1 || loaded.DNSServers[0] != "1.1.1.1" {
+		t.Errorf("defaults with profile = %+v, want the overrides on top of the defaults", loaded)
+	}
+}
+
+func TestSaveWithoutOverrides(t *testing.T) {
+	useConfigHome(t)
+
+	defaults := &settings{WorkerCount: 2}
+	path, err := Save("rqlite", "plain", defaults, defaults)
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	var loaded map[string]interface{}
+	data, _ := os.ReadFile(path)
+	if err := yaml.Unmarshal(data, &loaded); err != nil || len(loaded) != 0 {
+		t.Errorf("profile = %q, %v, want an empty mapping", data, err)
+	}
+}
+
+func TestSaveRejectsInvalidNames(t *testing.T) {
+	useConfigHome(t)
+
+	for _, name := range []string{"", "../ha", "-ci", "my profile"} {
+		if _, err := Save("kubernetes", name, &settings{}, &settings{}); err == nil {
+			t.Errorf("Save(%q) succeeded, want an invalid name error", name)
+		}
+	}
+}
+
+func TestFindReadAndDelete(t *testing.T) {
+	useConfigHome(t)
+
+	if _, err := Find("kubernetes", "ha"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Find() error = %v, want ErrNotFound", err)
+	}
+	if err := Delete("kubernetes", "ha"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Delete() error = %v, want ErrNotFound", err)
+	}
+
+	saved, err := Save("kubernetes", "ha", &settings{WorkerCount: 3}, &settings{WorkerCount: 2})
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if path, err := Find("kubernetes", "ha"); err != nil || path != saved {
+		t.Errorf("Find() = %q, %v, want %q", path, err, saved)
+	}
+	if data, err := Read("kubernetes", "ha"); err != nil || !strings.Contains(string(data), "worker_count: 3") {
+		t.Errorf("Read() = %q, %v, want the stored overrides", data, err)
+	}
+	// Profiles are stored per component
+	if _, err := Find("rqlite", "ha"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Find() of another component error = %v, want ErrNotFound", err)
+	}
+
+	if err := Delete("kubernetes", "ha"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := os.Stat(saved); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("profile file still exists after Delete(): %v", err)
+	}
+}
+
+func TestList(t *testing.T) {
+	useConfigHome(t)
+
+	if profiles, err := List(); err != nil || len(profiles) != 0 {
+		t.Fatalf("List() = %v, %v, want no profiles", profiles, err)
+	}
+
+	for _, p := range []struct{ component, name string }{
+		{"rqlite", "small"}, {"kubernetes", "small"}, {"kubernetes", "ci"},
+	} {
+		if _, err := Save(p.component, p.name, &settings{}, &settings{}); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	profiles, err := List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	var got []string
+	for _, p := range profiles {
+		got = append(got, p.Component+"/"+p.Name)
+	}
+	if want := "kubernetes/ci kubernetes/small rqlite/small"; strings.Join(got, " ") != want {
+		t.Errorf("List() = %v, want %s", got, want)
+	}
+}
+
+func TestDiffers(t *testing.T) {
+	defaults := &settings{Name: "lab", WorkerCount: 2}
+	named := *defaults
+	named.Name = "other"
+	if changed, err := Differs(&named, defaults); err != nil || changed {
+		t.Errorf("Differs() with another cluster name = %t, %v, want false", changed, err)
+	}
+	named.WorkerCount = 3
+	if changed, err := Differs(&named, defaults); err != nil || !changed {
+		t.Errorf("Differs() with another worker count = %t, %v, want true", changed, err)
+	}
+}
@@ -0,0 +1,92 @@
+package rqlite
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/ansible"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/component"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/config"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/state"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/vm"
+)
+
+// Tags are the tags of the plays in playbooks/rqlite.yml, in order
+var Tags = []string{"rqlite-setup", "leader", "followers", "validate"}
+
+// Converge re-runs the rqlite playbook against the recorded nodes, only the
+// plays with opts.Tags on the hosts in opts.Limit when they are set. The
+// inventory is rewritten first since the VMs may have new addresses, and the
+// cluster is recorded as ready or failed afterwards.
+func Converge(rqliteConfig *Config, opts component.ConvergeOptions) (err error) {
+	cluster, err := state.Load(rqliteConfig.clusterName())
+	if errors.Is(err, state.ErrNotFound) {
+		return fmt.Errorf("%w, provision it before converging", err)
+	}
+	if err != nil {
+		return err
+	}
+	if cluster.Component != ComponentName {
+		return fmt.Errorf("cluster %s is a %s cluster", cluster.Name, cluster.Component)
+	}
+	if err := ansible.CheckTags(opts.Tags, Tags); err != nil {
+		return err
+	}
+
+	names := rqliteConfig.VMNames()
+	if len(cluster.Nodes) > 0 {
+		names = cluster.NodeNames()
+	}
+	instances, err := vm.RunningInstances(newProvider(), names)
+	if err != nil {
+		return err
+	}
+	leader, followers := instances[0], instances[1:]
+
+	keyPath := cluster.SSHKey
+	if keyPath == "" {
+		if keyPath, err = vm.DefaultSSHKeyPath(); err != nil {
+			return err
+		}
+	}
+	inventory := buildInventory(leader, followers, keyPath)
+	if err := inventory.CheckLimit(opts.Limit); err != nil {
+		return err
+	}
+
+	cluster.Status = state.StatusProvisioning
+	cluster.Error = ""
+	defer func() { err = cluster.Finish(err) }()
+
+	cluster.Nodes = nodes(leader, followers)
+	if err := cluster.Save(); err != nil {
+		return err
+	}
+	configPath, err := cluster.SaveConfig(rqliteConfig)
+	if err != nil {
+		return err
+	}
+	inventoryPath := cluster.Path(state.InventoryFile)
+	if err := inventory.WriteFile(inventoryPath); err != nil {
+		return err
+	}
+
+	playbookPath, err := config.GetAnsiblePath("playbooks/rqlite.yml")
+	if err != nil {
+		return fmt.Errorf("failed to locate playbook: %w", err)
+	}
+
+	log, err := cluster.OpenLog("converge")
+	if err != nil {
+		return err
+	}
+	defer log.Close()
+
+	fmt.Println("Running Ansible playbook for rqlite...")
+	args := append(ansible.ExtraVarsFile(configPath), ansible.ScopeArgs(opts.Tags, opts.Limit)...)
+	if err := runPlaybook(playbookPath, inventoryPath, args, log); err != nil {
+		return fmt.Errorf("%w (see %s)", err, cluster.Path(state.LogFile))
+	}
+	fmt.Println("✓ rqlite cluster converged")
+	return nil
+}
@@ -0,0 +1,88 @@
+package rqlite
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/component"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/state"
+)
+
+// captureConverge replaces the playbook run with one that records its
+// arguments, since converging passes more than the extra vars
+func captureConverge(t *testing.T) *[]string {
+	t.Helper()
+	args := new([]string)
+	runPlaybook = func(playbookPath, inventory string, extraArgs []string, log io.Writer) error {
+		*args = append([]string{playbookPath, inventory}, extraArgs...)
+		return nil
+	}
+	return args
+}
+
+func TestConverge(t *testing.T) {
+	provider, _ := setupProvision(t)
+
+	rqliteConfig := withDefaults(&Config{RqliteVersion: "8.36.11"})
+	if err := Provision(rqliteConfig); err != nil {
+		t.Fatalf("Provision() error = %v", err)
+	}
+	launched := len(provider.CallsTo("launch"))
+	args := captureConverge(t)
+
+	if err := Converge(rqliteConfig, component.ConvergeOptions{Tags: []string{"followers"}, Limit: []string{"rqlite3"}}); err != nil {
+		t.Fatalf("Converge() error = %v", err)
+	}
+
+	if got := strings.Join(*args, " "); !strings.Contains(got, "--tags followers --limit rqlite3") {
+		t.Errorf("playbook args = %v, want the tags and limit", *args)
+	}
+	if len(provider.CallsTo("launch")) != launched {
+		t.Errorf("VMs launched while converging")
+	}
+	recorded, err := state.Load(ClusterName)
+	if err != nil {
+		t.Fatalf("state.Load() error = %v", err)
+	}
+	if recorded.Status != state.StatusReady {
+		t.Errorf("recorded status = %s, want ready", recorded.Status)
+	}
+}
+
+func TestConvergeErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		provision bool
+		opts      component.ConvergeOptions
+		want      string
+	}{
+		{"not recorded", false, component.ConvergeOptions{}, "provision it before converging"},
+		{"unknown tag", true, component.ConvergeOptions{Tags: []string{"dns"}}, "unknown tag(s) dns"},
+		{"unknown host", true, component.ConvergeOptions{Limit: []string{"rqlite4"}}, "unknown host(s) or group(s) rqlite4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupProvision(t)
+			rqliteConfig := withDefaults(&Config{})
+			if tt.provision {
+				if err := Provision(rqliteConfig); err != nil {
+					t.Fatalf("Provision() error = %v", err)
+				}
+			}
+			args := captureConverge(t)
+
+			err := Converge(rqliteConfig, tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Converge() error = %v, want %q", err, tt.want)
+			}
+			if !tt.provision && !errors.Is(err, state.ErrNotFound) {
+				t.Errorf("Converge() error = %v, want ErrNotFound", err)
+			}
+			if len(*args) != 0 {
+				t.Errorf("playbook ran with %v", *args)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package rqlite
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/component"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/interactive"
+)
+
+// releasePattern matches rqlite releases such as 8.36.11. The download URL
+// adds the leading v.
+var releasePattern = regexp.MustCompile(`^[0-9]+\.[0-9]+\.[0-9]+$`)
+
+// Validate checks every setting of c. It returns a
+// *component.ValidationError listing the invalid settings by YAML key.
+func (c *Config) Validate() error {
+	return component.CheckFields(c, c.formFields()).OrNil()
+}
+
+// formFields customizes the questions PromptConfig asks for the settings of
+// c. The Raft port is checked against the HTTP port entered before it.
+func (c *Config) formFields() map[string]interactive.Field {
+	return map[string]interactive.Field{
+		"cluster_name":       {Validate: interactive.CheckString(component.CheckName)},
+		"rqlite_version":     {Validate: interactive.CheckString(checkRelease)},
+		"rqlite_http_port":   {Validate: interactive.CheckInt(component.CheckPort)},
+		"rqlite_raft_port":   {Validate: interactive.CheckInt(c.checkRaftPort)},
+		"rqlite_data_dir":    {Validate: interactive.CheckString(component.CheckAbsPath)},
+		"rqlite_extract_dir": {Validate: interactive.CheckString(component.CheckAbsPath)},
+		"node_cpus":          {Validate: interactive.CheckInt(component.CheckCPUs)},
+		"node_memory":        {Validate: interactive.CheckString(component.CheckSize)},
+		"node_disk":          {Validate: interactive.CheckString(component.CheckSize)},
+		"dns_servers":        {Validate: interactive.CheckList(component.CheckDNSServers)},
+	}
+}
+
+// checkRelease rejects versions that are not an rqlite release
+func checkRelease(version string) error {
+	if !releasePattern.MatchString(version) {
+		return fmt.Errorf("must be a release such as 8.36.11, got %q", version)
+	}
+	return nil
+}
+
+// checkRaftPort rejects Raft ports that are not ports or clash with the
+// HTTP port
+func (c *Config) checkRaftPort(port int) error {
+	if err := component.CheckPort(port); err != nil {
+		return err
+	}
+	if port == c.RqliteHttpPort {
+		return fmt.Errorf("must differ from rqlite_http_port, got %d", port)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package rqlite
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/config"
+)
+
+func TestFormFields(t *testing.T) {
+	rqliteConfig := &Config{RqliteHttpPort: 4001}
+	fields := rqliteConfig.formFields()
+
+	// Every setting that is asked for is validated
+	configType := reflect.TypeOf(*rqliteConfig)
+	for i := 0; i < configType.NumField(); i++ {
+		field := configType.Field(i)
+		if _, ok := field.Tag.Lookup("prompt"); !ok {
+			continue
+		}
+		if key := config.YAMLKey(field); fields[key].Validate == nil {
+			t.Errorf("%s is asked for without validation", key)
+		}
+	}
+
+	tests := []struct {
+		key   string
+		value interface{}
+		valid bool
+	}{
+		{"rqlite_version", "8.36.11", true},
+		{"rqlite_version", "v8.36.11", false},
+		{"rqlite_http_port", 0, false},
+		{"rqlite_raft_port", 4002, true},
+		{"rqlite_raft_port", 4001, false},
+		{"rqlite_data_dir", "/home/{{ ansible_user }}/data", true},
+		{"rqlite_extract_dir", "opt/rqlite", false},
+		{"node_disk", "10G", true},
+		{"dns_servers", []string{"8.8.8.8", "resolver"}, false},
+	}
+	for _, tt := range tests {
+		err := fields[tt.key].Validate(tt.value)
+		if (err == nil) != tt.valid {
+			t.Errorf("%s = %v: error = %v, want valid %t", tt.key, tt.value, err, tt.valid)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	rqliteConfig := &Config{
+		RqliteVersion: "8.36.11", RqliteHttpPort: 4001, RqliteRaftPort: 4001,
+		RqliteDataDir: "/data", RqliteExtractDir: "/opt/rqlite",
+		NodeCPUs: 2, NodeMemory: "2G", NodeDisk: "10X", DNSServers: []string{"8.8.8.8"},
+	}
+
+	err := rqliteConfig.Validate()
+	want := "rqlite_raft_port must differ from rqlite_http_port, got 4001\n" +
+		"node_disk must be a size such as 512M or 8G, got \"10X\""
+	if err == nil || err.Error() != want {
+		t.Errorf("Validate() error = %v, want:\n%s", err, want)
+	}
+
+	rqliteConfig.RqliteRaftPort, rqliteConfig.NodeDisk = 4002, "10G"
+	if err := rqliteConfig.Validate(); err != nil {
+		t.Errorf("Validate() error = %v, want nil", err)
+	}
+}
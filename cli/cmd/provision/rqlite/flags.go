@@ -0,0 +1,48 @@
+package rqlite
+
+import "github.com/spf13/pflag"
+
+// addFlags registers one flag per Config field, storing the values in flags
+func addFlags(fs *pflag.FlagSet, flags *Config) {
+	fs.StringVar(&flags.RqliteVersion, "rqlite-version", "", "rqlite version (e.g. 8.36.11)")
+	fs.IntVar(&flags.RqliteHttpPort, "rqlite-http-port", 0, "rqlite HTTP API port")
+	fs.IntVar(&flags.RqliteRaftPort, "rqlite-raft-port", 0, "rqlite Raft port")
+	fs.StringVar(&flags.RqliteDataDir, "rqlite-data-dir", "", "Data directory on the nodes")
+	fs.StringVar(&flags.RqliteExtractDir, "rqlite-extract-dir", "", "Directory the rqlite release is extracted to")
+	fs.IntVar(&flags.NodeCPUs, "node-cpus", 0, "CPUs for each node VM")
+	fs.StringVar(&flags.NodeMemory, "node-memory", "", "Memory for each node VM (e.g. 2G)")
+	fs.StringVar(&flags.NodeDisk, "node-disk", "", "Disk for each node VM (e.g. 10G)")
+	fs.StringSliceVar(&flags.DNSServers, "dns-servers", nil, "DNS servers for the VMs")
+}
+
+// applyFlags copies the flags that were set explicitly from flags onto
+// rqliteConfig
+func applyFlags(fs *pflag.FlagSet, flags, rqliteConfig *Config) {
+	if fs.Changed("rqlite-version") {
+		rqliteConfig.RqliteVersion = flags.RqliteVersion
+	}
+	if fs.Changed("rqlite-http-port") {
+		rqliteConfig.RqliteHttpPort = flags.RqliteHttpPort
+	}
+	if fs.Changed("rqlite-raft-port") {
+		rqliteConfig.RqliteRaftPort = flags.RqliteRaftPort
+	}
+	if fs.Changed("rqlite-data-dir") {
+		rqliteConfig.RqliteDataDir = flags.RqliteDataDir
+	}
+	if fs.Changed("rqlite-extract-dir") {
+		rqliteConfig.RqliteExtractDir = flags.RqliteExtractDir
+	}
+	if fs.Changed("node-cpus") {
+		rqliteConfig.NodeCPUs = flags.NodeCPUs
+	}
+	if fs.Changed("node-memory") {
+		rqliteConfig.NodeMemory = flags.NodeMemory
+	}
+	if fs.Changed("node-disk") {
+		rqliteConfig.NodeDisk = flags.NodeDisk
+	}
+	if fs.Changed("dns-servers") {
+		rqliteConfig.DNSServers = flags.DNSServers
+	}
+}
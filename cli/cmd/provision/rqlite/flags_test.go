@@ -0,0 +1,34 @@
+package rqlite
+
+import (
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestApplyFlags(t *testing.T) {
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	var flags Config
+	addFlags(fs, &flags)
+
+	if err := fs.Parse([]string{"--rqlite-http-port", "5001", "--node-memory", "4G"}); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	rqliteConfig := &Config{
+		RqliteHttpPort: 4001,
+		RqliteRaftPort: 4002,
+		NodeMemory:     "2G",
+	}
+	applyFlags(fs, &flags, rqliteConfig)
+
+	if rqliteConfig.RqliteHttpPort != 5001 {
+		t.Errorf("RqliteHttpPort = %d, want 5001", rqliteConfig.RqliteHttpPort)
+	}
+	if rqliteConfig.NodeMemory != "4G" {
+		t.Errorf("NodeMemory = %q, want \"4G\"", rqliteConfig.NodeMemory)
+	}
+	if rqliteConfig.RqliteRaftPort != 4002 {
+		t.Errorf("RqliteRaftPort = %d, want it unchanged", rqliteConfig.RqliteRaftPort)
+	}
+}
@@ -0,0 +1,38 @@
+package rqlite
+
+import (
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/ansible"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/state"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/vm"
+)
+
+// buildInventory describes the cluster VMs as the rqlite_cluster group with
+// rqlite_leader and rqlite_followers children. The rqlite_nodes group var
+// lists every node, leader first.
+func buildInventory(leader vm.Instance, followers []vm.Instance, keyPath string) *ansible.Inventory {
+	inventory := ansible.NewInventory()
+	cluster := inventory.All().AddChild("rqlite_cluster")
+
+	leaderGroup := cluster.AddChild("rqlite_leader")
+	leaderGroup.AddHost(leader.Name, map[string]interface{}{"ansible_host": leader.IP()})
+
+	nodes := []string{leader.Name}
+	followersGroup := cluster.AddChild("rqlite_followers")
+	for _, follower := range followers {
+		followersGroup.AddHost(follower.Name, map[string]interface{}{"ansible_host": follower.IP()})
+		nodes = append(nodes, follower.Name)
+	}
+	cluster.SetVar("rqlite_nodes", nodes)
+
+	inventory.All().Vars = ansible.SSHVars(keyPath)
+	return inventory
+}
+
+// nodes lists the cluster VMs with their roles for the cluster state
+func nodes(leader vm.Instance, followers []vm.Instance) []state.Node {
+	nodes := []state.Node{{Name: leader.Name, Role: "leader", IP: leader.IP()}}
+	for _, follower := range followers {
+		nodes = append(nodes, state.Node{Name: follower.Name, Role: "follower", IP: follower.IP()})
+	}
+	return nodes
+}
@@ -0,0 +1,165 @@
+package rqlite
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/ansible"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/component"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/config"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/plan"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/state"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/vm"
+)
+
+// PlanProvision describes what Provision would do for rqliteConfig without
+// changing anything. Existing VMs are listed to tell which would be reused.
+func PlanProvision(rqliteConfig *Config) (*plan.Plan, error) {
+	if err := rqliteConfig.Validate(); err != nil {
+		return nil, err
+	}
+	if err := component.CheckNameFree(rqliteConfig.Name); err != nil {
+		return nil, err
+	}
+	if err := state.CheckNodesFree(rqliteConfig.clusterName(), rqliteConfig.VMNames()); err != nil {
+		return nil, err
+	}
+
+	p := &plan.Plan{Action: plan.Provision, Cluster: rqliteConfig.clusterName(), Component: ComponentName}
+
+	stateDir, err := config.GetStateDir(rqliteConfig.clusterName())
+	if err != nil {
+		return nil, err
+	}
+	keyPath, err := vm.DefaultSSHKeyPath()
+	if err != nil {
+		return nil, err
+	}
+
+	templatePath, err := config.GetMultipassPath("cloud-init/common.yaml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate cloud-init template: %w", err)
+	}
+	if p.CloudInit, err = vm.CloudInit(templatePath, vm.PlannedPublicKey(keyPath)); err != nil {
+		return nil, err
+	}
+
+	specs := launchOptions(rqliteConfig, "")
+	instances, launch, err := vm.PlanInstances(newProvider(), specs)
+	if err != nil {
+		p.Warnings = append(p.Warnings, fmt.Sprintf("%v, assuming no VMs exist", err))
+	}
+	if host, err := readHost(); err != nil {
+		p.Warnings = append(p.Warnings, fmt.Sprintf("%v, the capacity check is skipped", err))
+	} else if err := vm.CheckCapacity(host, specs, launch); err != nil {
+		p.Warnings = append(p.Warnings, err.Error())
+	}
+	leader, followers := instances[0], instances[1:]
+	p.VMs = plan.LaunchVMs(specs, nodes(leader, followers), launch)
+
+	inventory, err := buildInventory(leader, followers, keyPath).Marshal()
+	if err != nil {
+		return nil, err
+	}
+	p.Inventory = string(inventory)
+
+	playbookPath, err := config.GetAnsiblePath("playbooks/rqlite.yml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate playbook: %w", err)
+	}
+	inventoryPath := filepath.Join(stateDir, state.InventoryFile)
+	configPath := filepath.Join(stateDir, state.ConfigFile)
+	p.Commands = append(p.Commands, append([]string{"ansible-playbook"},
+		ansible.PlaybookArgs(playbookPath, inventoryPath, ansible.ExtraVarsFile(configPath))...))
+
+	p.Steps = append(p.Steps, fmt.Sprintf("Record the cluster, its config and inventory in %s", stateDir))
+	return p, nil
+}
+
+// PlanCleanup describes what Cleanup would do for rqliteConfig without
+// changing anything
+func PlanCleanup(rqliteConfig *Config) (*plan.Plan, error) {
+	p := &plan.Plan{Action: plan.Cleanup, Cluster: rqliteConfig.clusterName(), Component: ComponentName}
+
+	names, err := nodeNames(rqliteConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the VMs that exist are deleted, but all of them are listed when
+	// that cannot be told
+	existing := make(map[string]vm.Instance)
+	instances, err := newProvider().List()
+	if err != nil {
+		p.Warnings = append(p.Warnings, fmt.Sprintf("failed to list VMs: %v, assuming all of them exist", err))
+	}
+	for _, instance := range instances {
+		existing[instance.Name] = instance
+	}
+
+	for i, name := range names {
+		instance, ok := existing[name]
+		if !ok && err == nil {
+			continue
+		}
+		planned := plan.VM{Name: name, Role: "follower", Action: plan.Delete, CPUs: instance.CPUs, IP: instance.IP()}
+		if i == 0 {
+			planned.Role = "leader"
+		}
+		p.VMs = append(p.VMs, planned)
+	}
+
+	stateDir, err := config.GetStateDir(rqliteConfig.clusterName())
+	if err != nil {
+		return nil, err
+	}
+	p.Steps = append(p.Steps, fmt.Sprintf("Remove the state directory %s", stateDir))
+	return p, nil
+}
+
+// PlanTearDown describes what TearDown would do for rqliteConfig without
+// changing anything
+func PlanTearDown(rqliteConfig *Config) (*plan.Plan, error) {
+	p := &plan.Plan{Action: plan.Cleanup, Cluster: rqliteConfig.clusterName(), Component: ComponentName}
+
+	target, err := findTeardownTarget(rqliteConfig)
+	if err != nil {
+		p.Warnings = append(p.Warnings, fmt.Sprintf("%v, the teardown cannot be planned", err))
+		return p, nil
+	}
+	if target.leader == nil {
+		p.Warnings = append(p.Warnings, fmt.Sprintf("leader %s is not running, teardown is skipped", target.leaderName))
+		return p, nil
+	}
+
+	inventory, err := buildInventory(*target.leader, target.followers, target.keyPath).Marshal()
+	if err != nil {
+		return nil, err
+	}
+	p.Inventory = string(inventory)
+
+	playbookPath, err := config.GetAnsiblePath("playbooks/rqlite-teardown.yml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate teardown playbook: %w", err)
+	}
+	inventoryPath := target.cluster.Path(state.InventoryFile)
+	configPath := target.cluster.Path(state.ConfigFile)
+	p.Commands = append(p.Commands, append([]string{"ansible-playbook"},
+		ansible.PlaybookArgs(playbookPath, inventoryPath, ansible.ExtraVarsFile(configPath))...))
+	return p, nil
+}
+
+// Preflight checks that the host has the CPUs, memory and disk for the VMs
+// Provision would launch. It returns a *vm.CapacityError when they do not
+// fit, and an error wrapping vm.ErrCapacityUnknown when that cannot be told.
+func Preflight(rqliteConfig *Config) error {
+	specs := launchOptions(rqliteConfig, "")
+	// A failure to list the VMs is reported by Provision itself
+	_, launch, _ := vm.PlanInstances(newProvider(), specs)
+
+	host, err := readHost()
+	if err != nil {
+		return err
+	}
+	return vm.CheckCapacity(host, specs, launch)
+}
@@ -0,0 +1,117 @@
+package rqlite
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/plan"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/state"
+)
+
+func TestPlanProvision(t *testing.T) {
+	provider, extraVars := setupProvision(t)
+	leaderIP := provider.AddInstance("db-rqlite1")
+
+	rqliteConfig := withDefaults(&Config{NodeCPUs: 2, NodeMemory: "2G", NodeDisk: "10G"})
+	rqliteConfig.SetName("db")
+	p, err := PlanProvision(rqliteConfig)
+	if err != nil {
+		t.Fatalf("PlanProvision() error = %v", err)
+	}
+
+	// Nothing is launched, run or recorded
+	if len(provider.CallsTo("launch")) != 0 || len(*extraVars) != 0 {
+		t.Errorf("PlanProvision() launched VMs or ran the playbook")
+	}
+	if _, err := state.Load("db"); !errors.Is(err, state.ErrNotFound) {
+		t.Errorf("state.Load() error = %v, want the cluster not recorded", err)
+	}
+
+	want := []plan.VM{
+		{Name: "db-rqlite1", Role: "leader", Action: plan.Reuse, CPUs: 2, Memory: "2G", Disk: "10G", IP: leaderIP},
+		{Name: "db-rqlite2", Role: "follower", Action: plan.Launch, CPUs: 2, Memory: "2G", Disk: "10G"},
+		{Name: "db-rqlite3", Role: "follower", Action: plan.Launch, CPUs: 2, Memory: "2G", Disk: "10G"},
+	}
+	if len(p.VMs) != len(want) {
+		t.Fatalf("VMs = %+v, want %+v", p.VMs, want)
+	}
+	for i := range want {
+		if p.VMs[i] != want[i] {
+			t.Errorf("VMs[%d] = %+v, want %+v", i, p.VMs[i], want[i])
+		}
+	}
+
+	if !strings.Contains(p.Inventory, "ansible_host: "+leaderIP) || !strings.Contains(p.Inventory, "<db-rqlite2 IP>") {
+		t.Errorf("inventory does not contain the known and planned addresses:\n%s", p.Inventory)
+	}
+	if len(p.Commands) != 1 || !strings.HasSuffix(p.Commands[0][len(p.Commands[0])-1], "rqlite.yml") {
+		t.Errorf("commands = %v, want the rqlite playbook run", p.Commands)
+	}
+}
+
+func TestPlanCleanup(t *testing.T) {
+	provider, _ := setupProvision(t)
+	provider.AddInstance("rqlite1")
+	provider.AddInstance("rqlite3")
+
+	p, err := PlanCleanup(&Config{})
+	if err != nil {
+		t.Fatalf("PlanCleanup() error = %v", err)
+	}
+	if len(provider.CallsTo("delete")) != 0 || len(provider.Names()) != 2 {
+		t.Errorf("PlanCleanup() deleted VMs")
+	}
+
+	var names []string
+	for _, planned := range p.VMs {
+		names = append(names, planned.Name)
+	}
+	if strings.Join(names, ",") != "rqlite1,rqlite3" {
+		t.Errorf("VMs to delete = %v, want the existing rqlite1,rqlite3", names)
+	}
+
+	// Without a VM list every node is assumed to exist
+	provider.FailOn("list", "", errors.New("multipass is not running"))
+	if p, err = PlanCleanup(&Config{}); err != nil {
+		t.Fatalf("PlanCleanup() error = %v", err)
+	}
+	if len(p.VMs) != 3 || len(p.Warnings) != 1 {
+		t.Errorf("PlanCleanup() = %+v, want all nodes and a warning", p)
+	}
+}
+
+func TestPlanTearDown(t *testing.T) {
+	provider, extraVars := setupProvision(t)
+
+	p, err := PlanTearDown(&Config{})
+	if err != nil {
+		t.Fatalf("PlanTearDown() error = %v", err)
+	}
+	if len(p.Commands) != 0 || len(p.Warnings) != 1 || !strings.Contains(p.Warnings[0], "rqlite1") {
+		t.Errorf("PlanTearDown() = %+v, want teardown skipped as the leader is not running", p)
+	}
+
+	provider.AddInstance("rqlite1")
+	provider.AddInstance("rqlite2")
+	if p, err = PlanTearDown(&Config{}); err != nil {
+		t.Fatalf("PlanTearDown() error = %v", err)
+	}
+	if len(*extraVars) != 0 {
+		t.Errorf("PlanTearDown() ran the playbook")
+	}
+	if len(p.Commands) != 1 || !strings.HasSuffix(p.Commands[0][len(p.Commands[0])-1], "rqlite-teardown.yml") {
+		t.Errorf("commands = %v, want the teardown playbook run", p.Commands)
+	}
+	if !strings.Contains(p.Inventory, "rqlite2") || strings.Contains(p.Inventory, "rqlite3") {
+		t.Errorf("inventory does not list only the running nodes:\n%s", p.Inventory)
+	}
+
+	provider.FailOn("list", "", errors.New("multipass is not running"))
+	if p, err = PlanTearDown(&Config{}); err != nil {
+		t.Fatalf("PlanTearDown() error = %v", err)
+	}
+	if len(p.Commands) != 0 || len(p.Warnings) != 1 {
+		t.Errorf("PlanTearDown() = %+v, want a warning without a VM list", p)
+	}
+}
@@ -1,219 +1,374 @@
 package rqlite
 
 import (
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
-	"path/filepath"
+	"strings"
 
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/ansible"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/component"
 	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/config"
 	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/interactive"
-	"gopkg.in/yaml.v3"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/state"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/vm"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/vm/multipass"
 )
 
 // Config holds rqlite cluster configuration
 type Config struct {
-	RqliteVersion    string   `yaml:"rqlite_version"`
-	RqliteHttpPort   int      `yaml:"rqlite_http_port"`
-	RqliteRaftPort   int      `yaml:"rqlite_raft_port"`
-	RqliteDataDir    string   `yaml:"rqlite_data_dir"`
-	RqliteExtractDir string   `yaml:"rqlite_extract_dir"`
-	NodeCPUs         int      `yaml:"node_cpus"`
-	NodeMemory       string   `yaml:"node_memory"`
-	NodeDisk         string   `yaml:"node_disk"`
-	DNSServers       []string `yaml:"dns_servers"`
+	Name             string   `yaml:"cluster_name,omitempty"`
+	RqliteVersion    string   `yaml:"rqlite_version" prompt:"rqlite Version"`
+	RqliteHttpPort   int      `yaml:"rqlite_http_port" prompt:"HTTP Port"`
+	RqliteRaftPort   int      `yaml:"rqlite_raft_port" prompt:"Raft Port"`
+	RqliteDataDir    string   `yaml:"rqlite_data_dir" prompt:"Data Directory"`
+	RqliteExtractDir string   `yaml:"rqlite_extract_dir" prompt:"Install Directory"`
+	NodeCPUs         int      `yaml:"node_cpus" prompt:"Node CPUs"`
+	NodeMemory       string   `yaml:"node_memory" prompt:"Node Memory (e.g., 2G)"`
+	NodeDisk         string   `yaml:"node_disk" prompt:"Node Disk (e.g., 10G)"`
+	DNSServers       []string `yaml:"dns_servers" prompt:"DNS Servers (comma-separated)"`
 }
 
-// LoadDefaultConfig loads the default rqlite configuration from the defaults file
+// ComponentName identifies the component in the registry, the defaults
+// file and the recorded state of its clusters
+const ComponentName = "rqlite"
+
+// ClusterName names the default cluster, the one provisioned without a name
+const ClusterName = ComponentName
+
+// NodeNames are the VM names of the default cluster's nodes, leader first.
+// The nodes of a named cluster carry the cluster name as a prefix.
+var NodeNames = []string{"rqlite1", "rqlite2", "rqlite3"}
+
+// newProvider, runPlaybook and readHost are variables so tests can replace
+// the VMs, external commands and host and inspect how they are used
+var (
+	newProvider = func() vm.Provider { return multipass.New() }
+	runPlaybook = ansible.RunPlaybookWithLog
+	readHost    = func() (vm.Host, error) { return vm.ReadHost(multipass.StoragePath()) }
+)
+
+// BuiltinConfig returns the settings used where ansible/defaults/rqlite.yml
+// does not set them, e.g. in an older checkout given with --assets-dir
+func BuiltinConfig() *Config {
+	return &Config{
+		RqliteVersion:    "8.36.11",
+		RqliteHttpPort:   4001,
+		RqliteRaftPort:   4002,
+		RqliteDataDir:    "/home/{{ ansible_user }}/data",
+		RqliteExtractDir: "/opt/rqlite",
+		NodeCPUs:         2,
+		NodeMemory:       "2G",
+		NodeDisk:         "10G",
+		DNSServers:       []string{"8.8.8.8", "8.8.4.4"},
+	}
+}
+
+// LoadDefaultConfig loads the default rqlite configuration from the defaults
+// file on top of the built-in one
 func LoadDefaultConfig() (*Config, error) {
-	defaultsPath, err := config.GetAnsiblePath("defaults/rqlite.yml")
+	defaults := BuiltinConfig()
+	if _, err := config.Load(defaults, config.DefaultsLayer(ComponentName)); err != nil {
+		return nil, err
+	}
+	return defaults, nil
+}
+
+// SetName makes the config describe the named cluster
+func (c *Config) SetName(name string) {
+	c.Name = name
+}
+
+// clusterName returns the name the cluster is recorded under
+func (c *Config) clusterName() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return ClusterName
+}
+
+// VMNames returns the VM names of the cluster nodes, leader first
+func (c *Config) VMNames() []string {
+	if c.Name == "" {
+		return NodeNames
+	}
+
+	names := make([]string, 0, len(NodeNames))
+	for _, name := range NodeNames {
+		names = append(names, component.VMPrefix(c.Name)+name)
+	}
+	return names
+}
+
+// PrintConfig displays the settings that will be used for provisioning
+func PrintConfig(config *Config) {
+	if config.Name != "" {
+		fmt.Printf("Cluster Name: %s\n", config.Name)
+	}
+	fmt.Printf("rqlite Version: %s\n", config.RqliteVersion)
+	fmt.Printf("HTTP Port: %d\n", config.RqliteHttpPort)
+	fmt.Printf("Raft Port: %d\n", config.RqliteRaftPort)
+	fmt.Printf("Node Resources: %d CPUs, %s Memory, %s Disk\n",
+		config.NodeCPUs,
+		config.NodeMemory,
+		config.NodeDisk)
+}
+
+// PromptConfig asks whether to keep the settings in rqliteConfig and, if
+// not, prompts for every setting, rejecting invalid values as they are
+// entered
+func PromptConfig(rqliteConfig *Config) error {
+	useDefaults, err := interactive.PromptConfirm("Do you want to use these default settings?")
 	if err != nil {
-		return nil, fmt.Errorf("failed to locate defaults file: %w\n"+
-			"Please make sure you are running the command from within the repository and that the ansible/defaults/rqlite.yml file exists", err)
+		return err
+	}
+	if useDefaults {
+		return nil
 	}
 
-	data, err := os.ReadFile(defaultsPath)
+	return component.PromptForm(rqliteConfig, rqliteConfig.formFields(), rqliteConfig.Validate)
+}
+
+// Provision creates the rqlite cluster described by rqliteConfig without
+// asking any questions. The config, nodes, inventory and playbook output are
+// recorded in the cluster's state directory.
+func Provision(rqliteConfig *Config) (err error) {
+	if err := rqliteConfig.Validate(); err != nil {
+		return err
+	}
+	if err := component.CheckNameFree(rqliteConfig.Name); err != nil {
+		return err
+	}
+	if err := state.CheckNodesFree(rqliteConfig.clusterName(), rqliteConfig.VMNames()); err != nil {
+		return err
+	}
+
+	cluster, err := state.Begin(rqliteConfig.clusterName(), ComponentName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read defaults file at %s: %w\n"+
-			"Please check file permissions and that the repository is correctly cloned", defaultsPath, err)
+		return err
 	}
+	defer func() { err = cluster.Finish(err) }()
 
-	config := &Config{}
-	err = yaml.Unmarshal(data, config)
+	// The recorded config is passed to the playbook as extra vars so the
+	// user's values override ansible/defaults/rqlite.yml
+	configPath, err := cluster.SaveConfig(rqliteConfig)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse defaults file: %w", err)
+		return err
 	}
 
-	return config, nil
-}
+	keyPath, err := vm.DefaultSSHKeyPath()
+	if err != nil {
+		return err
+	}
+	cluster.SSHKey = keyPath
 
-// SaveConfig saves the rqlite configuration to a temporary file
-func SaveConfig(config *Config) (string, error) {
-	data, err := yaml.Marshal(config)
+	templatePath, err := config.GetMultipassPath("cloud-init/common.yaml")
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal config: %w", err)
+		return fmt.Errorf("failed to locate cloud-init template: %w", err)
 	}
 
-	// Create a temporary directory
-	tmpDir, err := os.MkdirTemp("", "rqlite-provision")
+	cloudInitPath, err := vm.PrepareCloudInit(templatePath, keyPath)
 	if err != nil {
-		return "", fmt.Errorf("failed to create temp dir: %w", err)
+		return err
 	}
+	defer os.Remove(cloudInitPath)
 
-	// Write config to a file
-	configPath := filepath.Join(tmpDir, "rqlite.yml")
-	err = os.WriteFile(configPath, data, 0o644)
+	// Create the VMs that don't exist yet
+	fmt.Println("Checking for existing VMs...")
+	instances, err := vm.EnsureInstances(newProvider(), launchOptions(rqliteConfig, cloudInitPath))
 	if err != nil {
-		return "", fmt.Errorf("failed to write config file: %w", err)
+		return err
 	}
 
-	return configPath, nil
-}
+	leader, followers := instances[0], instances[1:]
+	cluster.Nodes = nodes(leader, followers)
+	if err := cluster.Save(); err != nil {
+		return err
+	}
 
-// ProvisionInteractive handles interactive rqlite cluster provisioning
-func ProvisionInteractive() error {
-	// Load default configuration
-	defaultConfig, err := LoadDefaultConfig()
-	if err != nil {
-		// If the file doesn't exist, use hardcoded defaults
-		defaultConfig = &Config{
-			RqliteVersion:    "8.36.11",
-			RqliteHttpPort:   4001,
-			RqliteRaftPort:   4002,
-			RqliteDataDir:    "/home/ubuntu/data",
-			RqliteExtractDir: "/opt/rqlite",
-			NodeCPUs:         2,
-			NodeMemory:       "2G",
-			NodeDisk:         "10G",
-			DNSServers:       []string{"8.8.8.8", "8.8.4.4"},
-		}
+	inventoryPath := cluster.Path(state.InventoryFile)
+	if err := buildInventory(leader, followers, keyPath).WriteFile(inventoryPath); err != nil {
+		return err
 	}
+	fmt.Printf("✓ Ansible inventory created at %s\n", inventoryPath)
 
-	// Display default settings
-	fmt.Println("\nCurrent default settings:")
-	fmt.Printf("rqlite Version: %s\n", defaultConfig.RqliteVersion)
-	fmt.Printf("HTTP Port: %d\n", defaultConfig.RqliteHttpPort)
-	fmt.Printf("Raft Port: %d\n", defaultConfig.RqliteRaftPort)
-	fmt.Printf("Node Resources: %d CPUs, %s Memory, %s Disk\n",
-		defaultConfig.NodeCPUs,
-		defaultConfig.NodeMemory,
-		defaultConfig.NodeDisk)
+	playbookPath, err := config.GetAnsiblePath("playbooks/rqlite.yml")
+	if err != nil {
+		return fmt.Errorf("failed to locate playbook: %w", err)
+	}
 
-	// Ask if user wants to use default settings
-	useDefaults, err := interactive.PromptConfirm("Do you want to use these default settings?")
+	log, err := cluster.OpenLog("provision")
 	if err != nil {
 		return err
 	}
+	defer log.Close()
 
-	var config *Config
-	if useDefaults {
-		config = defaultConfig
-	} else {
-		config = defaultConfig // Start with defaults
+	fmt.Println("Running Ansible playbook for rqlite deployment...")
+	if err := runPlaybook(playbookPath, inventoryPath, ansible.ExtraVarsFile(configPath), log); err != nil {
+		return fmt.Errorf("%w (see %s)", err, cluster.Path(state.LogFile))
+	}
 
-		// Ask for each configurable value
-		rqliteVersion, err := interactive.PromptText("rqlite Version", config.RqliteVersion)
-		if err != nil {
-			return err
-		}
-		config.RqliteVersion = rqliteVersion
+	fmt.Println("✓ rqlite environment setup complete.")
+	fmt.Printf("Leader Node: %s:%d (HTTP) / %s:%d (Raft)\n",
+		leader.IP(), rqliteConfig.RqliteHttpPort, leader.IP(), rqliteConfig.RqliteRaftPort)
+	fmt.Println("\nTo access the rqlite cluster, run:")
+	fmt.Printf("rqlite -H %s -p %d\n", leader.IP(), rqliteConfig.RqliteHttpPort)
+	return nil
+}
 
-		rqliteHttpPort, err := interactive.PromptInt("HTTP Port", config.RqliteHttpPort)
-		if err != nil {
-			return err
-		}
-		config.RqliteHttpPort = rqliteHttpPort
+// launchOptions describes the node VMs, leader first
+func launchOptions(rqliteConfig *Config, cloudInitPath string) []vm.LaunchOptions {
+	names := rqliteConfig.VMNames()
+	specs := make([]vm.LaunchOptions, 0, len(names))
+	for _, name := range names {
+		specs = append(specs, vm.LaunchOptions{
+			Name:      name,
+			CPUs:      rqliteConfig.NodeCPUs,
+			Memory:    rqliteConfig.NodeMemory,
+			Disk:      rqliteConfig.NodeDisk,
+			CloudInit: cloudInitPath,
+		})
+	}
+	return specs
+}
 
-		rqliteRaftPort, err := interactive.PromptInt("Raft Port", config.RqliteRaftPort)
-		if err != nil {
-			return err
-		}
-		config.RqliteRaftPort = rqliteRaftPort
+// Cleanup deletes the cluster VMs and the recorded state
+func Cleanup(rqliteConfig *Config) error {
+	provider := newProvider()
 
-		// Node resources
-		nodeCPUs, err := interactive.PromptIntWithRange("Node CPUs", config.NodeCPUs, 1, 16)
-		if err != nil {
-			return err
-		}
-		config.NodeCPUs = nodeCPUs
+	names, err := nodeNames(rqliteConfig)
+	if err != nil {
+		return err
+	}
 
-		nodeMemory, err := interactive.PromptText("Node Memory (e.g., 2G)", config.NodeMemory)
-		if err != nil {
-			return err
+	fmt.Printf("Deleting rqlite VMs: %s\n", strings.Join(names, ", "))
+	for _, name := range names {
+		err := provider.Delete(name)
+		if errors.Is(err, vm.ErrNotFound) {
+			fmt.Printf("VM '%s' does not exist, skipping\n", name)
+			continue
 		}
-		config.NodeMemory = nodeMemory
-
-		nodeDisk, err := interactive.PromptText("Node Disk (e.g., 10G)", config.NodeDisk)
 		if err != nil {
-			return err
+			return fmt.Errorf("failed to delete VM %s: %w", name, err)
 		}
-		config.NodeDisk = nodeDisk
 	}
+	fmt.Println("✓ VMs deleted")
 
-	// Save configuration to a temporary file
-	configPath, err := SaveConfig(config)
-	if err != nil {
+	if err := state.Remove(rqliteConfig.clusterName()); err != nil {
 		return err
 	}
-	defer os.RemoveAll(filepath.Dir(configPath)) // Clean up temp dir when done
+	fmt.Println("✓ Cluster state removed")
+	return nil
+}
 
-	// Confirm before proceeding
-	fmt.Println("\nReady to provision rqlite cluster with the selected settings.")
-	proceed, err := interactive.PromptConfirm("Do you want to proceed?")
+// TearDown runs the teardown playbook against the running nodes, which
+// removes the followers from the Raft cluster and uninstalls rqlite. The
+// inventory is rewritten first since the VMs may have new addresses.
+func TearDown(rqliteConfig *Config) error {
+	target, err := findTeardownTarget(rqliteConfig)
 	if err != nil {
 		return err
 	}
-
-	if !proceed {
-		fmt.Println("Provisioning cancelled.")
+	if target.leader == nil {
+		fmt.Printf("Leader %s is not running, skipping teardown\n", target.leaderName)
 		return nil
 	}
 
-	// Run the provision script
-	fmt.Println("Running provisioning script...")
-	return runProvisionScript(configPath)
-}
+	cluster := target.cluster
+	inventoryPath := cluster.Path(state.InventoryFile)
+	if err := buildInventory(*target.leader, target.followers, target.keyPath).WriteFile(inventoryPath); err != nil {
+		return err
+	}
 
-// runProvisionScript runs the actual rqlite provisioning
-func runProvisionScript(configPath string) error {
-	// Get path to provision script
-	scriptPath, err := config.GetScriptsPath("provision-rqlite.sh")
+	configPath, err := cluster.SaveConfig(rqliteConfig)
 	if err != nil {
-		return fmt.Errorf("failed to locate provision script: %w", err)
+		return err
 	}
 
-	// Check if script exists
-	if _, err := os.Stat(scriptPath); os.IsNotExist(err) {
-		return fmt.Errorf("provision script does not exist: %s", scriptPath)
+	playbookPath, err := config.GetAnsiblePath("playbooks/rqlite-teardown.yml")
+	if err != nil {
+		return fmt.Errorf("failed to locate teardown playbook: %w", err)
 	}
 
-	// Run the provision script with the config file
-	cmd := exec.Command(scriptPath)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin // Pass stdin for any prompts
+	log, err := cluster.OpenLog("teardown")
+	if err != nil {
+		return err
+	}
+	defer log.Close()
 
-	return cmd.Run()
+	fmt.Println("Running Ansible playbook for rqlite teardown...")
+	if err := runPlaybook(playbookPath, inventoryPath, ansible.ExtraVarsFile(configPath), log); err != nil {
+		return fmt.Errorf("failed to tear down rqlite: %w", err)
+	}
+	fmt.Println("✓ rqlite removed from the cluster nodes")
+	return nil
 }
 
-// Cleanup handles rqlite cluster cleanup
-func Cleanup() error {
-	// Get path to cleanup script
-	scriptPath, err := config.GetScriptsPath("cleanup-rqlite.sh")
+// teardownTarget is the cluster and running nodes TearDown acts on
+type teardownTarget struct {
+	cluster    *state.Cluster
+	leaderName string
+	// leader is nil when the leader is not running
+	leader    *vm.Instance
+	followers []vm.Instance
+	keyPath   string
+}
+
+// findTeardownTarget looks up the running nodes of the cluster, using the
+// recorded nodes and SSH key when there are any
+func findTeardownTarget(rqliteConfig *Config) (*teardownTarget, error) {
+	cluster, err := state.LoadOrNew(rqliteConfig.clusterName(), ComponentName)
+	if err != nil {
+		return nil, err
+	}
+
+	names := rqliteConfig.VMNames()
+	if len(cluster.Nodes) > 0 {
+		names = cluster.NodeNames()
+	}
+
+	instances, err := newProvider().List()
 	if err != nil {
-		return fmt.Errorf("failed to locate cleanup script: %w", err)
+		return nil, fmt.Errorf("failed to list VMs: %w", err)
 	}
 
-	// Check if script exists
-	if _, err := os.Stat(scriptPath); os.IsNotExist(err) {
-		return fmt.Errorf("cleanup script does not exist: %s", scriptPath)
+	running := make(map[string]vm.Instance, len(instances))
+	for _, instance := range instances {
+		if instance.Running() && instance.IP() != "" {
+			running[instance.Name] = instance
+		}
 	}
 
-	// Run the cleanup script
-	cmd := exec.Command(scriptPath)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin // Pass stdin for any prompts
+	target := &teardownTarget{cluster: cluster, leaderName: names[0], keyPath: cluster.SSHKey}
+	if leader, ok := running[names[0]]; ok {
+		target.leader = &leader
+	}
+	for _, name := range names[1:] {
+		if follower, ok := running[name]; ok {
+			target.followers = append(target.followers, follower)
+		}
+	}
 
-	return cmd.Run()
+	if target.keyPath == "" {
+		if target.keyPath, err = vm.DefaultSSHKeyPath(); err != nil {
+			return nil, err
+		}
+	}
+	return target, nil
+}
+
+// nodeNames returns the recorded nodes of the cluster, leader first, or
+// the VM names from the config when the cluster has no recorded state
+func nodeNames(rqliteConfig *Config) ([]string, error) {
+	cluster, err := state.Load(rqliteConfig.clusterName())
+	if errors.Is(err, state.ErrNotFound) {
+		return rqliteConfig.VMNames(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(cluster.Nodes) == 0 {
+		return rqliteConfig.VMNames(), nil
+	}
+	return cluster.NodeNames(), nil
 }
@@ -0,0 +1,329 @@
+package rqlite
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/component"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/config"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/state"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/vm"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/vm/fake"
+	"gopkg.in/yaml.v3"
+)
+
+// withDefaults fills in the settings config leaves empty from the built-in
+// defaults, so it passes Validate
+func withDefaults(config *Config) *Config {
+	v, builtin := reflect.ValueOf(config).Elem(), reflect.ValueOf(BuiltinConfig()).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if field := v.Field(i); field.IsZero() {
+			field.Set(builtin.Field(i))
+		}
+	}
+	return config
+}
+
+// setupProvision creates a fake repository with a cloud-init template, an
+// existing SSH key and a fake VM provider, and captures the extra vars
+// passed to the playbook
+func setupProvision(t *testing.T) (*fake.Provider, *map[string]interface{}) {
+	t.Helper()
+
+	repoRoot := t.TempDir()
+	for _, dir := range []string{"ansible", "scripts", "multipass/cloud-init"} {
+		if err := os.MkdirAll(filepath.Join(repoRoot, dir), 0o755); err != nil {
+			t.Fatalf("Failed to create %s dir: %v", dir, err)
+		}
+	}
+	cloudInit := "ssh_authorized_keys:\n  - $SSH_PUBLIC_KEY\n"
+	if err := os.WriteFile(filepath.Join(repoRoot, "multipass/cloud-init/common.yaml"), []byte(cloudInit), 0o644); err != nil {
+		t.Fatalf("Failed to write cloud-init template: %v", err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	if err := os.Chdir(repoRoot); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	keyPath := filepath.Join(home, ".ssh", "id_rsa_provisioning")
+	if err := os.MkdirAll(filepath.Dir(keyPath), 0o700); err != nil {
+		t.Fatalf("Failed to create .ssh dir: %v", err)
+	}
+	for path, content := range map[string]string{keyPath: "private", keyPath + ".pub": "ssh-rsa AAAA test"} {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write key: %v", err)
+		}
+	}
+
+	origProvider, origPlaybook, origHost, origDelay := newProvider, runPlaybook, readHost, vm.InitDelay
+	t.Cleanup(func() {
+		newProvider, runPlaybook, readHost, vm.InitDelay = origProvider, origPlaybook, origHost, origDelay
+	})
+
+	readHost = func() (vm.Host, error) {
+		return vm.Host{CPUs: 64, Memory: 256 << 30, FreeDisk: 1 << 40, StoragePath: "/var/snap/multipass"}, nil
+	}
+
+	vm.InitDelay = 0
+	provider := fake.New()
+	newProvider = func() vm.Provider { return provider }
+
+	extraVars := new(map[string]interface{})
+	runPlaybook = func(playbookPath, inventory string, extraArgs []string, log io.Writer) error {
+		fmt.Fprintln(log, "PLAY RECAP")
+		if len(extraArgs) != 2 || extraArgs[0] != "-e" {
+			return errors.New("playbook was not invoked with -e @<config file>")
+		}
+		data, err := os.ReadFile(strings.TrimPrefix(extraArgs[1], "@"))
+		if err != nil {
+			return err
+		}
+		return yaml.Unmarshal(data, extraVars)
+	}
+
+	return provider, extraVars
+}
+
+// The built-in config only fills in settings the defaults file lacks, so it
+// must not drift from the file
+func TestBuiltinConfigMatchesDefaultsFile(t *testing.T) {
+	defaults, err := LoadDefaultConfig()
+	if err != nil {
+		t.Fatalf("LoadDefaultConfig() error = %v", err)
+	}
+	if builtin := BuiltinConfig(); !reflect.DeepEqual(builtin, defaults) {
+		t.Errorf("BuiltinConfig() = %+v, want the values of ansible/defaults/%s.yml %+v", builtin, ComponentName, defaults)
+	}
+}
+
+func TestProvision(t *testing.T) {
+	provider, extraVars := setupProvision(t)
+
+	if err := Provision(withDefaults(&Config{RqliteVersion: "8.36.11", RqliteHttpPort: 5001, NodeCPUs: 2})); err != nil {
+		t.Fatalf("Provision() error = %v", err)
+	}
+
+	if got := provider.Names(); strings.Join(got, ",") != strings.Join(NodeNames, ",") {
+		t.Errorf("VMs = %v, want %v", got, NodeNames)
+	}
+	stateDir, err := config.GetStateDir(ClusterName)
+	if err != nil {
+		t.Fatalf("GetStateDir() error = %v", err)
+	}
+	inventory, err := os.ReadFile(filepath.Join(stateDir, "inventory.yml"))
+	if err != nil {
+		t.Fatalf("inventory not written: %v", err)
+	}
+	var parsed struct {
+		All struct {
+			Children struct {
+				Cluster struct {
+					Children map[string]struct {
+						Hosts map[string]interface{} `yaml:"hosts"`
+					} `yaml:"children"`
+					Vars struct {
+						Nodes []string `yaml:"rqlite_nodes"`
+					} `yaml:"vars"`
+				} `yaml:"rqlite_cluster"`
+			} `yaml:"children"`
+		} `yaml:"all"`
+	}
+	if err := yaml.Unmarshal(inventory, &parsed); err != nil {
+		t.Fatalf("inventory is not valid YAML: %v", err)
+	}
+	cluster := parsed.All.Children.Cluster
+	if _, ok := cluster.Children["rqlite_leader"].Hosts["rqlite1"]; !ok || len(cluster.Children["rqlite_followers"].Hosts) != 2 {
+		t.Errorf("inventory does not have rqlite1 as leader and two followers:\n%s", inventory)
+	}
+	if strings.Join(cluster.Vars.Nodes, ",") != "rqlite1,rqlite2,rqlite3" {
+		t.Errorf("rqlite_nodes = %v, want [rqlite1 rqlite2 rqlite3]", cluster.Vars.Nodes)
+	}
+
+	if (*extraVars)["rqlite_http_port"] != 5001 {
+		t.Errorf("rqlite_http_port extra var = %v, want 5001", (*extraVars)["rqlite_http_port"])
+	}
+
+	recorded, err := state.Load(ClusterName)
+	if err != nil {
+		t.Fatalf("state.Load() error = %v", err)
+	}
+	if recorded.Status != state.StatusReady || recorded.Component != "rqlite" {
+		t.Errorf("recorded cluster = %+v, want a ready rqlite cluster", recorded)
+	}
+	if roles := []string{recorded.Nodes[0].Role, recorded.Nodes[1].Role, recorded.Nodes[2].Role}; strings.Join(roles, ",") != "leader,follower,follower" {
+		t.Errorf("recorded roles = %v, want leader first", roles)
+	}
+}
+
+func TestProvisionNamedCluster(t *testing.T) {
+	provider, _ := setupProvision(t)
+
+	if err := Provision(withDefaults(&Config{})); err != nil {
+		t.Fatalf("Provision() error = %v", err)
+	}
+	named := withDefaults(&Config{})
+	named.SetName("db-8.36")
+	if err := Provision(named); err != nil {
+		t.Fatalf("Provision(db-8.36) error = %v", err)
+	}
+
+	want := "rqlite1,rqlite2,rqlite3,db-8-36-rqlite1,db-8-36-rqlite2,db-8-36-rqlite3"
+	if names := provider.Names(); strings.Join(names, ",") != want {
+		t.Errorf("VMs = %v, want %s", names, want)
+	}
+	recorded, err := state.Load("db-8.36")
+	if err != nil {
+		t.Fatalf("state.Load() error = %v", err)
+	}
+	inventory, err := os.ReadFile(recorded.Path(state.InventoryFile))
+	if err != nil {
+		t.Fatalf("inventory not written: %v", err)
+	}
+	if !strings.Contains(string(inventory), "- db-8-36-rqlite1") {
+		t.Errorf("rqlite_nodes does not list the prefixed nodes:\n%s", inventory)
+	}
+
+	// Cleaning up the named cluster leaves the default one alone
+	if err := Cleanup(named); err != nil {
+		t.Fatalf("Cleanup() error = %v", err)
+	}
+	if names := provider.Names(); strings.Join(names, ",") != "rqlite1,rqlite2,rqlite3" {
+		t.Errorf("VMs after cleanup = %v, want the default cluster", names)
+	}
+	if _, err := state.Load(ClusterName); err != nil {
+		t.Errorf("state of the default cluster after cleanup: %v", err)
+	}
+}
+
+func TestProvisionListFailure(t *testing.T) {
+	provider, _ := setupProvision(t)
+	provider.FailOn("list", "", errors.New("multipass is not running"))
+
+	err := Provision(withDefaults(&Config{RqliteVersion: "8.36.11"}))
+	if err == nil || !strings.Contains(err.Error(), "multipass is not running") {
+		t.Errorf("Provision() error = %v, want list failure", err)
+	}
+	if len(provider.CallsTo("launch")) != 0 {
+		t.Errorf("VMs launched after a failed list")
+	}
+}
+
+func TestProvisionRejectsInvalidSettings(t *testing.T) {
+	provider, _ := setupProvision(t)
+
+	rqliteConfig := withDefaults(&Config{NodeMemory: "2X"})
+	rqliteConfig.SetName("db/dev")
+	err := Provision(rqliteConfig)
+	var validationErr *component.ValidationError
+	if !errors.As(err, &validationErr) || !validationErr.Has("cluster_name") || !validationErr.Has("node_memory") {
+		t.Errorf("Provision() error = %v, want the name and memory listed", err)
+	}
+	if len(provider.CallsTo("list")) != 0 || len(provider.CallsTo("launch")) != 0 {
+		t.Errorf("Provision() used Multipass before validating")
+	}
+}
+
+func TestCleanup(t *testing.T) {
+	provider, _ := setupProvision(t)
+	provider.AddInstance("rqlite1")
+	stateDir, err := config.GetStateDir(ClusterName)
+	if err != nil {
+		t.Fatalf("GetStateDir() error = %v", err)
+	}
+	if err := os.MkdirAll(stateDir, 0o755); err != nil {
+		t.Fatalf("Failed to create state dir: %v", err)
+	}
+
+	if err := Cleanup(&Config{}); err != nil {
+		t.Fatalf("Cleanup() error = %v", err)
+	}
+	if names := provider.Names(); len(names) != 0 {
+		t.Errorf("remaining VMs = %v, want none", names)
+	}
+	if _, err := os.Stat(stateDir); !os.IsNotExist(err) {
+		t.Errorf("state dir %s was not removed", stateDir)
+	}
+
+	// Recorded nodes are deleted instead of the default names
+	cluster, err := state.Begin(ClusterName, ComponentName)
+	if err != nil {
+		t.Fatalf("state.Begin() error = %v", err)
+	}
+	cluster.Nodes = []state.Node{{Name: "db1", Role: "leader"}}
+	if err := cluster.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	provider.AddInstance("db1")
+	if err := Cleanup(&Config{}); err != nil {
+		t.Fatalf("Cleanup() error = %v", err)
+	}
+	if names := provider.Names(); len(names) != 0 {
+		t.Errorf("remaining VMs = %v, want the recorded node deleted", names)
+	}
+
+	provider.AddInstance("rqlite2")
+	provider.FailOn("delete", "rqlite2", errors.New("permission denied"))
+	if err := Cleanup(&Config{}); err == nil || !strings.Contains(err.Error(), "rqlite2") {
+		t.Errorf("Cleanup() error = %v, want delete failure for rqlite2", err)
+	}
+}
+
+func TestTearDown(t *testing.T) {
+	provider, extraVars := setupProvision(t)
+	provider.AddInstance("rqlite1")
+	provider.AddInstance("rqlite3")
+
+	// Capture the playbook and the inventory it was run with
+	var playbook string
+	var inventory []byte
+	captureVars := runPlaybook
+	runPlaybook = func(playbookPath, inventoryPath string, extraArgs []string, log io.Writer) error {
+		playbook = playbookPath
+		inventory, _ = os.ReadFile(inventoryPath)
+		return captureVars(playbookPath, inventoryPath, extraArgs, log)
+	}
+
+	if err := TearDown(&Config{RqliteHttpPort: 5001}); err != nil {
+		t.Fatalf("TearDown() error = %v", err)
+	}
+	if filepath.Base(playbook) != "rqlite-teardown.yml" {
+		t.Errorf("playbook = %q, want rqlite-teardown.yml", playbook)
+	}
+	if !strings.Contains(string(inventory), "rqlite3") || strings.Contains(string(inventory), "rqlite2") {
+		t.Errorf("inventory does not list only the running nodes:\n%s", inventory)
+	}
+	if (*extraVars)["rqlite_http_port"] != 5001 {
+		t.Errorf("rqlite_http_port extra var = %v, want 5001", (*extraVars)["rqlite_http_port"])
+	}
+
+	// Without a running leader there is nothing to tear down
+	playbook = ""
+	if err := provider.Delete("rqlite1"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := TearDown(&Config{}); err != nil {
+		t.Fatalf("TearDown() error = %v", err)
+	}
+	if playbook != "" {
+		t.Errorf("teardown playbook ran without a leader")
+	}
+
+	provider.AddInstance("rqlite1")
+	runPlaybook = func(string, string, []string, io.Writer) error { return errors.New("unreachable") }
+	if err := TearDown(&Config{}); err == nil || !strings.Contains(err.Error(), "unreachable") {
+		t.Errorf("TearDown() error = %v, want playbook failure", err)
+	}
+}
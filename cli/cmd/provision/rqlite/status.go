@@ -0,0 +1,222 @@
+package rqlite
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"text/tabwriter"
+	"time"
+)
+
+// Status describes every node of the cluster as reported by its own HTTP
+// API, together with the membership seen by the first node that answered
+type Status struct {
+	Nodes   []NodeStatus `json:"nodes"`
+	Members []Member     `json:"members"`
+}
+
+// NodeStatus is the VM state and the Raft state of a single node
+type NodeStatus struct {
+	Name         string `json:"name"`
+	State        string `json:"state"`
+	IP           string `json:"ip"`
+	Reachable    bool   `json:"reachable"`
+	NodeID       string `json:"node_id,omitempty"`
+	RaftState    string `json:"raft_state,omitempty"`
+	Leader       string `json:"leader,omitempty"`
+	Term         uint64 `json:"term,omitempty"`
+	CommitIndex  uint64 `json:"commit_index,omitempty"`
+	AppliedIndex uint64 `json:"applied_index,omitempty"`
+	// Error explains why the node could not be queried
+	Error string `json:"error,omitempty"`
+}
+
+// Member is a cluster member from the /nodes endpoint
+type Member struct {
+	ID        string `json:"id"`
+	Addr      string `json:"addr"`
+	APIAddr   string `json:"api_addr"`
+	Voter     bool   `json:"voter"`
+	Reachable bool   `json:"reachable"`
+	Leader    bool   `json:"leader"`
+}
+
+// Healthy reports whether every node answers, exactly one is the leader,
+// all agree on it and every member is reachable
+func (s *Status) Healthy() bool {
+	if len(s.Nodes) == 0 {
+		return false
+	}
+
+	leaders := 0
+	leader := s.Nodes[0].Leader
+	for _, node := range s.Nodes {
+		if !node.Reachable || node.Leader == "" || node.Leader != leader {
+			return false
+		}
+		if node.RaftState == "Leader" {
+			leaders++
+		}
+	}
+
+	for _, member := range s.Members {
+		if !member.Reachable {
+			return false
+		}
+	}
+	return leaders == 1
+}
+
+// httpGet is a variable so tests can answer the rqlite API
+var httpGet = func(url string) ([]byte, error) {
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s returned %s", url, resp.Status)
+	}
+	return body, nil
+}
+
+// statusResponse is the part of GET /status that describes the store
+type statusResponse struct {
+	Store struct {
+		NodeID string `json:"node_id"`
+		Leader struct {
+			Addr   string `json:"addr"`
+			NodeID string `json:"node_id"`
+		} `json:"leader"`
+		Raft struct {
+			State        string `json:"state"`
+			Term         uint64 `json:"term"`
+			CommitIndex  uint64 `json:"commit_index"`
+			AppliedIndex uint64 `json:"applied_index"`
+		} `json:"raft"`
+	} `json:"store"`
+}
+
+// GetStatus queries the /status and /nodes endpoints of every cluster node
+// on rqliteConfig.RqliteHttpPort
+func GetStatus(rqliteConfig *Config) (*Status, error) {
+	names, err := nodeNames(rqliteConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	instances, err := newProvider().List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list VMs: %w", err)
+	}
+
+	isNode := make(map[string]bool, len(names))
+	for _, name := range names {
+		isNode[name] = true
+	}
+
+	status := &Status{}
+	for _, instance := range instances {
+		if !isNode[instance.Name] {
+			continue
+		}
+
+		node := NodeStatus{Name: instance.Name, State: instance.State, IP: instance.IP()}
+		if !instance.Running() || node.IP == "" {
+			node.Error = "VM is not running"
+			status.Nodes = append(status.Nodes, node)
+			continue
+		}
+
+		baseURL := fmt.Sprintf("http://%s:%d", node.IP, rqliteConfig.RqliteHttpPort)
+		if err := queryNode(baseURL, &node); err != nil {
+			node.Error = err.Error()
+		}
+
+		if node.Reachable && status.Members == nil {
+			if members, err := queryMembers(baseURL); err == nil {
+				status.Members = members
+			}
+		}
+		status.Nodes = append(status.Nodes, node)
+	}
+
+	return status, nil
+}
+
+// queryNode fills node from GET /status
+func queryNode(baseURL string, node *NodeStatus) error {
+	body, err := httpGet(baseURL + "/status")
+	if err != nil {
+		return err
+	}
+
+	var parsed statusResponse
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return fmt.Errorf("failed to parse /status response: %w", err)
+	}
+
+	node.Reachable = true
+	node.NodeID = parsed.Store.NodeID
+	node.RaftState = parsed.Store.Raft.State
+	node.Leader = parsed.Store.Leader.Addr
+	node.Term = parsed.Store.Raft.Term
+	node.CommitIndex = parsed.Store.Raft.CommitIndex
+	node.AppliedIndex = parsed.Store.Raft.AppliedIndex
+	return nil
+}
+
+// queryMembers returns the cluster membership from GET /nodes
+func queryMembers(baseURL string) ([]Member, error) {
+	body, err := httpGet(baseURL + "/nodes?ver=2")
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed struct {
+		Nodes []Member `json:"nodes"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse /nodes response: %w", err)
+	}
+	return parsed.Nodes, nil
+}
+
+// Print writes the status as tables
+func (s *Status) Print(w io.Writer) {
+	if len(s.Nodes) == 0 {
+		fmt.Fprintln(w, "No rqlite VMs found")
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VM\tSTATE\tIP\tRAFT\tLEADER\tTERM\tAPPLIED")
+	for _, node := range s.Nodes {
+		if node.Error != "" {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t\t\t\n", node.Name, node.State, node.IP, node.Error)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\t%d\n",
+			node.Name, node.State, node.IP, node.RaftState, node.Leader, node.Term, node.AppliedIndex)
+	}
+	tw.Flush()
+
+	if len(s.Members) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "MEMBER\tADDRESS\tVOTER\tREACHABLE\tLEADER")
+	for _, member := range s.Members {
+		fmt.Fprintf(tw, "%s\t%s\t%t\t%t\t%t\n", member.ID, member.Addr, member.Voter, member.Reachable, member.Leader)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,116 @@
+package rqlite
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/vm"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/vm/fake"
+)
+
+// statusJSON is a trimmed GET /status response
+func statusJSON(nodeID, state string) string {
+	return fmt.Sprintf(`{"store": {"node_id": %q, "leader": {"addr": "10.0.0.10:4002", "node_id": "2"},
+		"raft": {"state": %q, "term": 3, "commit_index": 42, "applied_index": 42}}}`, nodeID, state)
+}
+
+const nodesJSON = `{"nodes": [
+  {"id": "2", "addr": "10.0.0.10:4002", "api_addr": "http://10.0.0.10:4001", "voter": true, "reachable": true, "leader": true},
+  {"id": "3", "addr": "10.0.0.11:4002", "api_addr": "http://10.0.0.11:4001", "voter": true, "reachable": true, "leader": false},
+  {"id": "4", "addr": "10.0.0.12:4002", "api_addr": "http://10.0.0.12:4001", "voter": true, "reachable": false, "leader": false}
+]}`
+
+// useFakeCluster replaces the VM provider and the HTTP client, answering
+// requests from responses keyed by URL
+func useFakeCluster(t *testing.T, responses map[string]string) (*fake.Provider, *[]string) {
+	t.Helper()
+
+	origProvider, origGet := newProvider, httpGet
+	t.Cleanup(func() { newProvider, httpGet = origProvider, origGet })
+
+	provider := fake.New()
+	newProvider = func() vm.Provider { return provider }
+
+	requested := new([]string)
+	httpGet = func(url string) ([]byte, error) {
+		*requested = append(*requested, url)
+		body, ok := responses[url]
+		if !ok {
+			return nil, errors.New("connection refused")
+		}
+		return []byte(body), nil
+	}
+	return provider, requested
+}
+
+func TestGetStatus(t *testing.T) {
+	provider, requested := useFakeCluster(t, map[string]string{
+		"http://10.0.0.10:5001/status":      statusJSON("2", "Leader"),
+		"http://10.0.0.10:5001/nodes?ver=2": nodesJSON,
+		"http://10.0.0.11:5001/status":      statusJSON("3", "Follower"),
+	})
+	for _, name := range NodeNames {
+		provider.AddInstance(name)
+	}
+
+	status, err := GetStatus(&Config{RqliteHttpPort: 5001})
+	if err != nil {
+		t.Fatalf("GetStatus() error = %v", err)
+	}
+
+	if len(status.Nodes) != 3 {
+		t.Fatalf("Nodes = %v, want 3", status.Nodes)
+	}
+	leader := status.Nodes[0]
+	if !leader.Reachable || leader.RaftState != "Leader" || leader.Leader != "10.0.0.10:4002" || leader.AppliedIndex != 42 {
+		t.Errorf("Nodes[0] = %+v, want reachable leader", leader)
+	}
+	if status.Nodes[1].RaftState != "Follower" {
+		t.Errorf("Nodes[1] = %+v, want follower", status.Nodes[1])
+	}
+	if status.Nodes[2].Reachable || !strings.Contains(status.Nodes[2].Error, "connection refused") {
+		t.Errorf("Nodes[2] = %+v, want unreachable", status.Nodes[2])
+	}
+
+	// Membership is only queried from the first node that answers
+	if len(status.Members) != 3 || !status.Members[0].Leader {
+		t.Errorf("Members = %v, want 3 with the first as leader", status.Members)
+	}
+	if n := strings.Count(strings.Join(*requested, " "), "/nodes"); n != 1 {
+		t.Errorf("requested /nodes %d times, want once", n)
+	}
+
+	if status.Healthy() {
+		t.Errorf("Healthy() = true with an unreachable node")
+	}
+
+	var out bytes.Buffer
+	status.Print(&out)
+	for _, want := range []string{"rqlite1", "Leader", "Follower", "connection refused", "MEMBER"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Print() output does not contain %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestStatusHealthy(t *testing.T) {
+	status := &Status{
+		Nodes: []NodeStatus{
+			{Name: "rqlite1", Reachable: true, RaftState: "Leader", Leader: "10.0.0.10:4002"},
+			{Name: "rqlite2", Reachable: true, RaftState: "Follower", Leader: "10.0.0.10:4002"},
+		},
+		Members: []Member{{ID: "2", Reachable: true}, {ID: "3", Reachable: true}},
+	}
+	if !status.Healthy() {
+		t.Errorf("Healthy() = false, want true")
+	}
+
+	// Nodes that disagree about the leader are a split brain
+	status.Nodes[1].Leader = "10.0.0.11:4002"
+	if status.Healthy() {
+		t.Errorf("Healthy() = true with disagreeing leaders")
+	}
+}
@@ -0,0 +1,56 @@
+package state
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// PrintList writes one line per cluster
+func PrintList(w io.Writer, clusters []*Cluster) {
+	if len(clusters) == 0 {
+		fmt.Fprintln(w, "No clusters found")
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tCOMPONENT\tSTATUS\tNODES\tCREATED")
+	for _, cluster := range clusters {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n",
+			cluster.Name, cluster.Component, cluster.Status, len(cluster.Nodes), cluster.CreatedAt.Format(time.RFC3339))
+	}
+	tw.Flush()
+}
+
+// Describe writes the record, the nodes and the recorded config of the cluster
+func (c *Cluster) Describe(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Name:\t%s\n", c.Name)
+	fmt.Fprintf(tw, "Component:\t%s\n", c.Component)
+	fmt.Fprintf(tw, "Status:\t%s\n", c.Status)
+	if c.Error != "" {
+		fmt.Fprintf(tw, "Error:\t%s\n", c.Error)
+	}
+	fmt.Fprintf(tw, "Created:\t%s\n", c.CreatedAt.Format(time.RFC3339))
+	fmt.Fprintf(tw, "Updated:\t%s\n", c.UpdatedAt.Format(time.RFC3339))
+	fmt.Fprintf(tw, "SSH key:\t%s\n", c.SSHKey)
+	fmt.Fprintf(tw, "Inventory:\t%s\n", c.Path(InventoryFile))
+	fmt.Fprintf(tw, "Log:\t%s\n", c.Path(LogFile))
+	tw.Flush()
+
+	fmt.Fprintln(w, "\nNodes:")
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  NAME\tROLE\tIP")
+	for _, node := range c.Nodes {
+		fmt.Fprintf(tw, "  %s\t%s\t%s\n", node.Name, node.Role, node.IP)
+	}
+	tw.Flush()
+
+	config, err := os.ReadFile(c.Path(ConfigFile))
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "\nConfig (%s):\n%s", c.Path(ConfigFile), config)
+}
@@ -0,0 +1,294 @@
+// Package state records the clusters the CLI has provisioned in the state
+// directory, so later commands can find their config, nodes and inventory
+package state
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/config"
+	"gopkg.in/yaml.v3"
+)
+
+// Files in the state directory of a cluster
+const (
+	ClusterFile   = "cluster.yml"
+	ConfigFile    = "config.yml"
+	InventoryFile = "inventory.yml"
+	LogFile       = "provision.log"
+)
+
+// Provisioning outcomes recorded in Cluster.Status
+const (
+	StatusProvisioning = "provisioning"
+	StatusReady        = "ready"
+	StatusFailed       = "failed"
+)
+
+// ErrNotFound is returned when no state is recorded for a cluster
+var ErrNotFound = errors.New("cluster not found")
+
+// now is a variable so tests can fix the recorded times
+var now = time.Now
+
+// Cluster is what the CLI recorded about a provisioned cluster
+type Cluster struct {
+	Name      string    `yaml:"name" json:"name"`
+	Component string    `yaml:"component" json:"component"`
+	Status    string    `yaml:"status" json:"status"`
+	Error     string    `yaml:"error,omitempty" json:"error,omitempty"`
+	CreatedAt time.Time `yaml:"created_at" json:"created_at"`
+	UpdatedAt time.Time `yaml:"updated_at" json:"updated_at"`
+	SSHKey    string    `yaml:"ssh_key" json:"ssh_key"`
+	Nodes     []Node    `yaml:"nodes" json:"nodes"`
+
+	dir string
+}
+
+// Node is a VM of a cluster
+type Node struct {
+	Name string `yaml:"name" json:"name"`
+	Role string `yaml:"role" json:"role"`
+	IP   string `yaml:"ip" json:"ip"`
+}
+
+// Begin records that a cluster is being provisioned. The creation time and
+// nodes of an existing record are kept, so VMs that may still exist stay
+// claimed until provisioning records the ones it launched. The status and
+// error are reset. A name recorded for another component is refused.
+func Begin(name, component string) (*Cluster, error) {
+	cluster, err := LoadOrNew(name, component)
+	if err != nil {
+		return nil, err
+	}
+	if cluster.Component != component {
+		return nil, fmt.Errorf("cluster %s is a %s cluster, choose another name", name, cluster.Component)
+	}
+
+	cluster.Status = StatusProvisioning
+	cluster.Error = ""
+	if err := cluster.Save(); err != nil {
+		return nil, err
+	}
+	return cluster, nil
+}
+
+// Load reads the recorded state of a cluster
+func Load(name string) (*Cluster, error) {
+	dir, err := config.GetStateDir(name)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, ClusterFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cluster state: %w", err)
+	}
+
+	cluster := &Cluster{}
+	if err := yaml.Unmarshal(data, cluster); err != nil {
+		return nil, fmt.Errorf("failed to parse cluster state of %s: %w", name, err)
+	}
+	cluster.dir = dir
+	return cluster, nil
+}
+
+// LoadOrNew reads the recorded state of a cluster, or returns a new record
+// that is not saved yet when there is none
+func LoadOrNew(name, component string) (*Cluster, error) {
+	cluster, err := Load(name)
+	if !errors.Is(err, ErrNotFound) {
+		return cluster, err
+	}
+
+	dir, err := config.GetStateDir(name)
+	if err != nil {
+		return nil, err
+	}
+	return &Cluster{Name: name, Component: component, CreatedAt: now(), dir: dir}, nil
+}
+
+// List returns the recorded clusters sorted by name
+func List() ([]*Cluster, error) {
+	root, err := config.GetStateRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(root)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state directory: %w", err)
+	}
+
+	var clusters []*Cluster
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		cluster, err := Load(entry.Name())
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		clusters = append(clusters, cluster)
+	}
+
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i].Name < clusters[j].Name })
+	return clusters, nil
+}
+
+// CheckNodesFree returns an error if a VM in names is recorded for a cluster
+// other than the named one, so provisioning never takes over another
+// cluster's VMs
+func CheckNodesFree(name string, names []string) error {
+	clusters, err := List()
+	if err != nil {
+		return err
+	}
+
+	for _, cluster := range clusters {
+		if cluster.Name == name {
+			continue
+		}
+		for _, node := range names {
+			if cluster.HasNode(node) {
+				return fmt.Errorf("VM %s belongs to cluster %s, use --name to provision a separate cluster", node, cluster.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// Remove deletes the recorded state of a cluster. A cluster without state
+// is not an error.
+func Remove(name string) error {
+	dir, err := config.GetStateDir(name)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to remove state directory: %w", err)
+	}
+	return nil
+}
+
+// ConfigPath returns the recorded config file of a cluster if there is one
+func ConfigPath(name string) (string, bool) {
+	dir, err := config.GetStateDir(name)
+	if err != nil {
+		return "", false
+	}
+	path := filepath.Join(dir, ConfigFile)
+	if _, err := os.Stat(path); err != nil {
+		return "", false
+	}
+	return path, true
+}
+
+// Dir returns the state directory of the cluster
+func (c *Cluster) Dir() string {
+	return c.dir
+}
+
+// Path returns the path of a file in the cluster's state directory
+func (c *Cluster) Path(file string) string {
+	return filepath.Join(c.dir, file)
+}
+
+// NodeNames returns the names of the recorded nodes in order
+func (c *Cluster) NodeNames() []string {
+	names := make([]string, 0, len(c.Nodes))
+	for _, node := range c.Nodes {
+		names = append(names, node.Name)
+	}
+	return names
+}
+
+// HasNode reports whether a VM of that name was recorded for the cluster
+func (c *Cluster) HasNode(name string) bool {
+	for _, node := range c.Nodes {
+		if node.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Save writes the cluster record, updating its modification time
+func (c *Cluster) Save() error {
+	c.UpdatedAt = now()
+
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cluster state: %w", err)
+	}
+	return c.writeFile(ClusterFile, data)
+}
+
+// SaveConfig records the effective config of the cluster and returns the
+// path of the file, which can be passed to Ansible as extra vars
+func (c *Cluster) SaveConfig(config interface{}) (string, error) {
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal config: %w", err)
+	}
+	if err := c.writeFile(ConfigFile, data); err != nil {
+		return "", err
+	}
+	return c.Path(ConfigFile), nil
+}
+
+// OpenLog opens the provisioning log for appending and writes a header
+// marking the start of a run
+func (c *Cluster) OpenLog(action string) (io.WriteCloser, error) {
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	file, err := os.OpenFile(c.Path(LogFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open provisioning log: %w", err)
+	}
+	fmt.Fprintf(file, "=== %s %s at %s ===\n", action, c.Name, now().Format(time.RFC3339))
+	return file, nil
+}
+
+// Finish records the outcome of provisioning. It returns provisionErr, or
+// the error saving the record when provisioning succeeded.
+func (c *Cluster) Finish(provisionErr error) error {
+	c.Status = StatusReady
+	c.Error = ""
+	if provisionErr != nil {
+		c.Status = StatusFailed
+		c.Error = provisionErr.Error()
+	}
+
+	if err := c.Save(); err != nil && provisionErr == nil {
+		return err
+	}
+	return provisionErr
+}
+
+// writeFile writes a file to the state directory, creating it if needed
+func (c *Cluster) writeFile(name string, data []byte) error {
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	if err := os.WriteFile(c.Path(name), data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
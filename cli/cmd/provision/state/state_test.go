@@ -0,0 +1,185 @@
+package state
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useStateHome points the state directory at a temporary directory and
+// fixes the recorded time
+func useStateHome(t *testing.T) {
+	t.Helper()
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	origNow := now
+	t.Cleanup(func() { now = origNow })
+	now = func() time.Time { return time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC) }
+}
+
+func TestBeginAndFinish(t *testing.T) {
+	useStateHome(t)
+
+	cluster, err := Begin("lab", "kubernetes")
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	cluster.SSHKey = "/home/user/.ssh/id_rsa_provisioning"
+	cluster.Nodes = []Node{{Name: "controlplane", Role: "control-plane", IP: "10.0.0.10"}}
+
+	configPath, err := cluster.SaveConfig(map[string]int{"worker_count": 2})
+	if err != nil {
+		t.Fatalf("SaveConfig() error = %v", err)
+	}
+	if data, _ := os.ReadFile(configPath); string(data) != "worker_count: 2\n" {
+		t.Errorf("config file = %q, want the marshalled config", data)
+	}
+
+	if err := cluster.Finish(nil); err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
+
+	loaded, err := Load("lab")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if loaded.Status != StatusReady || loaded.Component != "kubernetes" || !loaded.HasNode("controlplane") {
+		t.Errorf("Load() = %+v, want the ready cluster with its node", loaded)
+	}
+	if loaded.Dir() != cluster.Dir() {
+		t.Errorf("Dir() = %s, want %s", loaded.Dir(), cluster.Dir())
+	}
+
+	// A failed run keeps the creation time and nodes and records the error
+	created := loaded.CreatedAt
+	now = func() time.Time { return created.Add(time.Hour) }
+	cluster, err = Begin("lab", "kubernetes")
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	if err := cluster.Finish(errors.New("launch failed")); err == nil {
+		t.Errorf("Finish() did not return the provisioning error")
+	}
+	loaded, _ = Load("lab")
+	if loaded.Status != StatusFailed || loaded.Error != "launch failed" || !loaded.CreatedAt.Equal(created) {
+		t.Errorf("Load() = %+v, want failed with the original creation time", loaded)
+	}
+	if !loaded.HasNode("controlplane") {
+		t.Errorf("Load() nodes = %v, want the VMs of the earlier run still recorded", loaded.Nodes)
+	}
+}
+
+func TestBeginRefusesOtherComponent(t *testing.T) {
+	useStateHome(t)
+
+	cluster, err := Begin("lab", "kubernetes")
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	if err := cluster.Finish(nil); err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
+
+	if _, err := Begin("lab", "rqlite"); err == nil || !strings.Contains(err.Error(), "kubernetes cluster") {
+		t.Errorf("Begin() error = %v, want the name to be refused", err)
+	}
+}
+
+func TestCheckNodesFree(t *testing.T) {
+	useStateHome(t)
+
+	cluster, err := Begin("k8s-a", "kubernetes")
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	cluster.Nodes = []Node{{Name: "k8s-a-controlplane", Role: "control-plane"}}
+	if err := cluster.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	if err := CheckNodesFree("k8s-a", []string{"k8s-a-controlplane"}); err != nil {
+		t.Errorf("CheckNodesFree() of the owner error = %v", err)
+	}
+	if err := CheckNodesFree("k8s-b", []string{"k8s-b-controlplane"}); err != nil {
+		t.Errorf("CheckNodesFree() of free VMs error = %v", err)
+	}
+	err = CheckNodesFree("kubernetes", []string{"k8s-a-controlplane"})
+	if err == nil || !strings.Contains(err.Error(), "belongs to cluster k8s-a") {
+		t.Errorf("CheckNodesFree() error = %v, want the VM to be claimed by k8s-a", err)
+	}
+}
+
+func TestListAndRemove(t *testing.T) {
+	useStateHome(t)
+
+	if clusters, err := List(); err != nil || len(clusters) != 0 {
+		t.Fatalf("List() without state = %v, %v", clusters, err)
+	}
+
+	for _, name := range []string{"rqlite", "kubernetes"} {
+		if _, err := Begin(name, name); err != nil {
+			t.Fatalf("Begin() error = %v", err)
+		}
+	}
+
+	clusters, err := List()
+	if err != nil || len(clusters) != 2 || clusters[0].Name != "kubernetes" {
+		t.Fatalf("List() = %v, %v, want both clusters sorted by name", clusters, err)
+	}
+
+	var out bytes.Buffer
+	PrintList(&out, clusters)
+	if !strings.Contains(out.String(), "rqlite") || !strings.Contains(out.String(), StatusProvisioning) {
+		t.Errorf("PrintList() output:\n%s", out.String())
+	}
+
+	if err := Remove("rqlite"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if _, err := Load("rqlite"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Load() after Remove() error = %v, want ErrNotFound", err)
+	}
+	if _, ok := ConfigPath("rqlite"); ok {
+		t.Errorf("ConfigPath() found a config after Remove()")
+	}
+}
+
+func TestOpenLogAppends(t *testing.T) {
+	useStateHome(t)
+	cluster, err := LoadOrNew("lab", "rqlite")
+	if err != nil {
+		t.Fatalf("LoadOrNew() error = %v", err)
+	}
+
+	for _, action := range []string{"provision", "teardown"} {
+		log, err := cluster.OpenLog(action)
+		if err != nil {
+			t.Fatalf("OpenLog() error = %v", err)
+		}
+		log.Write([]byte(action + " output\n"))
+		log.Close()
+	}
+
+	data, _ := os.ReadFile(cluster.Path(LogFile))
+	if !strings.Contains(string(data), "=== provision lab") || !strings.Contains(string(data), "teardown output") {
+		t.Errorf("log = %q, want both runs", data)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	useStateHome(t)
+	cluster, _ := Begin("lab", "kubernetes")
+	cluster.Nodes = []Node{{Name: "node01", Role: "worker", IP: "10.0.0.11"}}
+	cluster.SaveConfig(map[string]string{"name_prefix": "lab-"})
+
+	var out bytes.Buffer
+	cluster.Describe(&out)
+	for _, want := range []string{"Component:  kubernetes", "node01", "worker", "10.0.0.11", "name_prefix: lab-"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Describe() output does not contain %q:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Resources Multipass gives a VM launched without --cpus, --memory or --disk
+const (
+	DefaultCPUs   = 1
+	DefaultMemory = "1G"
+	DefaultDisk   = "5G"
+)
+
+// ErrCapacityUnknown is returned when the capacity of the host cannot be read
+var ErrCapacityUnknown = errors.New("host capacity is unknown")
+
+// Host is the capacity of the machine the VMs run on
+type Host struct {
+	CPUs int
+	// Memory is the memory in bytes available without swapping, which
+	// leaves out what running VMs and other processes already use
+	Memory uint64
+	// FreeDisk is the free space in bytes where the VM disks are stored
+	FreeDisk    uint64
+	StoragePath string
+}
+
+// CapacityError lists the resources the host lacks for the VMs to launch
+type CapacityError struct {
+	Shortfalls []string
+}
+
+func (e *CapacityError) Error() string {
+	return "the VMs to launch do not fit on this host: " + strings.Join(e.Shortfalls, "; ")
+}
+
+// CheckCapacity checks that host has the CPUs, memory and disk for the VMs
+// of specs that are launched, as told by PlanInstances. It returns a
+// *CapacityError when they do not fit.
+func CheckCapacity(host Host, specs []LaunchOptions, launch []bool) error {
+	var cpus int
+	var memory, disk uint64
+	for i, spec := range specs {
+		if !launch[i] {
+			continue
+		}
+
+		specMemory, err := ParseSize(orDefault(spec.Memory, DefaultMemory))
+		if err != nil {
+			return fmt.Errorf("invalid memory for VM %s: %w", spec.Name, err)
+		}
+		specDisk, err := ParseSize(orDefault(spec.Disk, DefaultDisk))
+		if err != nil {
+			return fmt.Errorf("invalid disk for VM %s: %w", spec.Name, err)
+		}
+
+		if spec.CPUs > 0 {
+			cpus += spec.CPUs
+		} else {
+			cpus += DefaultCPUs
+		}
+		memory += specMemory
+		disk += specDisk
+	}
+
+	var shortfalls []string
+	if cpus > host.CPUs {
+		shortfalls = append(shortfalls, fmt.Sprintf("%d CPUs requested, the host has %d", cpus, host.CPUs))
+	}
+	if memory > host.Memory {
+		shortfalls = append(shortfalls, fmt.Sprintf("%s memory requested, the host has %s available",
+			FormatSize(memory), FormatSize(host.Memory)))
+	}
+	if disk > host.FreeDisk {
+		shortfalls = append(shortfalls, fmt.Sprintf("%s disk requested, %s free in %s",
+			FormatSize(disk), FormatSize(host.FreeDisk), host.StoragePath))
+	}
+	if len(shortfalls) > 0 {
+		return &CapacityError{Shortfalls: shortfalls}
+	}
+	return nil
+}
+
+// ParseSize parses a size as accepted by multipass launch, a number of bytes
+// with an optional K, M, G or T suffix such as "512M", "8G" or "1.5GiB"
+func ParseSize(size string) (uint64, error) {
+	s := strings.ToUpper(strings.TrimSpace(size))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "B"), "I")
+
+	multiplier := uint64(1)
+	if s != "" {
+		if i := strings.IndexByte("KMGT", s[len(s)-1]); i >= 0 {
+			multiplier = 1 << (10 * (i + 1))
+			s = s[:len(s)-1]
+		}
+	}
+
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("%q is not a size such as 512M or 8G", size)
+	}
+	return uint64(value * float64(multiplier)), nil
+}
+
+// FormatSize formats bytes in gigabytes, as the sizes are usually given
+func FormatSize(bytes uint64) string {
+	return fmt.Sprintf("%.1fG", float64(bytes)/(1<<30))
+}
+
+func orDefault(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
@@ -0,0 +1,63 @@
+package vm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// ReadHost reads the CPU count, the available memory from /proc/meminfo and
+// the free disk space in storagePath, or in its nearest existing parent as
+// the storage directory may not be readable. Errors wrap ErrCapacityUnknown.
+func ReadHost(storagePath string) (Host, error) {
+	host := Host{CPUs: runtime.NumCPU(), StoragePath: storagePath}
+
+	meminfo, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return host, fmt.Errorf("%w: %v", ErrCapacityUnknown, err)
+	}
+	defer meminfo.Close()
+	if host.Memory, err = parseMemAvailable(meminfo); err != nil {
+		return host, fmt.Errorf("%w: %v", ErrCapacityUnknown, err)
+	}
+
+	var stat syscall.Statfs_t
+	for path := storagePath; ; path = filepath.Dir(path) {
+		err = syscall.Statfs(path, &stat)
+		if err == nil || path == filepath.Dir(path) {
+			break
+		}
+	}
+	if err != nil {
+		return host, fmt.Errorf("%w: failed to read free disk space in %s: %v", ErrCapacityUnknown, storagePath, err)
+	}
+	host.FreeDisk = stat.Bavail * uint64(stat.Bsize)
+	return host, nil
+}
+
+// parseMemAvailable returns the MemAvailable line of /proc/meminfo in bytes.
+// Unlike MemTotal it excludes the memory of VMs that are already running.
+func parseMemAvailable(r io.Reader) (uint64, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "MemAvailable:" {
+			continue
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse MemAvailable: %w", err)
+		}
+		return kb * 1024, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read /proc/meminfo: %w", err)
+	}
+	return 0, fmt.Errorf("no MemAvailable in /proc/meminfo")
+}
@@ -0,0 +1,31 @@
+package vm
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseMemAvailable(t *testing.T) {
+	meminfo := "MemTotal:       32636960 kB\nMemFree:         1203448 kB\nMemAvailable:   16318480 kB\n"
+	got, err := parseMemAvailable(strings.NewReader(meminfo))
+	if err != nil || got != 16318480*1024 {
+		t.Errorf("parseMemAvailable() = %d, %v, want %d", got, err, 16318480*1024)
+	}
+
+	if _, err := parseMemAvailable(strings.NewReader("MemTotal: 1 kB\n")); err == nil {
+		t.Errorf("parseMemAvailable() error = nil, want an error without MemAvailable")
+	}
+}
+
+func TestReadHost(t *testing.T) {
+	// A storage path that does not exist is measured at its nearest parent
+	storagePath := filepath.Join(t.TempDir(), "multipassd", "vault")
+	host, err := ReadHost(storagePath)
+	if err != nil {
+		t.Fatalf("ReadHost() error = %v", err)
+	}
+	if host.CPUs < 1 || host.Memory == 0 || host.FreeDisk == 0 || host.StoragePath != storagePath {
+		t.Errorf("ReadHost() = %+v, want the CPUs, memory and free disk of this machine", host)
+	}
+}
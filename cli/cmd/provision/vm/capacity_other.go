@@ -0,0 +1,10 @@
+//go:build !linux
+
+package vm
+
+import "fmt"
+
+// ReadHost is only implemented on Linux, the supported host OS
+func ReadHost(storagePath string) (Host, error) {
+	return Host{StoragePath: storagePath}, fmt.Errorf("%w: reading it is only supported on Linux", ErrCapacityUnknown)
+}
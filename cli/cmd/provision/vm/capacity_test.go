@@ -0,0 +1,67 @@
+package vm
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]uint64{
+		"512M":    512 << 20,
+		"8G":      8 << 30,
+		"8g":      8 << 30,
+		"1.5G":    3 << 29,
+		"2GiB":    2 << 30,
+		"4GB":     4 << 30,
+		"1T":      1 << 40,
+		"1048576": 1 << 20,
+	}
+	for size, want := range tests {
+		got, err := ParseSize(size)
+		if err != nil || got != want {
+			t.Errorf("ParseSize(%q) = %d, %v, want %d", size, got, err, want)
+		}
+	}
+
+	for _, size := range []string{"", "G", "eight", "-1G", "0"} {
+		if _, err := ParseSize(size); err == nil {
+			t.Errorf("ParseSize(%q) error = nil, want an error", size)
+		}
+	}
+}
+
+func TestCheckCapacity(t *testing.T) {
+	host := Host{CPUs: 8, Memory: 16 << 30, FreeDisk: 100 << 30, StoragePath: "/var/snap/multipass"}
+	specs := []LaunchOptions{
+		{Name: "controlplane", CPUs: 4, Memory: "8G", Disk: "40G"},
+		{Name: "node01", CPUs: 4, Memory: "8G", Disk: "40G"},
+		{Name: "node02", CPUs: 4, Memory: "8G", Disk: "40G"},
+	}
+
+	// Only the VMs that are launched count
+	if err := CheckCapacity(host, specs, []bool{true, true, false}); err != nil {
+		t.Errorf("CheckCapacity() error = %v, want the two launched VMs to fit", err)
+	}
+
+	err := CheckCapacity(host, specs, []bool{true, true, true})
+	var capacityErr *CapacityError
+	if !errors.As(err, &capacityErr) || len(capacityErr.Shortfalls) != 3 {
+		t.Fatalf("CheckCapacity() error = %v, want CPU, memory and disk shortfalls", err)
+	}
+	for _, want := range []string{"12 CPUs requested, the host has 8", "24.0G memory requested, the host has 16.0G available", "120.0G disk requested, 100.0G free in /var/snap/multipass"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("CheckCapacity() error = %q, want it to contain %q", err, want)
+		}
+	}
+
+	// Unset resources count as the Multipass defaults
+	if err := CheckCapacity(Host{CPUs: 1, Memory: 1 << 30, FreeDisk: 5 << 30}, []LaunchOptions{{Name: "vm"}}, []bool{true}); err != nil {
+		t.Errorf("CheckCapacity() error = %v, want a default VM to fit", err)
+	}
+
+	err = CheckCapacity(host, []LaunchOptions{{Name: "node01", Memory: "lots"}}, []bool{true})
+	if err == nil || errors.As(err, &capacityErr) || !strings.Contains(err.Error(), "node01") {
+		t.Errorf("CheckCapacity() error = %v, want an invalid memory error naming the VM", err)
+	}
+}
@@ -0,0 +1,97 @@
+package vm
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultSSHKeyPath returns the path of the key Ansible uses to reach the VMs
+func DefaultSSHKeyPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".ssh", "id_rsa_provisioning"), nil
+}
+
+// EnsureSSHKey generates the provisioning key pair if it does not exist yet
+// and returns the public key
+func EnsureSSHKey(keyPath string) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(keyPath), 0o700); err != nil {
+		return "", fmt.Errorf("failed to create SSH directory: %w", err)
+	}
+
+	if _, err := os.Stat(keyPath); os.IsNotExist(err) {
+		fmt.Printf("Generating new SSH key for provisioning at %s\n", keyPath)
+		cmd := exec.Command("ssh-keygen", "-t", "rsa", "-b", "4096", "-f", keyPath, "-N", "", "-C", "multipass_provisioning_key")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return "", fmt.Errorf("failed to generate SSH key: %w\n%s", err, out)
+		}
+	}
+
+	// Ensure correct permissions
+	if err := os.Chmod(keyPath, 0o600); err != nil {
+		return "", fmt.Errorf("failed to set SSH key permissions: %w", err)
+	}
+
+	publicKey, err := os.ReadFile(keyPath + ".pub")
+	if err != nil {
+		return "", fmt.Errorf("failed to read SSH public key: %w", err)
+	}
+
+	return strings.TrimSpace(string(publicKey)), nil
+}
+
+// PlannedPublicKey returns the public key of the key pair at keyPath, or a
+// placeholder when EnsureSSHKey has not generated it yet
+func PlannedPublicKey(keyPath string) string {
+	publicKey, err := os.ReadFile(keyPath + ".pub")
+	if err != nil {
+		return "<public key of " + keyPath + ", generated when provisioning>"
+	}
+	return strings.TrimSpace(string(publicKey))
+}
+
+// CloudInit returns the cloud-init template with $SSH_PUBLIC_KEY replaced
+func CloudInit(templatePath, publicKey string) (string, error) {
+	template, err := os.ReadFile(templatePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read cloud-init template: %w", err)
+	}
+	return strings.ReplaceAll(string(template), "$SSH_PUBLIC_KEY", publicKey), nil
+}
+
+// RenderCloudInit replaces $SSH_PUBLIC_KEY in the cloud-init template and
+// writes the result to a temp file, which the caller removes
+func RenderCloudInit(templatePath, publicKey string) (string, error) {
+	rendered, err := CloudInit(templatePath, publicKey)
+	if err != nil {
+		return "", err
+	}
+
+	tempFile, err := os.CreateTemp("", "cloud-init-*.yaml")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer tempFile.Close()
+
+	if _, err := tempFile.WriteString(rendered); err != nil {
+		return "", fmt.Errorf("failed to write cloud-init file: %w", err)
+	}
+
+	return tempFile.Name(), nil
+}
+
+// PrepareCloudInit ensures the SSH key at keyPath exists and renders the
+// cloud-init template with its public key
+func PrepareCloudInit(templatePath, keyPath string) (string, error) {
+	publicKey, err := EnsureSSHKey(keyPath)
+	if err != nil {
+		return "", err
+	}
+
+	return RenderCloudInit(templatePath, publicKey)
+}
@@ -0,0 +1,280 @@
+// Package fake provides an in-memory vm.Provider for tests
+package fake
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/vm"
+)
+
+// Call records a single Provider method invocation
+type Call struct {
+	Method string
+	Args   []string
+}
+
+// String formats the call as "method arg1 arg2"
+func (c Call) String() string {
+	return strings.TrimSpace(c.Method + " " + strings.Join(c.Args, " "))
+}
+
+// ExecFunc produces the output of a command run with Exec
+type ExecFunc func(name string, command []string) ([]byte, error)
+
+// Provider is an in-memory vm.Provider. Launched instances are Running and
+// get sequential IPs from Subnet. Every call is recorded in Calls.
+type Provider struct {
+	// Subnet is the prefix for assigned IPs, "10.0.0" by default
+	Subnet string
+	// ExecFunc answers commands run with Exec; nil returns empty output
+	ExecFunc ExecFunc
+	// Files holds the contents copied into instances, keyed "name:path"
+	Files map[string][]byte
+
+	mu        sync.Mutex
+	instances map[string]*vm.Instance
+	order     []string
+	calls     []Call
+	failures  map[string]error
+	nextIP    int
+}
+
+var _ vm.Provider = (*Provider)(nil)
+
+// New returns an empty fake provider
+func New() *Provider {
+	return &Provider{
+		Subnet:    "10.0.0",
+		Files:     make(map[string][]byte),
+		instances: make(map[string]*vm.Instance),
+		failures:  make(map[string]error),
+		nextIP:    10,
+	}
+}
+
+// AddInstance registers an existing running instance and returns its IP
+func (p *Provider) AddInstance(name string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.add(name, 0)
+}
+
+// SetState changes the state of an instance, e.g. to "Stopped"
+func (p *Provider) SetState(name, state string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if instance, ok := p.instances[name]; ok {
+		instance.State = state
+	}
+}
+
+// FailOn makes the given method fail with err. With a non-empty name the
+// failure only applies to calls for that instance.
+func (p *Provider) FailOn(method, name string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failures[failureKey(method, name)] = err
+}
+
+// Calls returns the recorded calls in order
+func (p *Provider) Calls() []Call {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Call(nil), p.calls...)
+}
+
+// CallsTo returns the recorded calls of a single method
+func (p *Provider) CallsTo(method string) []Call {
+	var calls []Call
+	for _, call := range p.Calls() {
+		if call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// Names returns the names of the current instances in creation order
+func (p *Provider) Names() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.order...)
+}
+
+// Launch creates a running instance with the next free IP
+func (p *Provider) Launch(opts vm.LaunchOptions) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.record("launch", opts.Name)
+	if err := p.failure("launch", opts.Name); err != nil {
+		return err
+	}
+
+	if _, ok := p.instances[opts.Name]; ok {
+		return fmt.Errorf("instance %s already exists", opts.Name)
+	}
+
+	p.add(opts.Name, opts.CPUs)
+	return nil
+}
+
+// Info returns a copy of the named instance
+func (p *Provider) Info(name string) (*vm.Instance, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.record("info", name)
+	if err := p.failure("info", name); err != nil {
+		return nil, err
+	}
+
+	instance, ok := p.instances[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", name, vm.ErrNotFound)
+	}
+	copied := *instance
+	return &copied, nil
+}
+
+// List returns copies of all instances in creation order
+func (p *Provider) List() ([]vm.Instance, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.record("list")
+	if err := p.failure("list", ""); err != nil {
+		return nil, err
+	}
+
+	instances := make([]vm.Instance, 0, len(p.order))
+	for _, name := range p.order {
+		instances = append(instances, *p.instances[name])
+	}
+	return instances, nil
+}
+
+// Delete removes the given instances
+func (p *Provider) Delete(names ...string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.record("delete", names...)
+	for _, name := range names {
+		if err := p.failure("delete", name); err != nil {
+			return err
+		}
+		if _, ok := p.instances[name]; !ok {
+			return fmt.Errorf("%s: %w", name, vm.ErrNotFound)
+		}
+	}
+
+	for _, name := range names {
+		delete(p.instances, name)
+		for i, existing := range p.order {
+			if existing == name {
+				p.order = append(p.order[:i], p.order[i+1:]...)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// Exec returns the output of ExecFunc for the command
+func (p *Provider) Exec(name string, command ...string) ([]byte, error) {
+	p.mu.Lock()
+	p.record("exec", append([]string{name}, command...)...)
+	err := p.failure("exec", name)
+	_, exists := p.instances[name]
+	execFunc := p.ExecFunc
+	p.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("%s: %w", name, vm.ErrNotFound)
+	}
+	if execFunc == nil {
+		return nil, nil
+	}
+	return execFunc(name, command)
+}
+
+// CopyTo stores the local file's contents in Files
+func (p *Provider) CopyTo(name, localPath, remotePath string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.record("copy-to", name, localPath, remotePath)
+	if err := p.failure("copy-to", name); err != nil {
+		return err
+	}
+	if _, ok := p.instances[name]; !ok {
+		return fmt.Errorf("%s: %w", name, vm.ErrNotFound)
+	}
+
+	data, err := os.ReadFile(localPath)
+	if err != nil {
+		return err
+	}
+	p.Files[name+":"+remotePath] = data
+	return nil
+}
+
+// CopyFrom writes the contents stored in Files to a local file
+func (p *Provider) CopyFrom(name, remotePath, localPath string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.record("copy-from", name, remotePath, localPath)
+	if err := p.failure("copy-from", name); err != nil {
+		return err
+	}
+	if _, ok := p.instances[name]; !ok {
+		return fmt.Errorf("%s: %w", name, vm.ErrNotFound)
+	}
+
+	data, ok := p.Files[name+":"+remotePath]
+	if !ok {
+		return fmt.Errorf("%s:%s: %w", name, remotePath, os.ErrNotExist)
+	}
+	return os.WriteFile(localPath, data, 0o644)
+}
+
+// add creates a running instance; the caller holds the lock
+func (p *Provider) add(name string, cpus int) string {
+	ip := fmt.Sprintf("%s.%d", p.Subnet, p.nextIP)
+	p.nextIP++
+
+	p.instances[name] = &vm.Instance{
+		Name:    name,
+		State:   "Running",
+		Release: "24.04 LTS",
+		IPv4:    []string{ip},
+		CPUs:    cpus,
+	}
+	p.order = append(p.order, name)
+	return ip
+}
+
+// record appends a call; the caller holds the lock
+func (p *Provider) record(method string, args ...string) {
+	p.calls = append(p.calls, Call{Method: method, Args: args})
+}
+
+// failure returns the configured error for a call; the caller holds the lock
+func (p *Provider) failure(method, name string) error {
+	if err, ok := p.failures[failureKey(method, name)]; ok {
+		return err
+	}
+	return p.failures[failureKey(method, "")]
+}
+
+func failureKey(method, name string) string {
+	return method + "/" + name
+}
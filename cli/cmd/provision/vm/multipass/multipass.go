@@ -0,0 +1,271 @@
+// Package multipass implements vm.Provider by wrapping the multipass CLI
+package multipass
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/vm"
+)
+
+// CommandError describes a failed multipass invocation
+type CommandError struct {
+	Args   []string
+	Stderr string
+	Err    error
+}
+
+func (e *CommandError) Error() string {
+	msg := fmt.Sprintf("multipass %s failed: %v", strings.Join(e.Args, " "), e.Err)
+	if e.Stderr != "" {
+		msg += ": " + e.Stderr
+	}
+	return msg
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// Runner executes multipass with the given arguments and returns its stdout.
+// A failed command should be reported as a *CommandError.
+type Runner func(args ...string) ([]byte, error)
+
+// Client runs multipass commands
+type Client struct {
+	run Runner
+}
+
+var _ vm.Provider = (*Client)(nil)
+
+// StorageEnv overrides where the Multipass daemon stores the VM images. It
+// is the variable multipassd itself reads.
+const StorageEnv = "MULTIPASS_STORAGE"
+
+// defaultStoragePath is where the Multipass snap stores the VM images
+const defaultStoragePath = "/var/snap/multipass/common/data/multipassd"
+
+// StoragePath returns the directory the VM disks are created in
+func StoragePath() string {
+	if path := os.Getenv(StorageEnv); path != "" {
+		return path
+	}
+	return defaultStoragePath
+}
+
+// New returns a client that runs the multipass binary found in PATH
+func New() *Client {
+	return NewWithRunner(execRunner("multipass"))
+}
+
+// NewWithRunner returns a client that uses run to execute multipass,
+// which lets tests replace the binary
+func NewWithRunner(run Runner) *Client {
+	return &Client{run: run}
+}
+
+// execRunner runs the given binary, capturing stdout and stderr
+func execRunner(binary string) Runner {
+	return func(args ...string) ([]byte, error) {
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command(binary, args...)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+
+		if err := cmd.Run(); err != nil {
+			return stdout.Bytes(), &CommandError{
+				Args:   args,
+				Stderr: strings.TrimSpace(stderr.String()),
+				Err:    err,
+			}
+		}
+		return stdout.Bytes(), nil
+	}
+}
+
+// listOutput matches `multipass list --format json`
+type listOutput struct {
+	List []struct {
+		Name    string   `json:"name"`
+		State   string   `json:"state"`
+		Release string   `json:"release"`
+		IPv4    []string `json:"ipv4"`
+	} `json:"list"`
+}
+
+// infoOutput matches `multipass info --format json`
+type infoOutput struct {
+	Errors []interface{} `json:"errors"`
+	Info   map[string]struct {
+		State        string   `json:"state"`
+		ImageRelease string   `json:"image_release"`
+		IPv4         []string `json:"ipv4"`
+		CPUCount     string   `json:"cpu_count"`
+		Memory       struct {
+			Total int64 `json:"total"`
+			Used  int64 `json:"used"`
+		} `json:"memory"`
+	} `json:"info"`
+}
+
+// List returns all instances known to Multipass
+func (c *Client) List() ([]vm.Instance, error) {
+	out, err := c.run("list", "--format", "json")
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed listOutput
+	if err := json.Unmarshal(out, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse multipass list output: %w", err)
+	}
+
+	instances := make([]vm.Instance, 0, len(parsed.List))
+	for _, item := range parsed.List {
+		instances = append(instances, vm.Instance{
+			Name:    item.Name,
+			State:   item.State,
+			Release: item.Release,
+			IPv4:    item.IPv4,
+		})
+	}
+	return instances, nil
+}
+
+// Info returns details for a single instance, or vm.ErrNotFound
+func (c *Client) Info(name string) (*vm.Instance, error) {
+	out, err := c.run("info", name, "--format", "json")
+	if err != nil {
+		if isNotFound(err) {
+			return nil, fmt.Errorf("%s: %w", name, vm.ErrNotFound)
+		}
+		return nil, err
+	}
+
+	var parsed infoOutput
+	if err := json.Unmarshal(out, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse multipass info output: %w", err)
+	}
+
+	info, ok := parsed.Info[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", name, vm.ErrNotFound)
+	}
+
+	// cpu_count is reported as a string and is empty for stopped instances
+	cpus, _ := strconv.Atoi(info.CPUCount)
+
+	return &vm.Instance{
+		Name:        name,
+		State:       info.State,
+		Release:     info.ImageRelease,
+		IPv4:        info.IPv4,
+		CPUs:        cpus,
+		MemoryTotal: info.Memory.Total,
+		MemoryUsed:  info.Memory.Used,
+	}, nil
+}
+
+// Launch creates and starts a new instance
+func (c *Client) Launch(opts vm.LaunchOptions) error {
+	if opts.Name == "" {
+		return errors.New("launch requires an instance name")
+	}
+
+	args := []string{"launch", "--name", opts.Name}
+	if opts.CPUs > 0 {
+		args = append(args, "--cpus", strconv.Itoa(opts.CPUs))
+	}
+	if opts.Memory != "" {
+		args = append(args, "--memory", opts.Memory)
+	}
+	if opts.Disk != "" {
+		args = append(args, "--disk", opts.Disk)
+	}
+	if opts.CloudInit != "" {
+		args = append(args, "--cloud-init", opts.CloudInit)
+	}
+	if opts.Image != "" {
+		args = append(args, opts.Image)
+	}
+
+	_, err := c.run(args...)
+	return err
+}
+
+// Delete permanently removes the given instances
+func (c *Client) Delete(names ...string) error {
+	return c.runOnInstances([]string{"delete", "--purge"}, names)
+}
+
+// Purge permanently removes all instances that were deleted without --purge
+func (c *Client) Purge() error {
+	_, err := c.run("purge")
+	return err
+}
+
+// Start starts the given instances
+func (c *Client) Start(names ...string) error {
+	return c.runOnInstances([]string{"start"}, names)
+}
+
+// Stop stops the given instances
+func (c *Client) Stop(names ...string) error {
+	return c.runOnInstances([]string{"stop"}, names)
+}
+
+// Exec runs a command inside an instance and returns its stdout
+func (c *Client) Exec(name string, command ...string) ([]byte, error) {
+	out, err := c.run(append([]string{"exec", name, "--"}, command...)...)
+	if err != nil && isNotFound(err) {
+		return nil, fmt.Errorf("%s: %w", name, vm.ErrNotFound)
+	}
+	return out, err
+}
+
+// CopyTo copies a local file into an instance
+func (c *Client) CopyTo(name, localPath, remotePath string) error {
+	return c.transfer(name, localPath, name+":"+remotePath)
+}
+
+// CopyFrom copies a file out of an instance
+func (c *Client) CopyFrom(name, remotePath, localPath string) error {
+	return c.transfer(name, name+":"+remotePath, localPath)
+}
+
+// transfer runs multipass transfer, where one side is "<instance>:<path>"
+func (c *Client) transfer(name, source, destination string) error {
+	_, err := c.run("transfer", source, destination)
+	if err != nil && isNotFound(err) {
+		return fmt.Errorf("%s: %w", name, vm.ErrNotFound)
+	}
+	return err
+}
+
+// runOnInstances runs a command followed by a list of instance names
+func (c *Client) runOnInstances(command []string, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	_, err := c.run(append(command, names...)...)
+	if err != nil && isNotFound(err) {
+		return fmt.Errorf("%s: %w", strings.Join(names, ", "), vm.ErrNotFound)
+	}
+	return err
+}
+
+// isNotFound reports whether a command failed because an instance is missing
+func isNotFound(err error) bool {
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		return false
+	}
+	return strings.Contains(cmdErr.Stderr, "does not exist")
+}
@@ -0,0 +1,229 @@
+package multipass
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/vm"
+)
+
+// fakeRunner returns canned output per command and records invocations
+type fakeRunner struct {
+	outputs map[string]string
+	errs    map[string]error
+	calls   [][]string
+}
+
+func (f *fakeRunner) run(args ...string) ([]byte, error) {
+	f.calls = append(f.calls, args)
+	key := strings.Join(args, " ")
+	if err, ok := f.errs[key]; ok {
+		return nil, err
+	}
+	return []byte(f.outputs[key]), nil
+}
+
+const listJSON = `{
+    "list": [
+        {
+            "ipv4": ["10.193.215.215", "10.244.0.1"],
+            "name": "controlplane",
+            "release": "24.04 LTS",
+            "state": "Running"
+        },
+        {
+            "ipv4": [],
+            "name": "node01",
+            "release": "24.04 LTS",
+            "state": "Stopped"
+        }
+    ]
+}`
+
+const infoJSON = `{
+    "errors": [],
+    "info": {
+        "controlplane": {
+            "cpu_count": "4",
+            "disks": {"sda1": {"total": "41567858688", "used": "3426156544"}},
+            "image_hash": "abc",
+            "image_release": "24.04 LTS",
+            "ipv4": ["10.193.215.215"],
+            "load": [0.1, 0.2, 0.3],
+            "memory": {"total": 8132734976, "used": 612646912},
+            "mounts": {},
+            "release": "Ubuntu 24.04.1 LTS",
+            "state": "Running"
+        }
+    }
+}`
+
+func TestList(t *testing.T) {
+	runner := &fakeRunner{outputs: map[string]string{"list --format json": listJSON}}
+	client := NewWithRunner(runner.run)
+
+	instances, err := client.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+
+	if len(instances) != 2 {
+		t.Fatalf("List() returned %d instances, want 2", len(instances))
+	}
+
+	if instances[0].Name != "controlplane" || instances[0].IP() != "10.193.215.215" || !instances[0].Running() {
+		t.Errorf("List()[0] = %+v, want running controlplane with IP 10.193.215.215", instances[0])
+	}
+
+	if instances[1].IP() != "" || instances[1].Running() {
+		t.Errorf("List()[1] = %+v, want stopped node01 without IP", instances[1])
+	}
+}
+
+func TestInfo(t *testing.T) {
+	runner := &fakeRunner{outputs: map[string]string{"info controlplane --format json": infoJSON}}
+	client := NewWithRunner(runner.run)
+
+	instance, err := client.Info("controlplane")
+	if err != nil {
+		t.Fatalf("Info() error = %v", err)
+	}
+
+	if instance.CPUs != 4 {
+		t.Errorf("CPUs = %d, want 4", instance.CPUs)
+	}
+	if instance.MemoryTotal != 8132734976 {
+		t.Errorf("MemoryTotal = %d, want 8132734976", instance.MemoryTotal)
+	}
+	if instance.IP() != "10.193.215.215" {
+		t.Errorf("IP() = %q, want 10.193.215.215", instance.IP())
+	}
+}
+
+func TestInfoNotFound(t *testing.T) {
+	runner := &fakeRunner{errs: map[string]error{
+		"info missing --format json": &CommandError{
+			Args:   []string{"info", "missing", "--format", "json"},
+			Stderr: `info failed: The following errors occurred:` + "\n" + `instance "missing" does not exist`,
+			Err:    errors.New("exit status 2"),
+		},
+	}}
+	client := NewWithRunner(runner.run)
+
+	_, err := client.Info("missing")
+	if !errors.Is(err, vm.ErrNotFound) {
+		t.Errorf("Info() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestInfoCommandError(t *testing.T) {
+	cmdErr := &CommandError{
+		Args:   []string{"info", "controlplane", "--format", "json"},
+		Stderr: "The client is not authenticated with the Multipass service.",
+		Err:    errors.New("exit status 1"),
+	}
+	runner := &fakeRunner{errs: map[string]error{"info controlplane --format json": cmdErr}}
+	client := NewWithRunner(runner.run)
+
+	_, err := client.Info("controlplane")
+	if errors.Is(err, vm.ErrNotFound) {
+		t.Errorf("Info() error = %v, should not be ErrNotFound", err)
+	}
+
+	var got *CommandError
+	if !errors.As(err, &got) || !strings.Contains(got.Error(), "not authenticated") {
+		t.Errorf("Info() error = %v, want CommandError with stderr", err)
+	}
+}
+
+func TestLaunchArgs(t *testing.T) {
+	runner := &fakeRunner{}
+	client := NewWithRunner(runner.run)
+
+	err := client.Launch(vm.LaunchOptions{
+		Name:      "node01",
+		CPUs:      2,
+		Memory:    "4G",
+		Disk:      "20G",
+		CloudInit: "/tmp/cloud-init.yaml",
+	})
+	if err != nil {
+		t.Fatalf("Launch() error = %v", err)
+	}
+
+	want := "launch --name node01 --cpus 2 --memory 4G --disk 20G --cloud-init /tmp/cloud-init.yaml"
+	if got := strings.Join(runner.calls[0], " "); got != want {
+		t.Errorf("Launch() ran %q, want %q", got, want)
+	}
+
+	if err := client.Launch(vm.LaunchOptions{}); err == nil {
+		t.Errorf("Launch() without a name returned nil error")
+	}
+}
+
+func TestExecAndTransfer(t *testing.T) {
+	runner := &fakeRunner{outputs: map[string]string{"exec controlplane -- hostname": "controlplane\n"}}
+	client := NewWithRunner(runner.run)
+
+	out, err := client.Exec("controlplane", "hostname")
+	if err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+	if string(out) != "controlplane\n" {
+		t.Errorf("Exec() = %q, want \"controlplane\\n\"", out)
+	}
+
+	if err := client.CopyTo("node01", "/tmp/local", "/home/ubuntu/remote"); err != nil {
+		t.Fatalf("CopyTo() error = %v", err)
+	}
+	if err := client.CopyFrom("node01", "/etc/hosts", "/tmp/hosts"); err != nil {
+		t.Fatalf("CopyFrom() error = %v", err)
+	}
+
+	if got := strings.Join(runner.calls[1], " "); got != "transfer /tmp/local node01:/home/ubuntu/remote" {
+		t.Errorf("CopyTo() ran %q", got)
+	}
+	if got := strings.Join(runner.calls[2], " "); got != "transfer node01:/etc/hosts /tmp/hosts" {
+		t.Errorf("CopyFrom() ran %q", got)
+	}
+}
+
+func TestDeleteAndPurge(t *testing.T) {
+	runner := &fakeRunner{}
+	client := NewWithRunner(runner.run)
+
+	if err := client.Delete("node01", "node02"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := client.Purge(); err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+
+	// Nothing to do for an empty list
+	if err := client.Delete(); err != nil {
+		t.Fatalf("Delete() with no names error = %v", err)
+	}
+
+	if len(runner.calls) != 2 {
+		t.Fatalf("ran %d commands, want 2", len(runner.calls))
+	}
+	if got := strings.Join(runner.calls[0], " "); got != "delete --purge node01 node02" {
+		t.Errorf("Delete() ran %q, want \"delete --purge node01 node02\"", got)
+	}
+	if got := strings.Join(runner.calls[1], " "); got != "purge" {
+		t.Errorf("Purge() ran %q, want \"purge\"", got)
+	}
+}
+
+func TestStoragePath(t *testing.T) {
+	t.Setenv(StorageEnv, "")
+	if got := StoragePath(); got != defaultStoragePath {
+		t.Errorf("StoragePath() = %q, want %q", got, defaultStoragePath)
+	}
+
+	t.Setenv(StorageEnv, "/data/multipass")
+	if got := StoragePath(); got != "/data/multipass" {
+		t.Errorf("StoragePath() = %q, want the %s override", got, StorageEnv)
+	}
+}
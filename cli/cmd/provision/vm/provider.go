@@ -0,0 +1,59 @@
+package vm
+
+import "errors"
+
+// ErrNotFound is returned when an instance does not exist
+var ErrNotFound = errors.New("instance does not exist")
+
+// Instance describes a VM
+type Instance struct {
+	Name        string
+	State       string
+	Release     string
+	IPv4        []string
+	CPUs        int
+	MemoryTotal int64
+	MemoryUsed  int64
+}
+
+// IP returns the first IPv4 address of the instance, or "" if it has none
+func (i Instance) IP() string {
+	if len(i.IPv4) == 0 {
+		return ""
+	}
+	return i.IPv4[0]
+}
+
+// Running reports whether the instance is in the Running state
+func (i Instance) Running() bool {
+	return i.State == "Running"
+}
+
+// LaunchOptions describes a VM to create
+type LaunchOptions struct {
+	Name      string
+	CPUs      int
+	Memory    string
+	Disk      string
+	CloudInit string
+	Image     string
+}
+
+// Provider creates and manages VMs. Methods that take instance names return
+// an error wrapping ErrNotFound when an instance does not exist.
+type Provider interface {
+	// Launch creates and starts a new instance
+	Launch(opts LaunchOptions) error
+	// Info returns details for a single instance
+	Info(name string) (*Instance, error)
+	// List returns all instances known to the provider
+	List() ([]Instance, error)
+	// Delete permanently removes the given instances
+	Delete(names ...string) error
+	// Exec runs a command inside an instance and returns its stdout
+	Exec(name string, command ...string) ([]byte, error)
+	// CopyTo copies a local file into an instance
+	CopyTo(name, localPath, remotePath string) error
+	// CopyFrom copies a file out of an instance
+	CopyFrom(name, remotePath, localPath string) error
+}
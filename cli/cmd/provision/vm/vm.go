@@ -0,0 +1,114 @@
+// Package vm prepares and creates the VMs that clusters run on
+package vm
+
+import (
+	"fmt"
+	"time"
+)
+
+// InitDelay is how long to wait after launching VMs before using them
+var InitDelay = 15 * time.Second
+
+// EnsureInstances launches the instances that do not exist yet and returns
+// all of them, in the order given, once each has an IP address
+func EnsureInstances(provider Provider, specs []LaunchOptions) ([]Instance, error) {
+	existing, err := instancesByName(provider)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list VMs: %w", err)
+	}
+
+	launched := false
+	for _, spec := range specs {
+		if _, ok := existing[spec.Name]; ok {
+			fmt.Printf("✓ VM '%s' exists\n", spec.Name)
+			continue
+		}
+
+		fmt.Printf("Creating VM: %s (%d CPUs, %s memory, %s disk)\n",
+			spec.Name, spec.CPUs, spec.Memory, spec.Disk)
+		if err := provider.Launch(spec); err != nil {
+			return nil, fmt.Errorf("failed to launch VM %s: %w", spec.Name, err)
+		}
+		launched = true
+	}
+
+	if launched {
+		fmt.Println("Waiting for VMs to initialize...")
+		time.Sleep(InitDelay)
+
+		existing, err = instancesByName(provider)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list VMs: %w", err)
+		}
+	}
+
+	instances := make([]Instance, 0, len(specs))
+	for _, spec := range specs {
+		instance, ok := existing[spec.Name]
+		if !ok || instance.IP() == "" {
+			return nil, fmt.Errorf("failed to get IP address for VM %s, please verify that it is running", spec.Name)
+		}
+		instances = append(instances, instance)
+	}
+
+	return instances, nil
+}
+
+// PlanInstances returns what EnsureInstances would return without launching
+// anything, and for each spec whether EnsureInstances would launch it. VMs
+// that would be launched get a placeholder address. When the VMs cannot be
+// listed, all are planned to be launched and the error is returned too.
+func PlanInstances(provider Provider, specs []LaunchOptions) ([]Instance, []bool, error) {
+	existing, err := instancesByName(provider)
+	if err != nil {
+		err = fmt.Errorf("failed to list VMs: %w", err)
+	}
+
+	instances := make([]Instance, 0, len(specs))
+	launch := make([]bool, 0, len(specs))
+	for _, spec := range specs {
+		instance, ok := existing[spec.Name]
+		if !ok {
+			instance = Instance{Name: spec.Name, IPv4: []string{"<" + spec.Name + " IP>"}}
+		}
+		instances = append(instances, instance)
+		launch = append(launch, !ok)
+	}
+	return instances, launch, err
+}
+
+// RunningInstances returns the named instances, in the order given. Each
+// must exist, be running and have an IP address.
+func RunningInstances(provider Provider, names []string) ([]Instance, error) {
+	existing, err := instancesByName(provider)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list VMs: %w", err)
+	}
+
+	instances := make([]Instance, 0, len(names))
+	for _, name := range names {
+		instance, ok := existing[name]
+		if !ok {
+			return nil, fmt.Errorf("VM %s does not exist, provision the cluster again to recreate it", name)
+		}
+		if !instance.Running() || instance.IP() == "" {
+			return nil, fmt.Errorf("VM %s is not running, start it with 'multipass start %s'", name, name)
+		}
+		instances = append(instances, instance)
+	}
+	return instances, nil
+}
+
+// instancesByName lists all instances keyed by name
+func instancesByName(provider Provider) (map[string]Instance, error) {
+	instances, err := provider.List()
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]Instance, len(instances))
+	for _, instance := range instances {
+		byName[instance.Name] = instance
+	}
+	return byName, nil
+}
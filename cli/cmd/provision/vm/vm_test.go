@@ -0,0 +1,141 @@
+package vm_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/vm"
+	"github.com/bxtal-lsn/kubernetes/cli/cmd/provision/vm/fake"
+)
+
+func TestEnsureInstancesLaunchesMissing(t *testing.T) {
+	vm.InitDelay = 0
+
+	provider := fake.New()
+	existingIP := provider.AddInstance("node01")
+
+	specs := []vm.LaunchOptions{
+		{Name: "controlplane", CPUs: 4, Memory: "8G", Disk: "40G"},
+		{Name: "node01", CPUs: 2, Memory: "4G", Disk: "20G"},
+	}
+	instances, err := vm.EnsureInstances(provider, specs)
+	if err != nil {
+		t.Fatalf("EnsureInstances() error = %v", err)
+	}
+
+	launches := provider.CallsTo("launch")
+	if len(launches) != 1 || launches[0].Args[0] != "controlplane" {
+		t.Errorf("launch calls = %v, want only controlplane", launches)
+	}
+
+	if len(instances) != 2 || instances[0].Name != "controlplane" || instances[1].Name != "node01" {
+		t.Fatalf("EnsureInstances() = %v, want controlplane and node01 in order", instances)
+	}
+	if instances[0].IP() == "" {
+		t.Errorf("launched instance has no IP")
+	}
+	if instances[1].IP() != existingIP {
+		t.Errorf("existing instance IP = %q, want %q", instances[1].IP(), existingIP)
+	}
+}
+
+func TestEnsureInstancesLaunchFailure(t *testing.T) {
+	vm.InitDelay = 0
+
+	provider := fake.New()
+	provider.FailOn("launch", "node02", errors.New("insufficient resources"))
+
+	specs := []vm.LaunchOptions{{Name: "node01"}, {Name: "node02"}}
+	_, err := vm.EnsureInstances(provider, specs)
+	if err == nil || !strings.Contains(err.Error(), "node02") || !strings.Contains(err.Error(), "insufficient resources") {
+		t.Errorf("EnsureInstances() error = %v, want launch failure for node02", err)
+	}
+}
+
+func TestRenderCloudInit(t *testing.T) {
+	templatePath := filepath.Join(t.TempDir(), "common.yaml")
+	if err := os.WriteFile(templatePath, []byte("ssh_authorized_keys:\n  - $SSH_PUBLIC_KEY\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+
+	path, err := vm.RenderCloudInit(templatePath, "ssh-rsa AAAA test")
+	if err != nil {
+		t.Fatalf("RenderCloudInit() error = %v", err)
+	}
+	defer os.Remove(path)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read rendered file: %v", err)
+	}
+	if string(data) != "ssh_authorized_keys:\n  - ssh-rsa AAAA test\n" {
+		t.Errorf("rendered cloud-init = %q", data)
+	}
+}
+
+func TestPlanInstances(t *testing.T) {
+	provider := fake.New()
+	existingIP := provider.AddInstance("node01")
+
+	specs := []vm.LaunchOptions{{Name: "controlplane"}, {Name: "node01"}}
+	instances, launch, err := vm.PlanInstances(provider, specs)
+	if err != nil {
+		t.Fatalf("PlanInstances() error = %v", err)
+	}
+	if len(provider.CallsTo("launch")) != 0 {
+		t.Errorf("PlanInstances() launched VMs")
+	}
+	if !launch[0] || launch[1] {
+		t.Errorf("launch = %v, want only controlplane", launch)
+	}
+	if instances[0].IP() != "<controlplane IP>" || instances[1].IP() != existingIP {
+		t.Errorf("planned IPs = %s, %s, want a placeholder and %s", instances[0].IP(), instances[1].IP(), existingIP)
+	}
+
+	// Without the list every VM is planned to be launched
+	provider.FailOn("list", "", errors.New("multipass is not running"))
+	_, launch, err = vm.PlanInstances(provider, specs)
+	if err == nil || !launch[0] || !launch[1] {
+		t.Errorf("PlanInstances() = %v, %v, want the list error and all VMs launched", launch, err)
+	}
+}
+
+func TestRunningInstances(t *testing.T) {
+	provider := fake.New()
+	controlPlaneIP := provider.AddInstance("controlplane")
+	provider.AddInstance("node01")
+
+	instances, err := vm.RunningInstances(provider, []string{"node01", "controlplane"})
+	if err != nil {
+		t.Fatalf("RunningInstances() error = %v", err)
+	}
+	if instances[0].Name != "node01" || instances[1].IP() != controlPlaneIP {
+		t.Errorf("RunningInstances() = %+v, want node01 then controlplane", instances)
+	}
+
+	if _, err := vm.RunningInstances(provider, []string{"node02"}); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("RunningInstances() error = %v, want node02 missing", err)
+	}
+
+	provider.SetState("node01", "Stopped")
+	if _, err := vm.RunningInstances(provider, []string{"node01"}); err == nil || !strings.Contains(err.Error(), "multipass start node01") {
+		t.Errorf("RunningInstances() error = %v, want node01 not running", err)
+	}
+}
+
+func TestPlannedPublicKey(t *testing.T) {
+	keyPath := filepath.Join(t.TempDir(), "id_rsa_provisioning")
+	if got := vm.PlannedPublicKey(keyPath); !strings.Contains(got, "generated when provisioning") {
+		t.Errorf("PlannedPublicKey() without a key = %q, want a placeholder", got)
+	}
+
+	if err := os.WriteFile(keyPath+".pub", []byte("ssh-rsa AAAA test\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+	if got := vm.PlannedPublicKey(keyPath); got != "ssh-rsa AAAA test" {
+		t.Errorf("PlannedPublicKey() = %q, want the public key", got)
+	}
+}
@@ -6,8 +6,16 @@ set -e  # Exit on error
 SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
 source "$SCRIPT_DIR/common-lib.sh"
 
-# Define VMs to clean up
-VM_NAMES=("controlplane" "node01" "node02" "node03")
+# Optional config file with the name_prefix the cluster was provisioned with
+CONFIG_FILE="${1:-$SCRIPT_DIR/../ansible/defaults/kubernetes.yml}"
+NAME_PREFIX=""
+if [ -f "$CONFIG_FILE" ] && command -v python3 >/dev/null && python3 -c "import yaml" 2>/dev/null; then
+    NAME_PREFIX=$(python3 -c "
+import yaml
+with open('$CONFIG_FILE', 'r') as f:
+    print((yaml.safe_load(f) or {}).get('name_prefix') or '')
+")
+fi
 
 echo "Checking for multipass..."
 if ! command -v multipass &> /dev/null; then
@@ -16,10 +24,25 @@ if ! command -v multipass &> /dev/null; then
     exit 1
 fi
 
+# Define VMs to clean up: the control planes, the load balancer of a
+# highly-available cluster and every numbered worker
+VM_NAMES=()
+while read -r name _; do
+    if [[ "$name" == "${NAME_PREFIX}controlplane" || "$name" == "${NAME_PREFIX}loadbalancer" ||
+          "$name" =~ ^${NAME_PREFIX}(controlplane|node)[0-9]{2,}$ ]]; then
+        VM_NAMES+=("$name")
+    fi
+done < <(multipass list --format csv | tail -n +2 | tr ',' ' ')
+
+if [[ ${#VM_NAMES[@]} -eq 0 ]]; then
+    echo "  No Kubernetes VMs found"
+    exit 0
+fi
+
 echo "The following VMs will be deleted:"
-multipass list | grep -E "controlplane|node01|node02|node03" || echo "  No Kubernetes VMs found"
+printf '  %s\n' "${VM_NAMES[@]}"
 
-echo -e "${YELLOW}⚠${NC} This will delete all Kubernetes-related VMs (controlplane, node01, node02, node03)."
+echo -e "${YELLOW}⚠${NC} This will delete all Kubernetes-related VMs (${VM_NAMES[*]})."
 echo -e "${YELLOW}⚠${NC} All deployments, configurations, and data on these VMs will be lost."
 echo "Continue? (y/n)"
 read -r response
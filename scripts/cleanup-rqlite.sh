@@ -0,0 +1,70 @@
+#!/bin/bash
+
+set -e  # Exit on error
+
+# Set variables
+SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
+source "$SCRIPT_DIR/common-lib.sh"
+STATE_DIR="${XDG_STATE_HOME:-$HOME/.local/state}/provision-cli/rqlite"
+INVENTORY_FILE="$STATE_DIR/inventory.yml"
+
+# Pass --teardown to leave the Raft cluster and uninstall rqlite first
+TEARDOWN=false
+if [[ "$1" == "--teardown" ]]; then
+    TEARDOWN=true
+fi
+
+echo "Checking for multipass..."
+if ! command -v multipass &> /dev/null; then
+    echo -e "${RED}✗ Error:${NC} multipass is not installed. Cannot clean up VMs."
+    echo "  Please install Multipass from: https://canonical.com/multipass/install"
+    exit 1
+fi
+
+VM_NAMES=()
+for name in rqlite1 rqlite2 rqlite3; do
+    if multipass info "$name" &> /dev/null; then
+        VM_NAMES+=("$name")
+    fi
+done
+
+if [[ ${#VM_NAMES[@]} -eq 0 ]]; then
+    echo "  No rqlite VMs found"
+    rm -rf "$STATE_DIR"
+    exit 0
+fi
+
+echo "The following VMs will be deleted:"
+printf '  %s\n' "${VM_NAMES[@]}"
+
+echo -e "${YELLOW}⚠${NC} This will delete all rqlite VMs (${VM_NAMES[*]})."
+echo -e "${YELLOW}⚠${NC} All data stored in the cluster will be lost."
+echo "Continue? (y/n)"
+read -r response
+if [[ ! "$response" =~ ^[Yy]$ ]]; then
+    echo "Operation cancelled."
+    exit 0
+fi
+
+if [[ "$TEARDOWN" == true ]]; then
+    if [[ ! -f "$INVENTORY_FILE" ]]; then
+        echo -e "${RED}✗ Error:${NC} No inventory found at $INVENTORY_FILE, cannot run the teardown playbook."
+        exit 1
+    fi
+    echo "Running Ansible playbook for rqlite teardown..."
+    ansible-playbook -i "$INVENTORY_FILE" "$SCRIPT_DIR/../ansible/playbooks/rqlite-teardown.yml"
+    echo -e "${GREEN}✓${NC} rqlite removed from the cluster nodes"
+fi
+
+echo "Deleting rqlite VMs..."
+multipass delete "${VM_NAMES[@]}" 2>/dev/null || true
+echo -e "${GREEN}✓${NC} VMs deleted"
+
+echo "Purging deleted VMs..."
+multipass purge
+echo -e "${GREEN}✓${NC} Deleted VMs purged"
+
+rm -rf "$STATE_DIR"
+echo -e "${GREEN}✓${NC} Generated inventory removed"
+
+echo -e "${GREEN}✓${NC} Cleanup complete."
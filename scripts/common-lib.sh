@@ -9,6 +9,17 @@ NC='\033[0m' # No Color
 # Function to check dependencies
 check_dependencies() {
     echo "Checking dependencies..."
+
+    # provision-cli doctor runs a fuller set of checks when it has been built
+    local cli="$(dirname "${BASH_SOURCE[0]}")/../provision-cli"
+    if [ ! -x "$cli" ]; then
+        cli=$(command -v provision-cli)
+    fi
+    if [ -n "$cli" ]; then
+        "$cli" doctor || return 1
+        echo
+        return 0
+    fi
     
     # Check if multipass is installed
     if command -v multipass &> /dev/null; then
@@ -1,5 +1,6 @@
 #!/bin/bash
 # provision-rqlite.sh - Creates and configures a rqlite environment with 3 nodes
+# Usage: provision-rqlite.sh [config-file]
 
 set -e  # Exit on error
 
@@ -20,22 +21,30 @@ SSH_PUB_KEY_PATH="$SSH_KEY_PATH.pub"
 # Cloud-init and inventory paths
 CLOUD_INIT_TEMPLATE="$SCRIPT_DIR/../multipass/cloud-init/common.yaml"
 CLOUD_INIT_PROCESSED="$SCRIPT_DIR/../multipass/cloud-init/common_processed.yaml"
-INVENTORY_FILE="$SCRIPT_DIR/../ansible/inventories/rqlite.yml"
+INVENTORY_FILE="${XDG_STATE_HOME:-$HOME/.local/state}/provision-cli/rqlite/inventory.yml"
 DEFAULTS_FILE="$SCRIPT_DIR/../ansible/defaults/rqlite.yml"
 
+# Optional config file (same keys as the defaults file) used for VM sizing
+# and passed to ansible-playbook as extra vars
+CONFIG_FILE="${1:-$DEFAULTS_FILE}"
+if [ ! -f "$CONFIG_FILE" ]; then
+    echo -e "${RED}✗ Error:${NC} Config file not found at $CONFIG_FILE"
+    exit 1
+fi
+
 # Default resource values
 NODE_CPU=2
 NODE_MEM="2G"
 NODE_DISK="10G"
 
-# Load resource values from defaults file if it exists
-if [ -f "$DEFAULTS_FILE" ] && command -v python3 >/dev/null && python3 -c "import yaml" 2>/dev/null; then
-    echo "Loading VM resource settings from defaults file..."
+# Load resource values from the config file
+if command -v python3 >/dev/null && python3 -c "import yaml" 2>/dev/null; then
+    echo "Loading VM resource settings from $CONFIG_FILE..."
     # Extract the VM resource values using Python
     RESOURCES=$(python3 -c "
 import yaml
 try:
-    with open('$DEFAULTS_FILE', 'r') as f:
+    with open('$CONFIG_FILE', 'r') as f:
         vars = yaml.safe_load(f)
         node_cpu = vars.get('node_cpus', 2)
         node_mem = vars.get('node_memory', '2G')
@@ -184,7 +193,7 @@ fi
 echo "Running Ansible playbook for rqlite deployment..."
 echo "This will take some time. Please be patient..."
 
-if ansible-playbook -i "$INVENTORY_FILE" "$PLAYBOOK"; then
+if ansible-playbook -i "$INVENTORY_FILE" -e @"$CONFIG_FILE" "$PLAYBOOK"; then
     echo -e "${GREEN}✓${NC} Ansible playbook completed successfully"
 else
     echo -e "${RED}✗ Error:${NC} Ansible playbook failed"